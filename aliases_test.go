@@ -0,0 +1,152 @@
+package completionflags
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestAliases_Resolve(t *testing.T) {
+	var ran []string
+	record := func(ctx *Context) error {
+		ran = ctx.SubcommandPath
+		return nil
+	}
+	cmd := NewCommand("app").
+		Subcommand("remove").Aliases("rm", "del").Handler(record).Done().
+		Subcommand("remote").
+		Subcommand("add").Handler(record).Done().
+		Subcommand("list").Aliases("ls").Handler(record).Done().
+		Done().
+		Build()
+
+	tests := []struct {
+		args []string
+		want []string
+	}{
+		{[]string{"rm"}, []string{"remove"}},
+		{[]string{"del"}, []string{"remove"}},
+		{[]string{"remote", "ls"}, []string{"remote", "list"}},
+	}
+	for _, tt := range tests {
+		ran = nil
+		if err := cmd.Execute(tt.args); err != nil {
+			t.Errorf("%v: %v", tt.args, err)
+			continue
+		}
+		if !reflect.DeepEqual(ran, tt.want) {
+			t.Errorf("%v: ran %v, want %v", tt.args, ran, tt.want)
+		}
+	}
+
+	// Prefixes need AllowPrefixMatch
+	if err := cmd.Execute([]string{"rem", "a"}); err == nil {
+		t.Error("prefix resolved without AllowPrefixMatch")
+	}
+}
+
+func TestAliases_PrefixMatch(t *testing.T) {
+	var ran []string
+	record := func(ctx *Context) error {
+		ran = ctx.SubcommandPath
+		return nil
+	}
+	cmd := NewCommand("app").
+		AllowPrefixMatch().
+		Subcommand("show").Handler(record).Done().
+		Subcommand("shutdown").Handler(record).Done().
+		Subcommand("remote").
+		Subcommand("add").Handler(record).Done().
+		Done().
+		Build()
+
+	if err := cmd.Execute([]string{"remot", "a"}); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if !reflect.DeepEqual(ran, []string{"remote", "add"}) {
+		t.Errorf("ran %v, want [remote add]", ran)
+	}
+
+	// "sh" could be show or shutdown
+	err := cmd.Execute([]string{"sh"})
+	var unknown UnknownCommandError
+	if !errors.As(err, &unknown) {
+		t.Fatalf("expected UnknownCommandError for ambiguous prefix, got %v", err)
+	}
+	if !reflect.DeepEqual(unknown.Suggestions, []string{"show", "shutdown"}) {
+		t.Errorf("unexpected suggestions: %v", unknown.Suggestions)
+	}
+}
+
+func TestAliases_Completion(t *testing.T) {
+	cmd := NewCommand("app").
+		AllowPrefixMatch().
+		Subcommand("remove").Aliases("rm", "del").Handler(func(ctx *Context) error { return nil }).Done().
+		Subcommand("remote").
+		Subcommand("list").Aliases("ls").Handler(func(ctx *Context) error { return nil }).Done().
+		Done().
+		Build()
+
+	matches, err := cmd.Complete([]string{"r"}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(matches, []string{"remote", "remove"}) {
+		t.Errorf("completing r: got %v", matches)
+	}
+
+	matches, _ = cmd.Complete([]string{"de"}, 1)
+	if !reflect.DeepEqual(matches, []string{"del"}) {
+		t.Errorf("completing de: got %v", matches)
+	}
+
+	matches, _ = cmd.Complete([]string{"remot", "l"}, 2)
+	if !reflect.DeepEqual(matches, []string{"list"}) {
+		t.Errorf("completing after prefix: got %v", matches)
+	}
+}
+
+func TestAliases_ShownInHelp(t *testing.T) {
+	cmd := NewCommand("app").
+		Subcommand("remove").Aliases("rm", "del").Description("Remove an item").Handler(func(ctx *Context) error { return nil }).Done().
+		Build()
+
+	if help := cmd.GenerateHelp(); !strings.Contains(help, "remove (rm, del)") {
+		t.Errorf("help missing aliases:\n%s", help)
+	}
+	if man := cmd.GenerateManPage(); !strings.Contains(man, "remove (rm, del)") {
+		t.Errorf("man page missing aliases:\n%s", man)
+	}
+	if help := cmd.GetSubcommand("remove").GenerateHelp("app"); !strings.Contains(help, "ALIASES:\n    rm, del") {
+		t.Errorf("subcommand help missing aliases:\n%s", help)
+	}
+}
+
+func TestAliases_ManPageEscaped(t *testing.T) {
+	cmd := NewCommand("app").
+		Subcommand("remote").
+		Subcommand("remove-all").Aliases("rm-all").Handler(func(ctx *Context) error { return nil }).Done().
+		Done().
+		Build()
+
+	remote := cmd.GetSubcommand("remote")
+	if man := remote.Subcommands["remove-all"].GenerateManPage("app remote"); !strings.Contains(man, ".SH ALIASES\nrm\\-all\n") {
+		t.Errorf("man page aliases not escaped:\n%s", man)
+	}
+	if man := remote.GenerateManPage("app"); !strings.Contains(man, ".B remove\\-all (rm\\-all)\n") {
+		t.Errorf("man page commands not escaped:\n%s", man)
+	}
+}
+
+func TestAliases_ConflictPanics(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Error("expected panic for alias colliding with a sibling")
+		}
+	}()
+	NewCommand("app").
+		Subcommand("list").Handler(func(ctx *Context) error { return nil }).Done().
+		Subcommand("ls-remote").Aliases("list").Handler(func(ctx *Context) error { return nil }).Done().
+		Build()
+}
@@ -0,0 +1,224 @@
+package completionflags
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ArgParser converts a command-line word into an argument value
+type ArgParser func(value string) (interface{}, error)
+
+// ArgTypeOptions holds the optional parts of a registered argument type
+type ArgTypeOptions struct {
+	Display   string       // Label used in help and man pages (defaults to the name)
+	Completer Completer    // Completer used when a flag doesn't set its own
+	GoType    reflect.Type // Type the parser returns (or an interface it implements); checked after every parse
+}
+
+// argTypeInfo is one entry in the argument type registry. Built-in types keep
+// their internal parser so ArgTime can see the flag's time configuration.
+type argTypeInfo struct {
+	name      string
+	display   string
+	parse     func(value string, spec *FlagSpec, index int, globalFlags map[string]interface{}) (interface{}, error)
+	completer Completer
+	goType    reflect.Type
+}
+
+var (
+	argTypesMu sync.RWMutex
+
+	// argTypes is indexed by ArgType; the first entries line up with the
+	// built-in ArgType constants
+	argTypes = []argTypeInfo{
+		ArgString: {name: "string", display: "string", goType: reflect.TypeOf(""),
+			parse: func(value string, _ *FlagSpec, _ int, _ map[string]interface{}) (interface{}, error) {
+				return value, nil
+			}},
+		ArgInt: {name: "int", display: "integer", goType: reflect.TypeOf(0),
+			parse: func(value string, _ *FlagSpec, _ int, _ map[string]interface{}) (interface{}, error) {
+				return strconv.Atoi(value)
+			}},
+		ArgFloat: {name: "float", display: "float", goType: reflect.TypeOf(float64(0)),
+			parse: func(value string, _ *FlagSpec, _ int, _ map[string]interface{}) (interface{}, error) {
+				return strconv.ParseFloat(value, 64)
+			}},
+		ArgBool: {name: "bool", display: "boolean", goType: reflect.TypeOf(false),
+			parse: func(value string, _ *FlagSpec, _ int, _ map[string]interface{}) (interface{}, error) {
+				return strconv.ParseBool(value)
+			}},
+		ArgDuration: {name: "duration", display: "duration", goType: durationType,
+			parse: func(value string, _ *FlagSpec, _ int, _ map[string]interface{}) (interface{}, error) {
+				return time.ParseDuration(value)
+			}},
+		ArgTime: {name: "time", display: "time", goType: reflect.TypeOf(time.Time{}),
+			parse: func(value string, spec *FlagSpec, _ int, globalFlags map[string]interface{}) (interface{}, error) {
+				return parseTimeValue(value, spec, globalFlags)
+			}},
+		ArgBytes: {name: "bytes", display: "size", goType: reflect.TypeOf(int64(0)),
+			completer: NoCompleter{Hint: "<SIZE>"},
+			parse: func(value string, _ *FlagSpec, _ int, _ map[string]interface{}) (interface{}, error) {
+				return parseBytes(value)
+			}},
+		ArgIP: {name: "ip", display: "IP address", goType: reflect.TypeOf(net.IP{}),
+			completer: NoCompleter{Hint: "<IP>"},
+			parse: func(value string, _ *FlagSpec, _ int, _ map[string]interface{}) (interface{}, error) {
+				return parseIP(value)
+			}},
+		ArgCIDR: {name: "cidr", display: "CIDR block", goType: reflect.TypeOf(&net.IPNet{}),
+			completer: NoCompleter{Hint: "<ADDR/BITS>"},
+			parse: func(value string, _ *FlagSpec, _ int, _ map[string]interface{}) (interface{}, error) {
+				_, network, err := net.ParseCIDR(value)
+				return network, err
+			}},
+		ArgURL: {name: "url", display: "URL", goType: reflect.TypeOf(&url.URL{}),
+			completer: NoCompleter{Hint: "<URL>"},
+			parse: func(value string, _ *FlagSpec, _ int, _ map[string]interface{}) (interface{}, error) {
+				return parseURL(value)
+			}},
+		ArgRegexp: {name: "regexp", display: "regular expression", goType: reflect.TypeOf(&regexp.Regexp{}),
+			completer: NoCompleter{Hint: "<REGEXP>"},
+			parse: func(value string, _ *FlagSpec, _ int, _ map[string]interface{}) (interface{}, error) {
+				return regexp.Compile(value)
+			}},
+		ArgEnum: {name: "enum", display: "enum", goType: reflect.TypeOf(""),
+			parse: func(value string, spec *FlagSpec, index int, _ map[string]interface{}) (interface{}, error) {
+				return parseEnum(value, spec.enumValues(index))
+			}},
+	}
+)
+
+// RegisterArgType adds an argument type to the registry and returns its
+// ArgType for use with ArgType(), Arg().Type() and friends. Parse errors are
+// reported as ParseErrors naming the flag. Registering an empty name, a nil
+// parser or a name already in use panics.
+//
+// Example:
+//
+//	var ArgSemver = cf.RegisterArgType("semver", parseSemver, cf.ArgTypeOptions{
+//	    Display: "semantic version",
+//	    GoType:  reflect.TypeOf(&semver.Version{}),
+//	})
+func RegisterArgType(name string, parse ArgParser, opts ArgTypeOptions) ArgType {
+	if name == "" {
+		panic("RegisterArgType: empty type name")
+	}
+	if parse == nil {
+		panic(fmt.Sprintf("RegisterArgType(%q): nil parser", name))
+	}
+	display := opts.Display
+	if display == "" {
+		display = name
+	}
+
+	argTypesMu.Lock()
+	defer argTypesMu.Unlock()
+	for _, info := range argTypes {
+		if info.name == name {
+			panic(fmt.Sprintf("RegisterArgType(%q): type already registered", name))
+		}
+	}
+	argTypes = append(argTypes, argTypeInfo{
+		name:    name,
+		display: display,
+		parse: func(value string, _ *FlagSpec, _ int, _ map[string]interface{}) (interface{}, error) {
+			return parse(value)
+		},
+		completer: opts.Completer,
+		goType:    opts.GoType,
+	})
+	return ArgType(len(argTypes) - 1)
+}
+
+// LookupArgType returns the ArgType registered under name
+func LookupArgType(name string) (ArgType, bool) {
+	argTypesMu.RLock()
+	defer argTypesMu.RUnlock()
+	for i, info := range argTypes {
+		if info.name == name {
+			return ArgType(i), true
+		}
+	}
+	return 0, false
+}
+
+// info returns the registry entry for t; unknown types behave as ArgString
+func (t ArgType) info() argTypeInfo {
+	argTypesMu.RLock()
+	defer argTypesMu.RUnlock()
+	if t < 0 || int(t) >= len(argTypes) {
+		return argTypes[ArgString]
+	}
+	return argTypes[t]
+}
+
+// String returns the name the type was registered under
+func (t ArgType) String() string {
+	return t.info().name
+}
+
+// GoType returns the type of the values the parser produces, or nil if the
+// type was registered without one
+func (t ArgType) GoType() reflect.Type {
+	return t.info().goType
+}
+
+// parseArgValue converts a string to the type of argument index of spec
+func parseArgValue(value string, spec *FlagSpec, index int, globalFlags map[string]interface{}) (interface{}, error) {
+	if spec.ParseFunc != nil {
+		result, err := spec.ParseFunc(value, dependencyValues(spec, nil, globalFlags))
+		return result, secretError(spec, err)
+	}
+	argType := ArgString
+	if index < len(spec.ArgTypes) {
+		argType = spec.ArgTypes[index]
+	}
+	info := argType.info()
+	result, err := info.parse(value, spec, index, globalFlags)
+	if err != nil {
+		return nil, secretError(spec, err)
+	}
+	if info.goType != nil && (result == nil || !reflect.TypeOf(result).AssignableTo(info.goType)) {
+		return nil, fmt.Errorf("%s parser returned %T, want %s", info.name, result, info.goType)
+	}
+	return result, nil
+}
+
+// argTypeName renders an ArgType as a human label, matching the vocabulary
+// used by formatPositional / formatPositionalForSubcommand.
+func argTypeName(t ArgType) string {
+	return t.info().display
+}
+
+// argLabel describes argument index of spec for help output, listing the
+// allowed values of an enum
+func argLabel(spec *FlagSpec, index int) string {
+	if index >= len(spec.ArgTypes) {
+		return argTypeName(ArgString)
+	}
+	if values := spec.enumValues(index); spec.ArgTypes[index] == ArgEnum && len(values) > 0 {
+		return "one of " + strings.Join(values, ", ")
+	}
+	return argTypeName(spec.ArgTypes[index])
+}
+
+// setArgType sets the type of argument index and, when the argument still
+// has the default NoCompleter, installs the type's completer
+func setArgType(spec *FlagSpec, index int, t ArgType) {
+	if index < 0 || index >= len(spec.ArgTypes) {
+		return
+	}
+	spec.ArgTypes[index] = t
+	if c := t.info().completer; c != nil && index < len(spec.ArgCompleters) {
+		if nc, ok := spec.ArgCompleters[index].(NoCompleter); ok && nc.Hint == "" {
+			spec.ArgCompleters[index] = c
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package completionflags
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testVersion struct{ Major, Minor int }
+
+func parseTestVersion(s string) (interface{}, error) {
+	major, minor, ok := strings.Cut(s, ".")
+	if !ok {
+		return nil, fmt.Errorf("%q is not MAJOR.MINOR", s)
+	}
+	a, err1 := strconv.Atoi(major)
+	b, err2 := strconv.Atoi(minor)
+	if err1 != nil || err2 != nil {
+		return nil, fmt.Errorf("%q is not MAJOR.MINOR", s)
+	}
+	return testVersion{a, b}, nil
+}
+
+var argTestVersion = RegisterArgType("test-version", parseTestVersion, ArgTypeOptions{
+	Display:   "version (MAJOR.MINOR)",
+	Completer: &StaticCompleter{Options: []string{"1.0", "2.0"}},
+	GoType:    reflect.TypeOf(testVersion{}),
+})
+
+func TestArgType_Registered(t *testing.T) {
+	var got interface{}
+	cmd := NewCommand("app").
+		Flag("-min").Args(1).ArgType(0, argTestVersion).Global().Done().
+		Handler(func(ctx *Context) error {
+			got = ctx.GlobalFlags["-min"]
+			return nil
+		}).
+		Build()
+
+	if err := cmd.Execute([]string{"-min", "1.2"}); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if got != (testVersion{1, 2}) {
+		t.Errorf("got %#v, want testVersion{1, 2}", got)
+	}
+
+	err := cmd.Execute([]string{"-min", "latest"})
+	var perr ParseError
+	if !errors.As(err, &perr) || perr.Flag != "-min" {
+		t.Fatalf("expected ParseError for -min, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "not MAJOR.MINOR") {
+		t.Errorf("parser message lost: %v", err)
+	}
+
+	if typ, ok := LookupArgType("test-version"); !ok || typ != argTestVersion {
+		t.Errorf("LookupArgType = %v, %v", typ, ok)
+	}
+	if argTestVersion.String() != "test-version" || ArgInt.String() != "int" {
+		t.Errorf("unexpected names: %s, %s", argTestVersion, ArgInt)
+	}
+}
+
+func TestArgType_CompleterAndHelp(t *testing.T) {
+	cmd := NewCommand("app").
+		Flag("-min").Args(1).ArgName(0, "VERSION").ArgType(0, argTestVersion).Global().Help("Minimum version").Done().
+		Handler(func(ctx *Context) error { return nil }).
+		Build()
+
+	matches, err := cmd.Complete([]string{"-min", "1"}, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(matches, []string{"1.0"}) {
+		t.Errorf("completion: got %v", matches)
+	}
+
+	text, err := cmd.HelpAt([]string{"-min", "1"}, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(text, "VERSION (version (MAJOR.MINOR))") {
+		t.Errorf("help-at missing type label:\n%s", text)
+	}
+
+	// An explicit completer wins over the type's default
+	cmd = NewCommand("app").
+		Flag("-min").Args(1).ArgCompleter(0, &StaticCompleter{Options: []string{"9.9"}}).ArgType(0, argTestVersion).Done().
+		Handler(func(ctx *Context) error { return nil }).
+		Build()
+	matches, _ = cmd.Complete([]string{"-min", ""}, 2)
+	if !reflect.DeepEqual(matches, []string{"9.9"}) {
+		t.Errorf("explicit completer replaced: got %v", matches)
+	}
+}
+
+func TestArgType_GoTypeChecked(t *testing.T) {
+	wrong := RegisterArgType("test-wrong", func(s string) (interface{}, error) { return s, nil },
+		ArgTypeOptions{GoType: reflect.TypeOf(0)})
+	if _, err := parseArgValue("x", &FlagSpec{ArgTypes: []ArgType{wrong}}, 0, nil); err == nil {
+		t.Error("expected error when parser returns the wrong type")
+	}
+
+	// An interface GoType accepts any type implementing it
+	stringer := RegisterArgType("test-stringer", func(s string) (interface{}, error) { return time.ParseDuration(s) },
+		ArgTypeOptions{GoType: reflect.TypeOf((*fmt.Stringer)(nil)).Elem()})
+	got, err := parseArgValue("90s", &FlagSpec{ArgTypes: []ArgType{stringer}}, 0, nil)
+	if err != nil {
+		t.Fatalf("interface GoType rejected an implementation: %v", err)
+	}
+	if got.(fmt.Stringer).String() != "1m30s" {
+		t.Errorf("got %v, want 1m30s", got)
+	}
+	notStringer := RegisterArgType("test-not-stringer", func(s string) (interface{}, error) { return len(s), nil },
+		ArgTypeOptions{GoType: reflect.TypeOf((*fmt.Stringer)(nil)).Elem()})
+	if _, err := parseArgValue("x", &FlagSpec{ArgTypes: []ArgType{notStringer}}, 0, nil); err == nil {
+		t.Error("expected error when the value doesn't implement the interface GoType")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic registering a duplicate name")
+		}
+	}()
+	RegisterArgType("test-version", parseTestVersion, ArgTypeOptions{})
+}
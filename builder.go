@@ -1,6 +1,9 @@
 package completionflags
 
-import "fmt"
+import (
+	"fmt"
+	"time"
+)
 
 // NewCommand creates a new command builder
 func NewCommand(name string) *CommandBuilder {
@@ -52,6 +55,145 @@ func (cb *CommandBuilder) Separators(seps ...string) *CommandBuilder {
 	return cb
 }
 
+// MutuallyExclusive declares that at most one of the flags may be given
+func (cb *CommandBuilder) MutuallyExclusive(flags ...string) *CommandBuilder {
+	cb.cmd.groups = append(cb.cmd.groups, FlagGroup{Kind: GroupMutuallyExclusive, Flags: flags})
+	return cb
+}
+
+// RequiredTogether declares that the flags must be given together or not at all
+func (cb *CommandBuilder) RequiredTogether(flags ...string) *CommandBuilder {
+	cb.cmd.groups = append(cb.cmd.groups, FlagGroup{Kind: GroupRequiredTogether, Flags: flags})
+	return cb
+}
+
+// OneRequired declares that at least one of the flags must be given
+func (cb *CommandBuilder) OneRequired(flags ...string) *CommandBuilder {
+	cb.cmd.groups = append(cb.cmd.groups, FlagGroup{Kind: GroupOneRequired, Flags: flags})
+	return cb
+}
+
+// ValidateContext adds a check on the parsed flags as a whole, run once
+// environment variables, configuration files, defaults and positionals have
+// been applied and the built-in validation has passed. The hooks also run
+// for subcommands, before their own. Errors are returned as ValidationErrors.
+//
+//	ValidateContext(func(ctx *cf.Context) error {
+//	    if _, ok := ctx.GlobalFlags["-limit"]; ok && ctx.GetString("-sort", "") == "" {
+//	        return errors.New("-limit needs -sort")
+//	    }
+//	    return nil
+//	})
+func (cb *CommandBuilder) ValidateContext(fn ContextValidatorFunc) *CommandBuilder {
+	cb.cmd.contextValidators = append(cb.cmd.contextValidators, fn)
+	return cb
+}
+
+// ShutdownTimeout bounds how long Execute waits for the handler to return
+// once SIGINT or SIGTERM has cancelled ctx.Ctx(); after d the process exits.
+// Without it only a second signal forces an exit.
+func (cb *CommandBuilder) ShutdownTimeout(d time.Duration) *CommandBuilder {
+	cb.cmd.shutdownTimeout = d
+	return cb
+}
+
+// Before adds a hook run before the handler of the command and of every
+// subcommand, once flags are parsed and validated. An error stops dispatch.
+func (cb *CommandBuilder) Before(fn BeforeFunc) *CommandBuilder {
+	cb.cmd.before = append(cb.cmd.before, fn)
+	return cb
+}
+
+// After adds a hook run after the handler of the command and of every
+// subcommand. It gets the handler's error and returns the one to report.
+func (cb *CommandBuilder) After(fn AfterFunc) *CommandBuilder {
+	cb.cmd.after = append(cb.cmd.after, fn)
+	return cb
+}
+
+// Use wraps the handler of the command and of every subcommand in mw. The
+// first middleware added is the outermost, and all of them wrap the Before
+// and After hooks. Hooks fire the same way from Execute, shell.Serve and SSH
+// sessions.
+func (cb *CommandBuilder) Use(mw ...Middleware) *CommandBuilder {
+	cb.cmd.middleware = append(cb.cmd.middleware, mw...)
+	return cb
+}
+
+// MinClauses requires at least n clauses
+func (cb *CommandBuilder) MinClauses(n int) *CommandBuilder {
+	cb.cmd.clauseRules.Min = n
+	return cb
+}
+
+// MaxClauses allows at most n clauses
+func (cb *CommandBuilder) MaxClauses(n int) *CommandBuilder {
+	cb.cmd.clauseRules.Max = n
+	return cb
+}
+
+// ClauseValidator adds a check run on every clause after the flags are
+// validated; an error is reported with the clause's index and separator
+func (cb *CommandBuilder) ClauseValidator(fn func(Clause) error) *CommandBuilder {
+	cb.cmd.clauseRules.Validators = append(cb.cmd.clauseRules.Validators, fn)
+	return cb
+}
+
+// AllowPrefixMatch lets users abbreviate subcommand names (and aliases) at
+// every level of the tree to any unambiguous prefix, so `myapp rem a`
+// runs `myapp remote add`. Ambiguous prefixes are reported as unknown
+// commands, with the candidates as suggestions.
+func (cb *CommandBuilder) AllowPrefixMatch() *CommandBuilder {
+	cb.cmd.allowPrefixMatch = true
+	return cb
+}
+
+// AllowEquals accepts a flag's single argument joined with "=", as in
+// -format=json or --format=json, and the double-dash spelling --format of
+// any -format flag. Bool flags take -verbose=true / -verbose=false.
+func (cb *CommandBuilder) AllowEquals() *CommandBuilder {
+	cb.cmd.syntax.equals = true
+	return cb
+}
+
+// AllowBundling accepts several single-letter flags in one word: -vq is
+// -v -q. All but the last must be Bool flags; the last may take arguments
+// from the following words (-vo out.txt).
+func (cb *CommandBuilder) AllowBundling() *CommandBuilder {
+	cb.cmd.syntax.bundling = true
+	return cb
+}
+
+// AllowNegation accepts -no-X for every Bool flag -X, setting it to false.
+// Useful when a Bool flag defaults to true (via Default, Env or a config
+// file).
+func (cb *CommandBuilder) AllowNegation() *CommandBuilder {
+	cb.cmd.syntax.negation = true
+	return cb
+}
+
+// ResponseFiles expands an @path argument into the words in the file before
+// anything else is parsed. See expandResponseFiles for the file format.
+func (cb *CommandBuilder) ResponseFiles() *CommandBuilder {
+	cb.cmd.responseFiles = true
+	return cb
+}
+
+// ExpressionClauses lets clauses combine as a boolean expression instead of
+// a flat list: "(" and ")" group, -and, -or and -not combine, "+" is OR and
+// "-" is AND NOT. The result is in Context.ClauseTree. The parentheses need
+// quoting in most shells: myapp \( -f a + -f b \) - -f c
+func (cb *CommandBuilder) ExpressionClauses() *CommandBuilder {
+	return cb.ExpressionOperators("-and", "-or", "-not")
+}
+
+// ExpressionOperators turns on ExpressionClauses with other spellings for
+// the AND, OR and NOT operators
+func (cb *CommandBuilder) ExpressionOperators(and, or, not string) *CommandBuilder {
+	cb.cmd.expr = &exprSyntax{and: and, or: or, not: not}
+	return cb
+}
+
 // PrefixHandler sets how to interpret + prefix on flags
 func (cb *CommandBuilder) PrefixHandler(h PrefixHandler) *CommandBuilder {
 	cb.cmd.prefixHandler = h
@@ -98,6 +240,30 @@ func (cb *CommandBuilder) Build() *Command {
 		panic(fmt.Sprintf("positional validation failed: %v", err))
 	}
 
+	// Validate flag groups
+	if err := resolveGroupFlags(cb.cmd.groups, cb.cmd.flags, nil); err != nil {
+		panic(fmt.Sprintf("flag group validation failed: %v", err))
+	}
+
+	// Validate flag dependencies
+	if err := resolveFlagDependencies(cb.cmd.flags, nil); err != nil {
+		panic(fmt.Sprintf("flag dependency validation failed: %v", err))
+	}
+
+	// Validate per-clause rules
+	if err := resolveClauseRules(cb.cmd.flags); err != nil {
+		panic(fmt.Sprintf("clause rule validation failed: %v", err))
+	}
+
+	// Add the -config override when configuration files are declared
+	cb.cmd.addConfigFlag()
+
+	// Add -timeout when requested
+	cb.cmd.addTimeoutFlag()
+
+	// Add -yes when requested
+	cb.cmd.addYesFlag()
+
 	return cb.cmd
 }
 
@@ -161,9 +327,7 @@ func (fb *FlagBuilder) ArgName(index int, name string) *FlagBuilder {
 
 // ArgType sets the type for a specific argument
 func (fb *FlagBuilder) ArgType(index int, t ArgType) *FlagBuilder {
-	if index >= 0 && index < len(fb.spec.ArgTypes) {
-		fb.spec.ArgTypes[index] = t
-	}
+	setArgType(fb.spec, index, t)
 	return fb
 }
 
@@ -215,18 +379,131 @@ func (fb *FlagBuilder) Time() *FlagBuilder {
 	return fb.Args(1).ArgType(0, ArgTime).ArgName(0, "TIME")
 }
 
+// Bytes is a shorthand for a single byte-size argument ("512MiB", "1.5G")
+func (fb *FlagBuilder) Bytes() *FlagBuilder {
+	return fb.Args(1).ArgType(0, ArgBytes).ArgName(0, "SIZE")
+}
+
+// IP is a shorthand for a single IP address argument
+func (fb *FlagBuilder) IP() *FlagBuilder {
+	return fb.Args(1).ArgType(0, ArgIP).ArgName(0, "IP")
+}
+
+// CIDR is a shorthand for a single CIDR block argument ("10.0.0.0/8")
+func (fb *FlagBuilder) CIDR() *FlagBuilder {
+	return fb.Args(1).ArgType(0, ArgCIDR).ArgName(0, "CIDR")
+}
+
+// URL is a shorthand for a single absolute URL argument
+func (fb *FlagBuilder) URL() *FlagBuilder {
+	return fb.Args(1).ArgType(0, ArgURL).ArgName(0, "URL")
+}
+
+// Regexp is a shorthand for a single regular expression argument
+func (fb *FlagBuilder) Regexp() *FlagBuilder {
+	return fb.Args(1).ArgType(0, ArgRegexp).ArgName(0, "REGEXP")
+}
+
+// Enum is a shorthand for a single argument that must be one of values.
+// The values are also offered for completion.
+func (fb *FlagBuilder) Enum(values ...string) *FlagBuilder {
+	fb.Args(1).ArgName(0, "VALUE")
+	setEnum(fb.spec, 0, values)
+	return fb
+}
+
+// Count makes a flag that takes no argument and counts its occurrences:
+// -v -v -v yields the int 3, and no -v at all yields 0
+func (fb *FlagBuilder) Count() *FlagBuilder {
+	fb.Bool()
+	fb.spec.IsCounter = true
+	if fb.spec.Default == nil {
+		fb.spec.Default = 0
+	}
+	return fb
+}
+
+// OptionalValue lets the flag's single argument be omitted, storing value
+// when it is: -color stores value, while -color always and -color=always
+// (with or without AllowEquals) store "always". The next word is the
+// argument only if it parses as one and isn't a flag, clause separator or
+// subcommand name; otherwise it is left for what follows. A flag with no
+// arguments yet gets a single string argument.
+func (fb *FlagBuilder) OptionalValue(value interface{}) *FlagBuilder {
+	if fb.spec.ArgCount == 0 {
+		fb.String()
+	}
+	if fb.spec.ArgCount != 1 {
+		panic(fmt.Sprintf("flag %s: OptionalValue requires a single-argument flag", fb.spec.Names[0]))
+	}
+	fb.spec.OptionalArg = true
+	fb.spec.BareValue = value
+	return fb
+}
+
 // Required marks the flag as required
 func (fb *FlagBuilder) Required() *FlagBuilder {
 	fb.spec.Required = true
 	return fb
 }
 
+// RequiredIf marks the flag as required when flag has the given value
+// (e.g. RequiredIf("-mode", "remote"))
+func (fb *FlagBuilder) RequiredIf(flag string, value interface{}) *FlagBuilder {
+	fb.spec.RequiredIf = append(fb.spec.RequiredIf, Condition{Flag: flag, Value: value})
+	return fb
+}
+
+// RequiredInEachClause requires a local flag in every clause, rather than
+// in at least one as Required does. Build panics if the flag is global.
+func (fb *FlagBuilder) RequiredInEachClause() *FlagBuilder {
+	fb.spec.RequiredInEachClause = true
+	return fb
+}
+
+// MaxPerClause limits how many times an accumulated or counted flag may be
+// given in one clause. Build panics if the flag is global or neither
+// Accumulate nor Count.
+func (fb *FlagBuilder) MaxPerClause(n int) *FlagBuilder {
+	fb.spec.MaxPerClause = n
+	return fb
+}
+
+// DependsOn makes the flag's value wait until the named flags have their
+// final values, whatever order they appear in, so ParseWith (or a time
+// flag's zone) can use them. Dependencies may be local or global, but a
+// global flag can only depend on global flags. Cycles panic at Build.
+func (fb *FlagBuilder) DependsOn(flags ...string) *FlagBuilder {
+	fb.spec.DependsOn = append(fb.spec.DependsOn, flags...)
+	return fb
+}
+
+// ParseWith parses the flag's argument with fn, which is given the values
+// of the flags listed in DependsOn:
+//
+//	Flag("-size").DependsOn("-unit").ParseWith(func(v string, deps map[string]interface{}) (interface{}, error) {
+//	    n, err := strconv.ParseFloat(v, 64)
+//	    return n * unitSize[deps["-unit"].(string)], err
+//	})
+func (fb *FlagBuilder) ParseWith(fn DependentParser) *FlagBuilder {
+	fb.spec.ParseFunc = fn
+	return fb
+}
+
 // Default sets the default value
 func (fb *FlagBuilder) Default(value interface{}) *FlagBuilder {
 	fb.spec.Default = value
 	return fb
 }
 
+// Env sets an environment variable to read the flag's value from when it is
+// absent from argv. The value goes through the same parsing as a command-line
+// argument and takes precedence over Default.
+func (fb *FlagBuilder) Env(name string) *FlagBuilder {
+	fb.spec.EnvVar = name
+	return fb
+}
+
 // Validate sets a validation function
 func (fb *FlagBuilder) Validate(fn ValidatorFunc) *FlagBuilder {
 	fb.spec.Validator = fn
@@ -262,6 +539,25 @@ func (fb *FlagBuilder) Hidden() *FlagBuilder {
 	return fb
 }
 
+// Secret marks the flag's value as sensitive. It is replaced by "********"
+// in Context.RawArgs and RedactArgs, its default isn't shown in help or man
+// pages, errors don't quote it, and it isn't echoed when asked for
+// interactively (see PromptForMissing).
+func (fb *FlagBuilder) Secret() *FlagBuilder {
+	fb.spec.Secret = true
+	return fb
+}
+
+// SecretFile makes the flag Secret and adds a -NAME-file FILE flag that
+// reads the value from a file instead, keeping it out of the process list
+// and shell history. Trailing newlines are removed. The flag must take one
+// argument.
+func (fb *FlagBuilder) SecretFile() *FlagBuilder {
+	fb.spec.Secret = true
+	fb.spec.SecretFile = true
+	return fb
+}
+
 // Accumulate marks the flag to accumulate multiple values (for multi-arg or single-arg flags)
 func (fb *FlagBuilder) Accumulate() *FlagBuilder {
 	fb.spec.IsSlice = true
@@ -332,9 +628,13 @@ func (fb *FlagBuilder) Arg(name string) *ArgBuilder {
 
 // Type sets the type for this argument
 func (ab *ArgBuilder) Type(t ArgType) *ArgBuilder {
-	if ab.argIndex >= 0 && ab.argIndex < len(ab.fb.spec.ArgTypes) {
-		ab.fb.spec.ArgTypes[ab.argIndex] = t
-	}
+	setArgType(ab.fb.spec, ab.argIndex, t)
+	return ab
+}
+
+// Enum restricts this argument to values and offers them for completion
+func (ab *ArgBuilder) Enum(values ...string) *ArgBuilder {
+	setEnum(ab.fb.spec, ab.argIndex, values)
 	return ab
 }
 
@@ -391,12 +691,19 @@ func (ab *ArgBuilder) Done() *FlagBuilder {
 
 // Done finalizes the flag and returns to the command or subcommand builder
 func (fb *FlagBuilder) Done() *CommandBuilder {
+	fileFlag := secretFileFlag(fb.spec)
 	if fb.sb != nil {
 		// Subcommand flag
 		fb.sb.subcmd.Flags = append(fb.sb.subcmd.Flags, fb.spec)
+		if fileFlag != nil {
+			fb.sb.subcmd.Flags = append(fb.sb.subcmd.Flags, fileFlag)
+		}
 		return fb.sb.root // Return root CommandBuilder for fluent chaining
 	}
 	// Command flag
 	fb.cb.cmd.flags = append(fb.cb.cmd.flags, fb.spec)
+	if fileFlag != nil {
+		fb.cb.cmd.flags = append(fb.cb.cmd.flags, fileFlag)
+	}
 	return fb.cb
 }
@@ -0,0 +1,206 @@
+package completionflags
+
+import "time"
+
+// Get returns flags[name] as a T, reporting whether it was present and of
+// that type. flags is typically Context.GlobalFlags, Clause.Flags or a Record:
+//
+//	limit, ok := cf.Get[int](ctx.GlobalFlags, "-limit")
+func Get[T any](flags map[string]interface{}, name string) (T, bool) {
+	v, ok := flags[name].(T)
+	return v, ok
+}
+
+// GetSlice returns the values of an accumulated flag that are a T, or a
+// single-element slice for a flag given once
+func GetSlice[T any](flags map[string]interface{}, name string) []T {
+	switch v := flags[name].(type) {
+	case nil:
+		return nil
+	case []interface{}:
+		out := make([]T, 0, len(v))
+		for _, item := range v {
+			if t, ok := item.(T); ok {
+				out = append(out, t)
+			}
+		}
+		return out
+	case T:
+		return []T{v}
+	}
+	return nil
+}
+
+// Record holds one occurrence of a multi-argument flag, keyed by Arg name:
+// -filter status eq active gives {"FIELD": "status", "OP": "eq", ...}
+type Record map[string]interface{}
+
+// GetString returns argument name as a string, or defaultValue
+func (r Record) GetString(name string, defaultValue string) string {
+	if s, ok := Get[string](r, name); ok {
+		return s
+	}
+	return defaultValue
+}
+
+// GetInt returns argument name as an int, or defaultValue
+func (r Record) GetInt(name string, defaultValue int) int {
+	if i, ok := Get[int](r, name); ok {
+		return i
+	}
+	return defaultValue
+}
+
+// GetFloat returns argument name as a float64, or defaultValue
+func (r Record) GetFloat(name string, defaultValue float64) float64 {
+	if f, ok := Get[float64](r, name); ok {
+		return f
+	}
+	return defaultValue
+}
+
+// GetBool retrieves a boolean flag value from the clause, returning defaultValue if not found or nil
+func (c Clause) GetBool(name string, defaultValue bool) bool {
+	if b, ok := Get[bool](c.Flags, name); ok {
+		return b
+	}
+	return defaultValue
+}
+
+// GetString retrieves a string flag value from the clause, returning defaultValue if not found or nil
+func (c Clause) GetString(name string, defaultValue string) string {
+	if s, ok := Get[string](c.Flags, name); ok {
+		return s
+	}
+	return defaultValue
+}
+
+// GetInt retrieves an int flag value from the clause, returning defaultValue if not found or nil
+func (c Clause) GetInt(name string, defaultValue int) int {
+	if i, ok := Get[int](c.Flags, name); ok {
+		return i
+	}
+	return defaultValue
+}
+
+// GetFloat retrieves a float64 flag value from the clause, returning defaultValue if not found or nil
+func (c Clause) GetFloat(name string, defaultValue float64) float64 {
+	if f, ok := Get[float64](c.Flags, name); ok {
+		return f
+	}
+	return defaultValue
+}
+
+// GetDuration retrieves a time.Duration flag value from the clause, returning defaultValue if not found or nil
+func (c Clause) GetDuration(name string, defaultValue time.Duration) time.Duration {
+	if d, ok := Get[time.Duration](c.Flags, name); ok {
+		return d
+	}
+	return defaultValue
+}
+
+// GetAll returns every occurrence of a flag in the clause as a Record, in
+// command-line order. A single-argument flag's value is keyed "VALUE".
+func (c Clause) GetAll(name string) []Record {
+	var occurrences []interface{}
+	switch v := c.Flags[name].(type) {
+	case nil:
+		return nil
+	case []interface{}:
+		occurrences = v
+	default:
+		occurrences = []interface{}{v}
+	}
+
+	records := make([]Record, 0, len(occurrences))
+	for _, occ := range occurrences {
+		if m, ok := occ.(map[string]interface{}); ok {
+			records = append(records, Record(m))
+		} else {
+			records = append(records, Record{"VALUE": occ})
+		}
+	}
+	return records
+}
+
+// isEmpty reports whether the clause was given no flags or positionals on
+// the command line. Values filled in by a default, the environment or a
+// config file don't count; a clause built by hand has no sources, and all of
+// its flags count.
+func (c Clause) isEmpty() bool {
+	if len(c.Positional) > 0 {
+		return false
+	}
+	for name := range c.Flags {
+		if c.sources == nil || c.sources[name] == SourceArgs {
+			return false
+		}
+	}
+	return true
+}
+
+// ClauseSet applies the conventional meaning of clause separators: the first
+// clause and each "+" clause is an alternative to include (OR), and each "-"
+// clause describes items to exclude. Any other separator includes.
+type ClauseSet struct {
+	Include []Clause
+	Exclude []Clause
+}
+
+// NewClauseSet sorts clauses into includes and excludes. Include clauses
+// given nothing on the command line are dropped, defaulted local flags
+// notwithstanding, so `tool - -filter ...` starts from everything.
+func NewClauseSet(clauses []Clause) ClauseSet {
+	var set ClauseSet
+	for _, clause := range clauses {
+		switch {
+		case clause.Separator == "-":
+			set.Exclude = append(set.Exclude, clause)
+		case !clause.isEmpty():
+			set.Include = append(set.Include, clause)
+		}
+	}
+	return set
+}
+
+// ClauseSet returns the context's clauses as a ClauseSet
+func (ctx *Context) ClauseSet() ClauseSet {
+	return NewClauseSet(ctx.Clauses)
+}
+
+// Match reports whether an item is selected: match must hold for at least
+// one include clause (or there are none) and for no exclude clause.
+//
+//	set := ctx.ClauseSet()
+//	for _, row := range rows {
+//	    if set.Match(func(c cf.Clause) bool { return rowMatches(row, c) }) {
+//	        // keep row
+//	    }
+//	}
+func (s ClauseSet) Match(match func(Clause) bool) bool {
+	for _, clause := range s.Exclude {
+		if match(clause) {
+			return false
+		}
+	}
+	if len(s.Include) == 0 {
+		return true
+	}
+	for _, clause := range s.Include {
+		if match(clause) {
+			return true
+		}
+	}
+	return false
+}
+
+// Filter returns the items selected by set, as decided by Match
+func Filter[T any](set ClauseSet, items []T, match func(item T, clause Clause) bool) []T {
+	var out []T
+	for _, item := range items {
+		if set.Match(func(c Clause) bool { return match(item, c) }) {
+			out = append(out, item)
+		}
+	}
+	return out
+}
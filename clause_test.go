@@ -0,0 +1,151 @@
+package completionflags
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestClause_Accessors(t *testing.T) {
+	var ctx *Context
+	cmd := NewCommand("tool").
+		Flag("-limit").Int().Global().Done().
+		Flag("-tag").StringSlice().Global().Done().
+		Flag("-sort").String().Local().Done().
+		Flag("-min").Int().Local().Done().
+		Flag("-filter").
+		Arg("FIELD").Done().
+		Arg("OP").Done().
+		Arg("VALUE").Done().
+		Accumulate().
+		Local().
+		Done().
+		Handler(func(c *Context) error {
+			ctx = c
+			return nil
+		}).
+		Build()
+
+	err := cmd.Execute([]string{
+		"-limit", "5", "-tag", "a", "-tag", "b",
+		"-filter", "status", "eq", "active", "-filter", "role", "eq", "admin", "-sort", "name", "-min", "3",
+	})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	if limit, ok := Get[int](ctx.GlobalFlags, "-limit"); !ok || limit != 5 {
+		t.Errorf("Get[int] = %d, %v", limit, ok)
+	}
+	if _, ok := Get[string](ctx.GlobalFlags, "-limit"); ok {
+		t.Error("Get[string] of an int flag should fail")
+	}
+	if tags := GetSlice[string](ctx.GlobalFlags, "-tag"); !reflect.DeepEqual(tags, []string{"a", "b"}) {
+		t.Errorf("GetSlice = %v", tags)
+	}
+
+	clause := ctx.Clauses[0]
+	if got := clause.GetString("-sort", ""); got != "name" {
+		t.Errorf("GetString = %q", got)
+	}
+	if got := clause.GetInt("-min", 0); got != 3 {
+		t.Errorf("GetInt = %d", got)
+	}
+	if got := clause.GetBool("-missing", true); !got {
+		t.Error("GetBool should return the default")
+	}
+
+	filters := clause.GetAll("-filter")
+	if len(filters) != 2 || filters[1].GetString("FIELD", "") != "role" || filters[0].GetString("VALUE", "") != "active" {
+		t.Errorf("GetAll(-filter) = %v", filters)
+	}
+	if got := clause.GetAll("-sort"); len(got) != 1 || got[0].GetString("VALUE", "") != "name" {
+		t.Errorf("GetAll(-sort) = %v", got)
+	}
+	if got := clause.GetAll("-missing"); got != nil {
+		t.Errorf("GetAll(-missing) = %v", got)
+	}
+}
+
+func TestClauseSet(t *testing.T) {
+	var ctx *Context
+	cmd := NewCommand("tool").
+		Flag("-filter").
+		Arg("FIELD").Done().
+		Arg("OP").Done().
+		Arg("VALUE").Done().
+		Accumulate().
+		Local().
+		Done().
+		Handler(func(c *Context) error {
+			ctx = c
+			return nil
+		}).
+		Build()
+
+	rows := []map[string]string{
+		{"status": "active", "role": "admin"},
+		{"status": "active", "role": "user"},
+		{"status": "idle", "role": "admin"},
+		{"status": "idle", "role": "user"},
+	}
+	matches := func(row map[string]string, c Clause) bool {
+		for _, f := range c.GetAll("-filter") {
+			if row[f.GetString("FIELD", "")] != f.GetString("VALUE", "") {
+				return false
+			}
+		}
+		return true
+	}
+
+	tests := []struct {
+		args []string
+		want []int
+	}{
+		{[]string{"-filter", "status", "eq", "active"}, []int{0, 1}},
+		{[]string{"-filter", "status", "eq", "active", "+", "-filter", "role", "eq", "admin"}, []int{0, 1, 2}},
+		{[]string{"-filter", "status", "eq", "active", "-", "-filter", "role", "eq", "user"}, []int{0}},
+		{[]string{"-", "-filter", "role", "eq", "user"}, []int{0, 2}},
+	}
+	for _, tt := range tests {
+		if err := cmd.Execute(tt.args); err != nil {
+			t.Fatalf("%v: %v", tt.args, err)
+		}
+		got := Filter(ctx.ClauseSet(), rows, matches)
+		var want []map[string]string
+		for _, i := range tt.want {
+			want = append(want, rows[i])
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%v: got %v, want %v", tt.args, got, want)
+		}
+	}
+}
+
+func TestClauseSet_DefaultsDontCount(t *testing.T) {
+	var ctx *Context
+	cmd := NewCommand("tool").
+		Flag("-filter").String().Local().Done().
+		Flag("-limit").Int().Default(10).Local().Done().
+		Handler(func(c *Context) error {
+			ctx = c
+			return nil
+		}).
+		Build()
+
+	if err := cmd.Execute([]string{"-", "-filter", "x"}); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	// The first clause only has the default -limit, so it includes nothing
+	// and the set starts from everything
+	set := ctx.ClauseSet()
+	if len(set.Include) != 0 || len(set.Exclude) != 1 {
+		t.Errorf("got %d include and %d exclude clauses, want 0 and 1", len(set.Include), len(set.Exclude))
+	}
+
+	if err := cmd.Execute([]string{"-limit", "5", "-", "-filter", "x"}); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if set := ctx.ClauseSet(); len(set.Include) != 1 {
+		t.Errorf("a clause given -limit on the command line was dropped")
+	}
+}
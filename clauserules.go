@@ -0,0 +1,107 @@
+package completionflags
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// ClauseRules constrains the clauses of a command or subcommand (see
+// MinClauses, MaxClauses and ClauseValidator)
+type ClauseRules struct {
+	Min        int                  // Fewest clauses allowed (0 = no minimum)
+	Max        int                  // Most clauses allowed (0 = no maximum)
+	Validators []func(Clause) error // Run on every clause
+}
+
+// clauseLabel names clause i for error messages: `clause 0` or
+// `clause 2 (after "+")`
+func clauseLabel(i int, clause Clause) string {
+	if clause.Separator == "" {
+		return fmt.Sprintf("clause %d", i)
+	}
+	return fmt.Sprintf("clause %d (after %s)", i, strconv.Quote(clause.Separator))
+}
+
+// validateClauseCount enforces MinClauses and MaxClauses
+func (cmd *Command) validateClauseCount(ctx *Context) error {
+	n := len(ctx.Clauses)
+	if min := cmd.clauseRules.Min; min > 0 && n < min {
+		return ValidationError{Message: fmt.Sprintf("at least %d clauses required, got %d", min, n)}
+	}
+	if max := cmd.clauseRules.Max; max > 0 && n > max {
+		return ValidationError{Message: fmt.Sprintf("at most %d clauses allowed, got %d", max, n)}
+	}
+	return nil
+}
+
+// resolveClauseRules checks the per-clause rules of flags at Build time:
+// RequiredInEachClause and MaxPerClause need a local flag, and MaxPerClause
+// an Accumulate or Count one, since a plain flag keeps only its last value.
+func resolveClauseRules(flags []*FlagSpec) error {
+	for _, spec := range flags {
+		if !spec.RequiredInEachClause && spec.MaxPerClause <= 0 {
+			continue
+		}
+		if spec.Scope == ScopeGlobal {
+			return fmt.Errorf("flag %s: per-clause rules require a local flag", spec.Names[0])
+		}
+		if spec.MaxPerClause > 0 && !spec.IsSlice && !spec.IsCounter {
+			return fmt.Errorf("flag %s: MaxPerClause requires an Accumulate or Count flag", spec.Names[0])
+		}
+	}
+	return nil
+}
+
+// validatePerClause enforces RequiredInEachClause and MaxPerClause for a
+// local flag
+func validatePerClause(ctx *Context, spec *FlagSpec) error {
+	if spec.Scope == ScopeGlobal || (!spec.RequiredInEachClause && spec.MaxPerClause <= 0) {
+		return nil
+	}
+	name := spec.Names[0]
+	for i, clause := range ctx.Clauses {
+		value, exists := clause.Flags[name]
+		if !exists {
+			if spec.RequiredInEachClause {
+				return ValidationError{
+					Flag:    fmt.Sprintf("%s in %s", name, clauseLabel(i, clause)),
+					Message: "required in every clause",
+				}
+			}
+			continue
+		}
+		if n := occurrences(spec, value); spec.MaxPerClause > 0 && n > spec.MaxPerClause {
+			return ValidationError{
+				Flag:    fmt.Sprintf("%s in %s", name, clauseLabel(i, clause)),
+				Message: fmt.Sprintf("given %d times, at most %d allowed", n, spec.MaxPerClause),
+			}
+		}
+	}
+	return nil
+}
+
+// occurrences returns how many times a flag was given, judging by its value
+func occurrences(spec *FlagSpec, value interface{}) int {
+	switch {
+	case spec.IsCounter:
+		n, _ := value.(int)
+		return n
+	case spec.IsSlice:
+		if values, ok := value.([]interface{}); ok {
+			return len(values)
+		}
+	}
+	return 1
+}
+
+// runClauseValidators runs the ClauseValidator hooks on every clause
+func (cmd *Command) runClauseValidators(ctx *Context) error {
+	for i, clause := range ctx.Clauses {
+		for _, fn := range cmd.clauseRules.Validators {
+			if err := fn(clause); err != nil {
+				return ValidationError{Flag: clauseLabel(i, clause), Message: err.Error()}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package completionflags
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestClauseRules(t *testing.T) {
+	cmd := NewCommand("tool").
+		MinClauses(1).
+		MaxClauses(3).
+		ClauseValidator(func(c Clause) error {
+			if c.GetString("-field", "") == "secret" {
+				return errors.New("field secret is not allowed")
+			}
+			return nil
+		}).
+		Flag("-field").String().Local().RequiredInEachClause().Done().
+		Flag("-sort").StringSlice().Local().MaxPerClause(2).Done().
+		Handler(func(ctx *Context) error { return nil }).
+		Build()
+
+	tests := []struct {
+		args string
+		want string
+	}{
+		{"-field a + -field b", ""},
+		{"-field a + -sort x", `-field in clause 1 (after "+"): required in every clause`},
+		{"-sort x", "-field in clause 0: required in every clause"},
+		{"-field a -sort x -sort y -sort z", "-sort in clause 0: given 3 times, at most 2 allowed"},
+		{"-field a + -field b + -field c + -field d", "at most 3 clauses allowed, got 4"},
+		{"-field a - -field secret", `clause 1 (after "-"): field secret is not allowed`},
+	}
+	for _, tt := range tests {
+		err := cmd.Execute(strings.Fields(tt.args))
+		if tt.want == "" {
+			if err != nil {
+				t.Errorf("%s: unexpected error %v", tt.args, err)
+			}
+			continue
+		}
+		var verr ValidationError
+		if !errors.As(err, &verr) || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: got %v, want ValidationError containing %q", tt.args, err, tt.want)
+		}
+	}
+
+	if help := cmd.GenerateHelp(); !strings.Contains(help, "Required in every clause") || !strings.Contains(help, "At most 2 per clause") {
+		t.Errorf("help missing per-clause notes:\n%s", help)
+	}
+}
+
+func TestClauseRules_Subcommand(t *testing.T) {
+	cmd := NewCommand("tool").
+		Subcommand("join").
+		MinClauses(2).
+		Flag("-on").String().Local().RequiredInEachClause().Done().
+		Handler(func(ctx *Context) error { return nil }).
+		Done().
+		Build()
+
+	if err := cmd.Execute([]string{"join", "-on", "id"}); err == nil || !strings.Contains(err.Error(), "at least 2 clauses required, got 1") {
+		t.Errorf("got %v", err)
+	}
+	if err := cmd.Execute([]string{"join", "-on", "id", "+", "-on", "ref"}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestClauseRules_BuildErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		build func()
+		want  string
+	}{
+		{"max on plain flag", func() {
+			NewCommand("tool").
+				Flag("-sort").String().Local().MaxPerClause(1).Done().
+				Handler(func(ctx *Context) error { return nil }).
+				Build()
+		}, "flag -sort: MaxPerClause requires an Accumulate or Count flag"},
+		{"required in each on global", func() {
+			NewCommand("tool").
+				Flag("-field").String().Global().RequiredInEachClause().Done().
+				Handler(func(ctx *Context) error { return nil }).
+				Build()
+		}, "flag -field: per-clause rules require a local flag"},
+		{"subcommand", func() {
+			NewCommand("tool").
+				Subcommand("join").
+				Flag("-on").String().Global().RequiredInEachClause().Done().
+				Handler(func(ctx *Context) error { return nil }).
+				Done().
+				Build()
+		}, `subcommand "join" clause rule validation failed`},
+	}
+	for _, tt := range tests {
+		func() {
+			defer func() {
+				r := recover()
+				if r == nil || !strings.Contains(fmt.Sprint(r), tt.want) {
+					t.Errorf("%s: panic = %v, want %q", tt.name, r, tt.want)
+				}
+			}()
+			tt.build()
+		}()
+	}
+}
@@ -37,6 +37,22 @@ type CompletionContext struct {
 	// argv alone.
 	UpstreamFields []string // field names flowing in from upstream (e.g. a prior pipeline stage)
 	State          any      // host-service state, the completion-time analogue of Context.State
+
+	// inlinePrefix is the "-flag=" text of a -flag=value word under the
+	// cursor; Partial then holds only the value and results are re-prefixed
+	inlinePrefix string
+}
+
+// precedingArgs returns the words before the one being completed
+func (ctx CompletionContext) precedingArgs() []string {
+	end := ctx.Position - 1
+	if end > len(ctx.Args) {
+		end = len(ctx.Args)
+	}
+	if end < 0 {
+		return nil
+	}
+	return ctx.Args[:end]
 }
 
 // CompletionFunc is a function-based completer
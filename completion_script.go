@@ -5,6 +5,7 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 )
@@ -170,6 +171,8 @@ _autocli_complete() {
 _autocli_process_output() {
     local output="$1"
     local cur="${COMP_WORDS[COMP_CWORD]}"
+    # Bash splits -flag=value at "="; right after it there is no prefix yet
+    [[ "$cur" == "=" ]] && cur=""
 
     # Parse JSON directives if jq is available
     if command -v jq &>/dev/null; then
@@ -302,16 +305,29 @@ func (cmd *Command) CompleteWithContext(args []string, pos int, seed CompletionC
 type completionSeed struct {
 	upstreamFields []string
 	state          any
+
+	// splitValue is set by complete when bash split the -flag=value word
+	// under the cursor at "=", so only the value is completed
+	splitValue bool
 }
 
 // apply copies the seeded fields onto an engine-built context.
 func (s completionSeed) apply(ctx *CompletionContext) {
 	ctx.UpstreamFields = s.upstreamFields
 	ctx.State = s.state
+	if s.splitValue {
+		ctx.inlinePrefix = ""
+	}
 }
 
 // complete generates completions for a given position
 func (cmd *Command) complete(args []string, pos int, seed completionSeed) ([]string, error) {
+	args, pos, seed.splitValue = cmd.splitEqualsWords(args, pos)
+
+	if matches, ok := cmd.completeResponseFile(args, pos); ok {
+		return matches, nil
+	}
+
 	// Check if we have subcommands
 	if len(cmd.subcommands) > 0 {
 		return cmd.completeWithSubcommands(args, pos, seed)
@@ -347,8 +363,11 @@ func (cmd *Command) analyzeCompletionContext(args []string, pos int) CompletionC
 	// Adjust pos for the rest of the analysis to work with args indices
 	pos = argIndex
 
+	// A -flag=value word completes the value after the "="
+	inlineSpec, prefix, value, inline := cmd.inlineValuePartial(ctx.Partial)
+
 	// If completing a flag name
-	if strings.HasPrefix(ctx.Partial, "-") || strings.HasPrefix(ctx.Partial, "+") {
+	if !inline && (strings.HasPrefix(ctx.Partial, "-") || strings.HasPrefix(ctx.Partial, "+")) {
 		// Complete flag names
 		return ctx
 	}
@@ -362,15 +381,26 @@ func (cmd *Command) analyzeCompletionContext(args []string, pos int) CompletionC
 	if parseUpTo > len(args) {
 		parseUpTo = len(args)
 	}
-	partialCtx, _ := cmd.Parse(args[:parseUpTo])
+	partialCtx := cmd.parseForCompletion(args[:parseUpTo])
 	if partialCtx != nil {
 		ctx.ParsedClauses = partialCtx.Clauses
 		ctx.GlobalFlags = partialCtx.GlobalFlags
-		if len(partialCtx.Clauses) > 0 {
+		if partialCtx.trailing != nil {
+			// Expression clauses: the clause the cursor is in may be empty
+			ctx.CurrentClause = partialCtx.trailing
+		} else if len(partialCtx.Clauses) > 0 {
 			ctx.CurrentClause = &partialCtx.Clauses[len(partialCtx.Clauses)-1]
 		}
 	}
 
+	if inline {
+		ctx.FlagName = inlineSpec.Names[0]
+		ctx.ArgIndex = 0
+		ctx.Partial = value
+		ctx.inlinePrefix = prefix
+		return ctx
+	}
+
 	// Find the flag we're completing an argument for
 	// When pos >= len(args), we need to look at the last actual arg
 	searchPos := pos
@@ -390,6 +420,12 @@ func (cmd *Command) analyzeCompletionContext(args []string, pos int) CompletionC
 			actualPos = pos - 1
 		}
 		ctx.ArgIndex = actualPos - flagPos - 1
+		if spec.OptionalArg && ctx.ArgIndex != 0 {
+			// Only the word right after an OptionalValue flag can be its
+			// value
+			ctx.FlagName, ctx.ArgIndex = "", 0
+			return ctx
+		}
 
 		// Collect previous arguments of this flag
 		if ctx.ArgIndex > 0 && flagPos+1 < len(args) {
@@ -421,16 +457,19 @@ func (cmd *Command) findLastFlag(args []string, pos int) (int, *FlagSpec) {
 
 		// Check if this is a flag
 		if strings.HasPrefix(arg, "-") || strings.HasPrefix(arg, "+") {
-			// Normalize to find spec
-			normalized := arg
-			if strings.HasPrefix(arg, "+") {
-				normalized = "-" + arg[1:]
+			spec, word := cmd.resolveFlagWord(arg, cmd.findFlagSpec)
+			if spec == nil {
+				// A bundle's arguments belong to its last flag
+				if bundle := cmd.splitBundle(arg, cmd.findFlagSpec); bundle != nil {
+					return i, bundle[len(bundle)-1]
+				}
+				continue
 			}
-
-			spec := cmd.findFlagSpec(normalized)
-			if spec != nil {
-				return i, spec
+			if word.inline {
+				// -flag=value already has its argument
+				return -1, nil
 			}
+			return i, spec
 		}
 	}
 
@@ -440,8 +479,8 @@ func (cmd *Command) findLastFlag(args []string, pos int) (int, *FlagSpec) {
 // executeCompletion executes the appropriate completion based on context
 func (cmd *Command) executeCompletion(ctx CompletionContext) ([]string, error) {
 	// Case 1: Completing a flag name
-	if strings.HasPrefix(ctx.Partial, "-") || strings.HasPrefix(ctx.Partial, "+") {
-		return cmd.completeFlags(ctx.Partial), nil
+	if ctx.inlinePrefix == "" && (strings.HasPrefix(ctx.Partial, "-") || strings.HasPrefix(ctx.Partial, "+")) {
+		return append(cmd.completeFlags(ctx.Partial), cmd.exprCompletions(ctx.precedingArgs(), ctx.Partial)...), nil
 	}
 
 	// Case 2: Completing a flag argument
@@ -449,7 +488,18 @@ func (cmd *Command) executeCompletion(ctx CompletionContext) ([]string, error) {
 		spec := cmd.findFlagSpec(ctx.FlagName)
 		if spec != nil && ctx.ArgIndex >= 0 && ctx.ArgIndex < len(spec.ArgCompleters) {
 			completer := spec.ArgCompleters[ctx.ArgIndex]
-			return completer.Complete(ctx)
+			matches, err := completer.Complete(ctx)
+			if ctx.inlinePrefix != "" {
+				for i := range matches {
+					matches[i] = ctx.inlinePrefix + matches[i]
+				}
+			} else if spec.OptionalArg && err == nil {
+				// The word after an OptionalValue flag may instead be the
+				// next positional
+				positional, err := cmd.completePositional(ctx)
+				return append(matches, positional...), err
+			}
+			return matches, err
 		}
 		// If ArgIndex is out of bounds for this flag, fall through
 	}
@@ -466,7 +516,7 @@ func (cmd *Command) executeCompletion(ctx CompletionContext) ([]string, error) {
 	// Case 4: Empty partial - show all flags
 	// This handles both "no flag context" and "flag arguments exhausted"
 	if ctx.Partial == "" {
-		return cmd.completeFlags(""), nil
+		return append(cmd.completeFlags(""), cmd.exprCompletions(ctx.precedingArgs(), "")...), nil
 	}
 
 	// Case 5: Default to no completions
@@ -475,7 +525,7 @@ func (cmd *Command) executeCompletion(ctx CompletionContext) ([]string, error) {
 
 // completeFlags generates flag name completions.
 func (cmd *Command) completeFlags(partial string) []string {
-	return completeFlagSet(cmd.flags, partial)
+	return append(completeFlagSet(cmd.flags, partial), cmd.negationCompletions(cmd.flags, partial)...)
 }
 
 // completeFlagSet builds flag-name completions with "declutter" rules so a
@@ -547,7 +597,7 @@ func completeBuiltinFlags(pl string, specific bool) []string {
 	if pl == "" || pl == "-" || strings.HasPrefix("--help", pl) {
 		out = append(out, "--help")
 	}
-	for _, b := range []string{"-help", "-h", "-man", "-completion-script"} {
+	for _, b := range []string{"-help", "-h", "-man", "-explain", "-explain-json", "-schema", "-completion-script"} {
 		if specific && strings.HasPrefix(b, pl) {
 			out = append(out, b)
 		}
@@ -574,7 +624,12 @@ func (cmd *Command) completePositional(ctx CompletionContext) ([]string, error)
 			// Check if this is a flag that takes arguments
 			if strings.HasPrefix(arg, "-") || strings.HasPrefix(arg, "+") {
 				spec := cmd.findFlagSpec(arg)
-				if spec != nil {
+				if spec != nil && spec.OptionalArg {
+					// Skip the word an OptionalValue flag takes as its value
+					if _, ok := cmd.optionalValue(spec, ctx.Args[:argIndex], i, ctx.GlobalFlags); ok {
+						i++
+					}
+				} else if spec != nil {
 					// Skip this flag's arguments
 					i += spec.ArgCount
 				}
@@ -634,6 +689,13 @@ func (cmd *Command) completeWithSubcommands(args []string, pos int, seed complet
 			partial = args[argIndex]
 		}
 
+		// -flag=value: complete the value of a root flag
+		if _, _, _, ok := cmd.inlineValuePartial(partial); ok {
+			ctx := cmd.analyzeCompletionContext(args, pos)
+			seed.apply(&ctx)
+			return cmd.executeCompletion(ctx)
+		}
+
 		// If completing a flag, show root global flags
 		if strings.HasPrefix(partial, "-") || strings.HasPrefix(partial, "+") {
 			return cmd.completeRootGlobalFlags(partial), nil
@@ -652,8 +714,7 @@ func (cmd *Command) completeWithSubcommands(args []string, pos int, seed complet
 
 		// Walk the tree as far as we have confirmed subcommands
 		for argIndex < len(remaining) && argIndex < remainingPos {
-			subcommandName := remaining[argIndex]
-			subcmd := currentSubcommands[subcommandName]
+			subcmd, subcommandName := cmd.resolveSubcommand(currentSubcommands, remaining[argIndex])
 
 			if subcmd == nil {
 				// Not a subcommand - stop walking
@@ -689,6 +750,16 @@ func (cmd *Command) completeWithSubcommands(args []string, pos int, seed complet
 					name:       leafSubcmd.Name,
 					flags:      append(cmd.demotedRootGlobalFlags(), leafSubcmd.Flags...),
 					separators: leafSubcmd.Separators,
+					syntax:     cmd.syntax,
+					expr:       cmd.expr,
+				}
+				if _, _, _, ok := tempCmd.inlineValuePartial(partial); ok {
+					ctx := tempCmd.analyzeCompletionContext([]string{partial}, 1)
+					for k, v := range rootGlobals {
+						ctx.GlobalFlags[k] = v
+					}
+					seed.apply(&ctx)
+					return tempCmd.executeCompletion(ctx)
 				}
 				return tempCmd.completeFlagNames(partial), nil
 			}
@@ -702,6 +773,8 @@ func (cmd *Command) completeWithSubcommands(args []string, pos int, seed complet
 				name:       leafSubcmd.Name,
 				flags:      append(cmd.demotedRootGlobalFlags(), leafSubcmd.Flags...),
 				separators: leafSubcmd.Separators,
+				syntax:     cmd.syntax,
+				expr:       cmd.expr,
 			}
 			positionalCtx := CompletionContext{
 				Partial:     partial,
@@ -727,6 +800,8 @@ func (cmd *Command) completeWithSubcommands(args []string, pos int, seed complet
 				name:       leafSubcmd.Name,
 				flags:      append(cmd.demotedRootGlobalFlags(), leafSubcmd.Flags...),
 				separators: leafSubcmd.Separators,
+				syntax:     cmd.syntax,
+				expr:       cmd.expr,
 			}
 
 			// Complete using subcommand context (remaining args after subcommand path)
@@ -764,42 +839,42 @@ func (cmd *Command) completeRootGlobalFlags(partial string) []string {
 			globals = append(globals, spec)
 		}
 	}
-	return completeFlagSet(globals, partial)
+	return append(completeFlagSet(globals, partial), cmd.negationCompletions(globals, partial)...)
 }
 
 // completeSubcommandNames generates completions for subcommand names
 func (cmd *Command) completeSubcommandNames(partial string) []string {
-	var matches []string
-	partialLower := strings.ToLower(partial)
-
-	for name := range cmd.subcommands {
-		nameLower := strings.ToLower(name)
-		if partialLower == "" || strings.HasPrefix(nameLower, partialLower) {
-			matches = append(matches, name)
-		}
-	}
-
-	return matches
+	return cmd.completeNestedSubcommandNames(cmd.subcommands, partial)
 }
 
-// completeNestedSubcommandNames generates completions for nested subcommand names
+// completeNestedSubcommandNames generates completions for nested subcommand
+// names. Canonical names come first (sorted); an alias is offered only when
+// the partial matches it but not its subcommand's canonical name.
 func (cmd *Command) completeNestedSubcommandNames(subcommands map[string]*Subcommand, partial string) []string {
-	var matches []string
+	var matches, aliasMatches []string
 	partialLower := strings.ToLower(partial)
 
-	for name := range subcommands {
+	for name, subcmd := range subcommands {
 		nameLower := strings.ToLower(name)
 		if partialLower == "" || strings.HasPrefix(nameLower, partialLower) {
 			matches = append(matches, name)
+			continue
+		}
+		for _, alias := range subcmd.Aliases {
+			if partialLower != "" && strings.HasPrefix(strings.ToLower(alias), partialLower) {
+				aliasMatches = append(aliasMatches, alias)
+			}
 		}
 	}
 
-	return matches
+	sort.Strings(matches)
+	sort.Strings(aliasMatches)
+	return append(matches, aliasMatches...)
 }
 
 // completeFlagNames generates completions for flag names (used by temporary commands)
 // completeFlagNames is an alias for completeFlags (kept as a named entry
 // point for the nested-subcommand path); both share completeFlagSet.
 func (cmd *Command) completeFlagNames(partial string) []string {
-	return completeFlagSet(cmd.flags, partial)
+	return append(completeFlagSet(cmd.flags, partial), cmd.negationCompletions(cmd.flags, partial)...)
 }
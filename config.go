@@ -0,0 +1,466 @@
+package completionflags
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// configFlagName is the root-global flag added by ConfigFile that replaces the
+// declared configuration file locations with a single explicit file.
+const configFlagName = "-config"
+
+// ConfigFile declares configuration file locations, highest precedence first
+// (e.g. ConfigFile("~/.myapp.json", "/etc/myapp.json")). Files that don't
+// exist are skipped; values from earlier files win over later ones. A global
+// -config FILE flag is added at Build() (unless the command already defines
+// one) that replaces the declared list with a single, mandatory file.
+//
+// Documents are JSON or a simple INI/TOML subset. Top-level keys configure
+// root flags; nested objects (JSON) or [section] headers (INI/TOML) named
+// after a subcommand configure it, by path. An object under any other key is
+// a flag value (a multi-argument flag's words by argument name):
+//
+//	{
+//	    "-timezone": "UTC",
+//	    "query": {"-format": "json", "-limit": 50},
+//	    "remote": {"add": {"-fetch": true}}
+//	}
+//
+//	timezone = "UTC"
+//	[query]
+//	format = "json"
+//	[remote add]
+//	fetch = true
+//
+// A section sets the flags of its own subcommand, and root global flags
+// along with the top level and every section above it, the nearest winning.
+//
+// Precedence is argv > environment (Env) > configuration file > Default; use
+// Context.Source to find out which layer supplied a value.
+func (cb *CommandBuilder) ConfigFile(paths ...string) *CommandBuilder {
+	cb.cmd.configFiles = append(cb.cmd.configFiles, paths...)
+	return cb
+}
+
+// addConfigFlag registers the -config override flag when configuration files
+// are declared and the command doesn't already define the flag itself.
+func (cmd *Command) addConfigFlag() {
+	if len(cmd.configFiles) == 0 || cmd.findFlagSpec(configFlagName) != nil {
+		return
+	}
+	cmd.flags = append(cmd.flags, &FlagSpec{
+		Names:         []string{configFlagName},
+		Description:   fmt.Sprintf("Read flag values from FILE instead of %s", strings.Join(cmd.configFiles, ", ")),
+		Scope:         ScopeGlobal,
+		ArgCount:      1,
+		ArgNames:      []string{"FILE"},
+		ArgTypes:      []ArgType{ArgString},
+		ArgCompleters: []Completer{&FileCompleter{Hint: "<FILE>"}},
+	})
+}
+
+// configEntry is a single flag value read from a configuration file
+type configEntry struct {
+	value interface{}
+	file  string
+}
+
+// applyConfig fills flags that are still unset after argv and environment
+// from the configuration files, mirroring applyEnv.
+func (cmd *Command) applyConfig(ctx *Context) error {
+	if len(cmd.configFiles) == 0 {
+		return nil
+	}
+
+	// -config given after a subcommand name (or via its env var) is in
+	// GlobalFlags; one given before the subcommand name arrives through
+	// configOverride and beats the env var.
+	files, required := cmd.configFiles, false
+	override, ok := ctx.GlobalFlags[configFlagName].(string)
+	if cmd.configOverride != "" && ctx.sources[configFlagName] != SourceArgs {
+		override, ok = cmd.configOverride, true
+	}
+	if ok {
+		files, required = []string{override}, true
+	}
+
+	tree := cmd.configTree
+	if tree == nil {
+		tree = cmd.subcommands
+	}
+	inherited, own, err := loadConfigEntries(files, cmd.configPath, tree, required)
+	if err != nil {
+		return err
+	}
+
+	for _, spec := range cmd.flags {
+		if spec.Names[0] == configFlagName {
+			continue
+		}
+		entries := own
+		if cmd.isConfigRootFlag(spec) {
+			entries = inherited
+		}
+		entry, ok := lookupConfigEntry(entries, spec)
+		if !ok {
+			continue
+		}
+		occurrences, err := configOccurrences(entry.value, spec)
+		if err == nil {
+			err = cmd.fillFromLayer(ctx, spec, occurrences)
+		}
+		if err != nil {
+			return ParseError{
+				Flag:    spec.Names[0],
+				Message: fmt.Sprintf("invalid value in config %s: %v", entry.file, err),
+			}
+		}
+	}
+	return nil
+}
+
+// isConfigRootFlag reports whether spec is a root global flag on the
+// temporary per-subcommand Command, and so is configured by the top level and
+// every section along the path
+func (cmd *Command) isConfigRootFlag(spec *FlagSpec) bool {
+	for _, root := range cmd.configRootFlags {
+		if root == spec {
+			return true
+		}
+	}
+	return false
+}
+
+// lookupConfigEntry finds a flag's entry by any of its names, with or without
+// the leading dash (INI/TOML keys are usually written bare).
+func lookupConfigEntry(entries map[string]configEntry, spec *FlagSpec) (configEntry, bool) {
+	for _, name := range spec.Names {
+		if entry, ok := entries[name]; ok {
+			return entry, true
+		}
+		if entry, ok := entries[strings.TrimLeft(name, "-+")]; ok {
+			return entry, true
+		}
+	}
+	return configEntry{}, false
+}
+
+// loadConfigEntries reads every file in files (highest precedence first) and
+// returns the flag values that apply to the given subcommand path, as
+// configSections splits them along the subcommand tree. Missing files are
+// skipped unless required is set.
+func loadConfigEntries(files []string, path []string, tree map[string]*Subcommand, required bool) (inherited, own map[string]configEntry, err error) {
+	inherited = make(map[string]configEntry)
+	own = make(map[string]configEntry)
+	for i := len(files) - 1; i >= 0; i-- {
+		file := expandHome(files[i])
+		data, err := os.ReadFile(file)
+		if err != nil {
+			if os.IsNotExist(err) && !required {
+				continue
+			}
+			return nil, nil, fmt.Errorf("config %s: %w", file, err)
+		}
+		doc, err := decodeConfig(file, data)
+		if err != nil {
+			return nil, nil, fmt.Errorf("config %s: %w", file, err)
+		}
+		docInherited, docOwn := configSections(doc, path, tree)
+		for key, value := range docInherited {
+			inherited[key] = configEntry{value: value, file: file}
+		}
+		for key, value := range docOwn {
+			own[key] = configEntry{value: value, file: file}
+		}
+	}
+	return inherited, own, nil
+}
+
+// configSections flattens the values that apply along a subcommand path.
+// inherited, for root global flags, holds the top-level values overlaid by
+// each section on the path in turn, the nearest last; own, for the flags of
+// the subcommand itself, holds just its section (the top level for the root
+// command). Only nested objects keyed by the name of a subcommand at that
+// level are sections, and are skipped; any other key is a flag value like a
+// scalar one, whether or not a flag goes by that name.
+func configSections(doc map[string]interface{}, path []string, tree map[string]*Subcommand) (inherited, own map[string]interface{}) {
+	collect := func(flat, node map[string]interface{}, subs map[string]*Subcommand) {
+		for key, value := range node {
+			if _, isSection := value.(map[string]interface{}); isSection && subs[key] != nil {
+				continue
+			}
+			flat[key] = value
+		}
+	}
+
+	inherited = make(map[string]interface{})
+	own = make(map[string]interface{})
+	collect(inherited, doc, tree)
+	node, subs := doc, tree
+	for _, name := range path {
+		next, ok := node[name].(map[string]interface{})
+		if !ok || subs[name] == nil {
+			return inherited, own
+		}
+		node, subs = next, subs[name].Subcommands
+		collect(inherited, node, subs)
+	}
+	collect(own, node, subs)
+	return inherited, own
+}
+
+// expandHome expands a leading ~/ to the user's home directory
+func expandHome(path string) string {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, path[1:])
+		}
+	}
+	return path
+}
+
+// decodeConfig parses a configuration document. .json files (or content that
+// starts with '{') are JSON; everything else uses the INI/TOML subset.
+func decodeConfig(file string, data []byte) (map[string]interface{}, error) {
+	trimmed := bytes.TrimSpace(data)
+	if strings.EqualFold(filepath.Ext(file), ".json") || bytes.HasPrefix(trimmed, []byte("{")) {
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.UseNumber()
+		doc := make(map[string]interface{})
+		if err := dec.Decode(&doc); err != nil {
+			return nil, err
+		}
+		return doc, nil
+	}
+	return decodeINI(data)
+}
+
+// decodeINI parses the INI/TOML subset: "# ..." and "; ..." comments,
+// [section] headers whose path elements are separated by spaces or dots, and
+// key = value pairs where value is a bare word, a single- or double-quoted
+// string, or a [a, "b", c] array.
+func decodeINI(data []byte) (map[string]interface{}, error) {
+	doc := make(map[string]interface{})
+	section := doc
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") {
+			if !strings.HasSuffix(line, "]") {
+				return nil, fmt.Errorf("line %d: unterminated section header", lineNo)
+			}
+			section = doc
+			for _, name := range strings.FieldsFunc(line[1:len(line)-1], func(r rune) bool {
+				return r == '.' || r == ' ' || r == '\t'
+			}) {
+				next, ok := section[name].(map[string]interface{})
+				if !ok {
+					next = make(map[string]interface{})
+					section[name] = next
+				}
+				section = next
+			}
+			continue
+		}
+
+		eq := strings.IndexByte(line, '=')
+		if eq < 0 {
+			return nil, fmt.Errorf("line %d: expected key = value", lineNo)
+		}
+		key := strings.TrimSpace(line[:eq])
+		value, err := parseINIValue(strings.TrimSpace(line[eq+1:]))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", lineNo, err)
+		}
+		section[key] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// parseINIValue parses a scalar or array value from the INI/TOML subset
+func parseINIValue(s string) (interface{}, error) {
+	if strings.HasPrefix(s, "[") {
+		if !strings.HasSuffix(s, "]") {
+			return nil, fmt.Errorf("unterminated array")
+		}
+		var items []interface{}
+		rest := strings.TrimSpace(s[1 : len(s)-1])
+		for rest != "" {
+			item, n, err := parseINIScalar(rest, ",")
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+			rest = strings.TrimSpace(rest[n:])
+			rest = strings.TrimSpace(strings.TrimPrefix(rest, ","))
+		}
+		return items, nil
+	}
+	value, n, err := parseINIScalar(s, "#;")
+	if err != nil {
+		return nil, err
+	}
+	if rest := strings.TrimSpace(s[n:]); rest != "" && !strings.ContainsAny(rest[:1], "#;") {
+		return nil, fmt.Errorf("unexpected %q after value", rest)
+	}
+	return value, nil
+}
+
+// parseINIScalar reads one quoted or bare value from the start of s and
+// returns it with the number of bytes consumed. Bare values end at any byte
+// in stop.
+func parseINIScalar(s string, stop string) (string, int, error) {
+	if s == "" {
+		return "", 0, nil
+	}
+	switch s[0] {
+	case '\'':
+		end := strings.IndexByte(s[1:], '\'')
+		if end < 0 {
+			return "", 0, fmt.Errorf("unterminated single quote")
+		}
+		return s[1 : 1+end], end + 2, nil
+	case '"':
+		var sb strings.Builder
+		for i := 1; i < len(s); i++ {
+			switch s[i] {
+			case '\\':
+				if i+1 < len(s) {
+					i++
+					switch s[i] {
+					case 'n':
+						sb.WriteByte('\n')
+					case 't':
+						sb.WriteByte('\t')
+					default:
+						sb.WriteByte(s[i])
+					}
+				}
+			case '"':
+				return sb.String(), i + 1, nil
+			default:
+				sb.WriteByte(s[i])
+			}
+		}
+		return "", 0, fmt.Errorf("unterminated double quote")
+	}
+	end := strings.IndexAny(s, stop)
+	if end < 0 {
+		end = len(s)
+	}
+	return strings.TrimSpace(s[:end]), end, nil
+}
+
+// configOccurrences converts a decoded configuration value into argv-style
+// word groups, one group of ArgCount words per occurrence of the flag:
+//   - scalars behave like an environment value (see envOccurrences)
+//   - arrays list the occurrences of accumulating flags, or the arguments of
+//     a single multi-argument occurrence
+//   - objects map argument names to values for multi-argument flags
+func configOccurrences(value interface{}, spec *FlagSpec) ([][]string, error) {
+	switch v := value.(type) {
+	case []interface{}:
+		if !spec.IsSlice {
+			words, err := configWords(v)
+			if err != nil {
+				return nil, err
+			}
+			return [][]string{words}, nil
+		}
+		var occurrences [][]string
+		for _, item := range v {
+			if s, isScalar := configScalar(item); isScalar && spec.ArgCount > 1 {
+				occurrences = append(occurrences, strings.Fields(s))
+				continue
+			}
+			words, err := configWords(item)
+			if err != nil {
+				return nil, err
+			}
+			occurrences = append(occurrences, words)
+		}
+		return occurrences, nil
+	case map[string]interface{}:
+		words, err := configWords(v, spec.ArgNames...)
+		if err != nil {
+			return nil, err
+		}
+		return [][]string{words}, nil
+	}
+
+	s, ok := configScalar(value)
+	if !ok {
+		return nil, fmt.Errorf("unsupported value %v", value)
+	}
+	return envOccurrences(s, spec)
+}
+
+// configWords flattens a value into the words of a single occurrence. Objects
+// are read in argNames order.
+func configWords(value interface{}, argNames ...string) ([]string, error) {
+	switch v := value.(type) {
+	case []interface{}:
+		var words []string
+		for _, item := range v {
+			s, ok := configScalar(item)
+			if !ok {
+				return nil, fmt.Errorf("unsupported array element %v", item)
+			}
+			words = append(words, s)
+		}
+		return words, nil
+	case map[string]interface{}:
+		var words []string
+		for _, name := range argNames {
+			item, ok := v[name]
+			if !ok {
+				return nil, fmt.Errorf("missing argument %s", name)
+			}
+			s, ok := configScalar(item)
+			if !ok {
+				return nil, fmt.Errorf("unsupported value for %s", name)
+			}
+			words = append(words, s)
+		}
+		return words, nil
+	}
+	s, ok := configScalar(value)
+	if !ok {
+		return nil, fmt.Errorf("unsupported value %v", value)
+	}
+	return []string{s}, nil
+}
+
+// configScalar renders a scalar configuration value as the string the user
+// would have typed on the command line.
+func configScalar(value interface{}) (string, bool) {
+	switch v := value.(type) {
+	case string:
+		return v, true
+	case json.Number:
+		return v.String(), true
+	case bool:
+		if v {
+			return "true", true
+		}
+		return "false", true
+	case float64:
+		return fmt.Sprintf("%v", v), true
+	}
+	return "", false
+}
@@ -0,0 +1,373 @@
+package completionflags
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeConfig(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestConfig_JSONRootAndSubcommand(t *testing.T) {
+	path := writeConfig(t, "app.json", `{
+		"-timezone": "UTC",
+		"query": {"-format": "json", "-limit": 50},
+		"remote": {"add": {"-fetch": true}}
+	}`)
+
+	var tz, format string
+	var limit int
+	var fetch bool
+	cmd := NewCommand("app").
+		ConfigFile(path).
+		Flag("-timezone").String().Global().Done().
+		Subcommand("query").
+		Flag("-format").String().Global().Default("text").Done().
+		Flag("-limit").Int().Global().Done().
+		Handler(func(ctx *Context) error {
+			tz = ctx.GetString("-timezone", "")
+			format = ctx.GetString("-format", "")
+			limit = ctx.GetInt("-limit", 0)
+			return nil
+		}).
+		Done().
+		Subcommand("remote").
+		Subcommand("add").
+		Flag("-fetch").Bool().Global().Done().
+		Handler(func(ctx *Context) error {
+			fetch = ctx.GetBool("-fetch", false)
+			return nil
+		}).
+		Done().
+		Done().
+		Build()
+
+	if err := cmd.Execute([]string{"query"}); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if tz != "UTC" || format != "json" || limit != 50 {
+		t.Errorf("unexpected values: tz=%q format=%q limit=%d", tz, format, limit)
+	}
+
+	if err := cmd.Execute([]string{"remote", "add"}); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if !fetch {
+		t.Error("expected -fetch from nested config section")
+	}
+}
+
+func TestConfig_INISubset(t *testing.T) {
+	path := writeConfig(t, "app.conf", `
+# defaults for this host
+timezone = "America/New_York"
+
+[query]
+format = csv   ; inline comment
+fields = [name, "home dir", uid]
+`)
+
+	var tz, format string
+	var fields []interface{}
+	cmd := NewCommand("app").
+		ConfigFile(path).
+		Flag("-timezone").String().Global().Done().
+		Subcommand("query").
+		Flag("-format").String().Global().Done().
+		Flag("-field").String().Accumulate().Global().Done().
+		Handler(func(ctx *Context) error {
+			tz = ctx.GetString("-timezone", "")
+			format = ctx.GetString("-format", "")
+			fields, _ = ctx.GlobalFlags["-field"].([]interface{})
+			return nil
+		}).
+		Done().
+		Build()
+
+	// "fields" doesn't name the flag; only -field does
+	if err := cmd.Execute([]string{"query"}); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if tz != "America/New_York" || format != "csv" {
+		t.Errorf("unexpected values: tz=%q format=%q", tz, format)
+	}
+	if len(fields) != 0 {
+		t.Errorf("unexpected fields: %v", fields)
+	}
+
+	path = writeConfig(t, "app.ini", "[query]\nfield = [name, \"home dir\", uid]\n")
+	cmd.configFiles = []string{path}
+	if err := cmd.Execute([]string{"query"}); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if len(fields) != 3 || fields[1] != "home dir" {
+		t.Errorf("unexpected fields: %v", fields)
+	}
+}
+
+func TestConfig_Precedence(t *testing.T) {
+	path := writeConfig(t, "app.json", `{"-format": "config", "-output": "config.out"}`)
+	t.Setenv("APP_FORMAT", "env")
+
+	var format, output, level string
+	var sources [3]ValueSource
+	cmd := NewCommand("app").
+		ConfigFile(path).
+		Flag("-format").String().Global().Env("APP_FORMAT").Done().
+		Flag("-output").String().Global().Done().
+		Flag("-level").String().Global().Default("info").Done().
+		Handler(func(ctx *Context) error {
+			format = ctx.GetString("-format", "")
+			output = ctx.GetString("-output", "")
+			level = ctx.GetString("-level", "")
+			sources = [3]ValueSource{ctx.Source("-format"), ctx.Source("-output"), ctx.Source("-level")}
+			return nil
+		}).
+		Build()
+
+	if err := cmd.Execute([]string{}); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if format != "env" || output != "config.out" || level != "info" {
+		t.Errorf("unexpected values: format=%q output=%q level=%q", format, output, level)
+	}
+	if sources != [3]ValueSource{SourceEnv, SourceConfig, SourceDefault} {
+		t.Errorf("unexpected sources: %v", sources)
+	}
+
+	if err := cmd.Execute([]string{"-format", "argv"}); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if format != "argv" || sources[0] != SourceArgs {
+		t.Errorf("expected argv to win, got %q from %v", format, sources[0])
+	}
+}
+
+func TestConfig_EarlierFileWins(t *testing.T) {
+	user := writeConfig(t, "user.json", `{"-format": "user"}`)
+	system := writeConfig(t, "system.json", `{"-format": "system", "-output": "system.out"}`)
+
+	var format, output string
+	cmd := NewCommand("app").
+		ConfigFile(user, filepath.Join(t.TempDir(), "missing.json"), system).
+		Flag("-format").String().Global().Done().
+		Flag("-output").String().Global().Done().
+		Handler(func(ctx *Context) error {
+			format = ctx.GetString("-format", "")
+			output = ctx.GetString("-output", "")
+			return nil
+		}).
+		Build()
+
+	if err := cmd.Execute([]string{}); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if format != "user" || output != "system.out" {
+		t.Errorf("unexpected values: format=%q output=%q", format, output)
+	}
+}
+
+func TestConfig_OverrideFlag(t *testing.T) {
+	declared := writeConfig(t, "app.json", `{"query": {"-format": "declared"}}`)
+	other := writeConfig(t, "other.json", `{"query": {"-format": "other"}}`)
+
+	var format string
+	cmd := NewCommand("app").
+		ConfigFile(declared).
+		Subcommand("query").
+		Flag("-format").String().Global().Done().
+		Handler(func(ctx *Context) error {
+			format = ctx.GetString("-format", "")
+			return nil
+		}).
+		Done().
+		Build()
+
+	// Before the subcommand name
+	if err := cmd.Execute([]string{"-config", other, "query"}); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if format != "other" {
+		t.Errorf("expected -config before subcommand to apply, got %q", format)
+	}
+
+	// After the subcommand name
+	format = ""
+	if err := cmd.Execute([]string{"query", "-config", other}); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if format != "other" {
+		t.Errorf("expected -config after subcommand to apply, got %q", format)
+	}
+
+	// An explicit -config file must exist
+	err := cmd.Execute([]string{"-config", filepath.Join(t.TempDir(), "missing.json"), "query"})
+	if err == nil || !strings.Contains(err.Error(), "missing.json") {
+		t.Errorf("expected missing config error, got %v", err)
+	}
+}
+
+func TestConfig_InvalidValueIsParseError(t *testing.T) {
+	path := writeConfig(t, "app.json", `{"-limit": "lots"}`)
+	cmd := NewCommand("app").
+		ConfigFile(path).
+		Flag("-limit").Int().Global().Done().
+		Handler(func(ctx *Context) error { return nil }).
+		Build()
+
+	err := cmd.Execute([]string{})
+	perr, ok := err.(ParseError)
+	if !ok {
+		t.Fatalf("expected ParseError, got %T: %v", err, err)
+	}
+	if perr.Flag != "-limit" || !strings.Contains(perr.Message, path) {
+		t.Errorf("unexpected error: %v", perr)
+	}
+}
+
+func TestConfig_SectionsAlongPath(t *testing.T) {
+	path := writeConfig(t, "app.conf", `
+timezone = UTC
+fetch = true
+
+[remote]
+timezone = "Europe/London"
+
+[remote add]
+depth = 3
+`)
+
+	var tz string
+	var fetch bool
+	var depth int
+	cmd := NewCommand("app").
+		ConfigFile(path).
+		Flag("-timezone").String().Global().Done().
+		Subcommand("remote").
+		Subcommand("add").
+		Flag("-fetch").Bool().Global().Done().
+		Flag("-depth").Int().Global().Done().
+		Handler(func(ctx *Context) error {
+			tz = ctx.GetString("-timezone", "")
+			fetch = ctx.GetBool("-fetch", false)
+			depth = ctx.GetInt("-depth", 0)
+			return nil
+		}).
+		Done().
+		Done().
+		Build()
+
+	if err := cmd.Execute([]string{"remote", "add"}); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	// [remote] overrides the top level for the root global; the top-level
+	// fetch key is for root flags, not for the subcommand's -fetch
+	if tz != "Europe/London" || fetch || depth != 3 {
+		t.Errorf("unexpected values: tz=%q fetch=%v depth=%d", tz, fetch, depth)
+	}
+}
+
+func TestConfig_RootGlobalGivenBeforeSubcommand(t *testing.T) {
+	path := writeConfig(t, "app.json", `{"-limit": "lots"}`)
+	t.Setenv("APP_WIDTH", "wide")
+
+	var limit, width int
+	cmd := NewCommand("app").
+		ConfigFile(path).
+		Flag("-limit").Int().Global().Done().
+		Flag("-width").Int().Global().Env("APP_WIDTH").Done().
+		Subcommand("list").
+		Handler(func(ctx *Context) error {
+			limit = ctx.GetInt("-limit", 0)
+			width = ctx.GetInt("-width", 0)
+			return nil
+		}).
+		Done().
+		Build()
+
+	// The invalid config and env values are never looked at
+	if err := cmd.Execute([]string{"-limit", "5", "-width", "80", "list"}); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if limit != 5 || width != 80 {
+		t.Errorf("unexpected values: limit=%d width=%d", limit, width)
+	}
+}
+
+func TestConfig_ObjectKeysOnlySectionsForSubcommands(t *testing.T) {
+	path := writeConfig(t, "app.json", `{
+		"range": {"LO": 1, "HI": 5},
+		"qurey": {"-limit": 7},
+		"query": {"-limit": 50}
+	}`)
+
+	var lo, hi, limit int
+	cmd := NewCommand("app").
+		ConfigFile(path).
+		Flag("-range").Args(2).ArgName(0, "LO").ArgType(0, ArgInt).ArgName(1, "HI").ArgType(1, ArgInt).Global().Done().
+		Subcommand("query").
+		Flag("-limit").Int().Global().Done().
+		Handler(func(ctx *Context) error {
+			limit = ctx.GetInt("-limit", 0)
+			return nil
+		}).
+		Done().
+		Handler(func(ctx *Context) error {
+			if r, ok := ctx.GlobalFlags["-range"].(map[string]interface{}); ok {
+				lo, hi = r["LO"].(int), r["HI"].(int)
+			}
+			return nil
+		}).
+		Build()
+
+	// "range" names no subcommand, so its object is the flag's value
+	if err := cmd.Execute(nil); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if lo != 1 || hi != 5 {
+		t.Errorf("expected -range 1 5 from config, got %d %d", lo, hi)
+	}
+
+	// The misspelt section is an unknown key, and is ignored like one
+	if err := cmd.Execute([]string{"query"}); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if limit != 50 {
+		t.Errorf("expected -limit 50 from [query], got %d", limit)
+	}
+}
+
+func TestConfig_CompletionReadsNoConfig(t *testing.T) {
+	dir := t.TempDir()
+	data := filepath.Join(dir, "data.csv")
+	if err := os.WriteFile(data, []byte("name,age\nAlice,30\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	path := writeConfig(t, "app.json", `{"-input": `)
+	t.Setenv("APP_INPUT", data)
+
+	cmd := NewCommand("app").
+		ConfigFile(path).
+		Flag("-input").String().Global().Env("APP_INPUT").Done().
+		Flag("FIELD").String().FieldsFromFlag("-input").Global().Done().
+		Handler(func(ctx *Context) error { return nil }).
+		Build()
+
+	// The broken config file would fail the parse; completion never reads
+	// it and still sees -input from the environment
+	got, err := cmd.Complete([]string{""}, 1)
+	if err != nil {
+		t.Fatalf("Complete failed: %v", err)
+	}
+	if strings.Join(got, " ") != "name age" {
+		t.Errorf("expected fields from the env -input, got %v", got)
+	}
+}
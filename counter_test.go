@@ -0,0 +1,242 @@
+package completionflags
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCount(t *testing.T) {
+	var ctx *Context
+	cmd := NewCommand("tool").
+		AllowBundling().AllowEquals().AllowNegation().
+		Flag("-verbose", "-v").Count().Global().Done().
+		Flag("-q").Bool().Global().Done().
+		Handler(func(c *Context) error {
+			ctx = c
+			return nil
+		}).
+		Build()
+
+	tests := []struct {
+		args []string
+		want int
+	}{
+		{[]string{}, 0},
+		{[]string{"-v"}, 1},
+		{[]string{"-v", "-v", "-verbose"}, 3},
+		{[]string{"-vvv"}, 3},
+		{[]string{"-qvv"}, 2},
+		{[]string{"-v", "-verbose=5"}, 5},
+		{[]string{"-vv", "-no-verbose", "-v"}, 1},
+	}
+	for _, tt := range tests {
+		ctx = nil
+		if err := cmd.Execute(tt.args); err != nil {
+			t.Errorf("%v: %v", tt.args, err)
+			continue
+		}
+		if got := ctx.GetInt("-verbose", -1); got != tt.want {
+			t.Errorf("%v: count = %d, want %d", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestCount_SubcommandAndEnv(t *testing.T) {
+	var ctx *Context
+	cmd := NewCommand("tool").
+		Flag("-v").Count().Global().Env("TOOL_VERBOSE").Done().
+		Subcommand("run").
+		Handler(func(c *Context) error {
+			ctx = c
+			return nil
+		}).
+		Done().
+		Build()
+
+	if err := cmd.Execute([]string{"-v", "run", "-v", "-v"}); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if got := ctx.GetInt("-v", 0); got != 3 {
+		t.Errorf("root and subcommand occurrences: got %d, want 3", got)
+	}
+
+	os.Setenv("TOOL_VERBOSE", "2")
+	defer os.Unsetenv("TOOL_VERBOSE")
+	if err := cmd.Execute([]string{"run"}); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if got := ctx.GetInt("-v", 0); got != 2 {
+		t.Errorf("env count: got %d, want 2", got)
+	}
+}
+
+func TestOptionalValue(t *testing.T) {
+	var ctx *Context
+	cmd := NewCommand("tool").
+		Flag("-color").Enum("always", "never", "auto").OptionalValue("always").Global().Done().
+		Flag("FILE").String().Options("notes.txt").Global().Done().
+		Handler(func(c *Context) error {
+			ctx = c
+			return nil
+		}).
+		Build()
+
+	tests := []struct {
+		args      []string
+		color     string
+		file      string
+		wantError bool
+	}{
+		{[]string{"-color"}, "always", "", false},
+		{[]string{"-color=never"}, "never", "", false},
+		{[]string{"-color", "never"}, "never", "", false},
+		{[]string{"-color", "never", "notes.txt"}, "never", "notes.txt", false},
+		{[]string{"-color", "notes.txt"}, "always", "notes.txt", false},
+		{[]string{"-color", "--", "never"}, "always", "", false},
+		{[]string{"-color=auto", "notes.txt"}, "auto", "notes.txt", false},
+		{[]string{"-color=sometimes"}, "", "", true},
+	}
+	for _, tt := range tests {
+		ctx = nil
+		err := cmd.Execute(tt.args)
+		if tt.wantError {
+			if err == nil {
+				t.Errorf("%v: expected error", tt.args)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: %v", tt.args, err)
+			continue
+		}
+		if got := ctx.GetString("-color", ""); got != tt.color {
+			t.Errorf("%v: -color = %q, want %q", tt.args, got, tt.color)
+		}
+		if got := ctx.GetString("FILE", ""); got != tt.file {
+			t.Errorf("%v: FILE = %q, want %q", tt.args, got, tt.file)
+		}
+	}
+}
+
+func TestOptionalValue_Subcommand(t *testing.T) {
+	var ctx *Context
+	cmd := NewCommand("tool").
+		Subcommand("show").
+		Flag("-color").Enum("always", "never").OptionalValue("always").Done().
+		Flag("FILE").String().Done().
+		Handler(func(c *Context) error {
+			ctx = c
+			return nil
+		}).
+		Done().
+		Build()
+
+	if err := cmd.Execute([]string{"show", "-color=never", "in.csv"}); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if got := ctx.Clauses[0].Flags["-color"]; got != "never" {
+		t.Errorf("-color = %v, want never", got)
+	}
+	if err := cmd.Execute([]string{"show", "-color", "in.csv"}); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if got := ctx.Clauses[0].Flags["FILE"]; got != "in.csv" {
+		t.Errorf("FILE = %v, want in.csv", got)
+	}
+	if err := cmd.Execute([]string{"show", "-color", "never", "in.csv"}); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if got := ctx.Clauses[0].Flags["-color"]; got != "never" {
+		t.Errorf("-color = %v, want never", got)
+	}
+
+	matches, _ := cmd.Complete([]string{"show", "-color", "=", "n"}, 4)
+	if !reflect.DeepEqual(matches, []string{"never"}) {
+		t.Errorf("completion of bash-split inline value: got %v", matches)
+	}
+}
+
+func TestOptionalValue_RootGlobal(t *testing.T) {
+	var ctx *Context
+	cmd := NewCommand("tool").
+		Flag("-color").Enum("always", "never").OptionalValue("always").Global().Done().
+		Flag("-token").String().OptionalValue("ask").Secret().Global().Done().
+		Subcommand("show").
+		Handler(func(c *Context) error {
+			ctx = c
+			return nil
+		}).
+		Done().
+		Build()
+
+	// A subcommand name is never taken as the value
+	tests := []struct {
+		args  []string
+		color string
+	}{
+		{[]string{"-color", "show"}, "always"},
+		{[]string{"-color", "never", "show"}, "never"},
+		{[]string{"show", "-color", "never"}, "never"},
+	}
+	for _, tt := range tests {
+		ctx = nil
+		if err := cmd.Execute(tt.args); err != nil {
+			t.Fatalf("%v: %v", tt.args, err)
+		}
+		if got := ctx.GetString("-color", ""); got != tt.color {
+			t.Errorf("%v: -color = %q, want %q", tt.args, got, tt.color)
+		}
+	}
+
+	// A value taken from the next word is redacted like any other
+	got := cmd.RedactArgs([]string{"-token", "hunter2", "show", "-token", "-color"})
+	want := []string{"-token", redacted, "show", "-token", "-color"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("RedactArgs = %v, want %v", got, want)
+	}
+}
+
+func TestOptionalValue_CompletionAndHelp(t *testing.T) {
+	cmd := NewCommand("tool").
+		Flag("-color").Enum("always", "never", "auto").OptionalValue("always").Global().Done().
+		Flag("FILE").String().Options("notes.txt").Global().Done().
+		Handler(func(ctx *Context) error { return nil }).
+		Build()
+
+	// The word after the bare flag is its value or the positional
+	matches, _ := cmd.Complete([]string{"-color", ""}, 2)
+	if !reflect.DeepEqual(matches, []string{"always", "never", "auto", "notes.txt"}) {
+		t.Errorf("completion after bare flag: got %v", matches)
+	}
+	matches, _ = cmd.Complete([]string{"-color", "never", ""}, 3)
+	if !reflect.DeepEqual(matches, []string{"notes.txt"}) {
+		t.Errorf("completion after flag and value: got %v", matches)
+	}
+	matches, _ = cmd.Complete([]string{"-color=n"}, 1)
+	if !reflect.DeepEqual(matches, []string{"-color=never"}) {
+		t.Errorf("completion of inline value: got %v", matches)
+	}
+	matches, _ = cmd.Complete([]string{"-color", "=", "a"}, 3)
+	if !reflect.DeepEqual(matches, []string{"always", "auto"}) {
+		t.Errorf("completion of bash-split inline value: got %v", matches)
+	}
+	matches, _ = cmd.Complete([]string{"-color", "=", "never", ""}, 4)
+	if !reflect.DeepEqual(matches, []string{"notes.txt"}) {
+		t.Errorf("completion after bash-split inline value: got %v", matches)
+	}
+
+	help := cmd.GenerateHelp()
+	if !strings.Contains(help, "-color[=VALUE]") || !strings.Contains(help, "without one: always") {
+		t.Errorf("help missing optional value form:\n%s", help)
+	}
+
+	text, err := cmd.HelpAt([]string{"-color", ""}, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(text, "-color[=VALUE]") {
+		t.Errorf("help-at missing optional value form:\n%s", text)
+	}
+}
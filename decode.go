@@ -0,0 +1,299 @@
+package completionflags
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// decodeTag is the struct tag read by Context.Decode
+const decodeTag = "autocli"
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// Decode fills the struct pointed to by v from the parsed flags, replacing
+// GetString calls and type assertions on GlobalFlags with typed fields.
+//
+// Fields are matched by `autocli` struct tags naming a flag's primary name or
+// a positional's name:
+//
+//	type filter struct {
+//	    Sep   string `autocli:",separator"`       // "+" / "-" that started the clause
+//	    Match struct {
+//	        Field string `autocli:"FIELD"`
+//	        Value string `autocli:"VALUE"`
+//	    } `autocli:"-match"`                      // Arg("FIELD").Done().Arg("VALUE")
+//	}
+//
+//	type options struct {
+//	    Input   string        `autocli:"-input"`
+//	    Timeout time.Duration `autocli:"-timeout"`
+//	    Fields  []string      `autocli:"-field"`   // Accumulate()
+//	    Files   []string      `autocli:"FILES"`    // Variadic()
+//	    Filters []filter      `autocli:",clauses"` // one element per clause
+//	}
+//
+// A flag is read from GlobalFlags, falling back to the first clause that holds
+// it (as Source does). A []struct field tagged ",clauses" gets one element per
+// clause, decoded from that clause's flags only. Multi-argument flags decode
+// into structs (fields matched by tag or, case-insensitively, by name against
+// the Arg names) or maps. Accumulated and variadic values decode into slices.
+//
+// Untagged fields, fields tagged "-" and flags without a value are left
+// untouched; pointer fields are allocated only when a value is present. A value
+// that doesn't fit its field's type is reported as an error naming the flag
+// and the field.
+func (ctx *Context) Decode(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("decode: expected a non-nil pointer to a struct, got %T", v)
+	}
+	return ctx.decodeStruct(rv.Elem(), nil)
+}
+
+// lookup finds a flag value the way Source does: globals first, then the
+// first clause that holds the flag
+func (ctx *Context) lookup(name string) (interface{}, bool) {
+	if v, ok := ctx.GlobalFlags[name]; ok {
+		return v, true
+	}
+	for _, clause := range ctx.Clauses {
+		if v, ok := clause.Flags[name]; ok {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+// decodeStruct fills the tagged fields of dst. With a nil clause the values
+// come from the whole context; otherwise only from that clause.
+func (ctx *Context) decodeStruct(dst reflect.Value, clause *Clause) error {
+	t := dst.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag, ok := field.Tag.Lookup(decodeTag)
+		if !ok || tag == "-" || field.PkgPath != "" {
+			continue
+		}
+		name, option, _ := strings.Cut(tag, ",")
+
+		switch option {
+		case "":
+		case "clauses":
+			if clause != nil {
+				return fmt.Errorf("decode: field %s: \",clauses\" is only valid at the top level", field.Name)
+			}
+			if err := ctx.decodeClauses(dst.Field(i), field); err != nil {
+				return err
+			}
+			continue
+		case "separator":
+			if clause == nil {
+				return fmt.Errorf("decode: field %s: \",separator\" is only valid inside a clause struct", field.Name)
+			}
+			if field.Type.Kind() != reflect.String {
+				return fmt.Errorf("decode: field %s: \",separator\" needs a string field, not %s", field.Name, field.Type)
+			}
+			dst.Field(i).SetString(clause.Separator)
+			continue
+		default:
+			return fmt.Errorf("decode: field %s: unknown tag option %q", field.Name, option)
+		}
+
+		var value interface{}
+		var found bool
+		if clause != nil {
+			value, found = clause.Flags[name]
+		} else {
+			value, found = ctx.lookup(name)
+		}
+		if !found || value == nil {
+			continue
+		}
+		if err := decodeValue(dst.Field(i), value); err != nil {
+			return fmt.Errorf("decode %s into field %s: %v", name, field.Name, err)
+		}
+	}
+	return nil
+}
+
+// decodeClauses fills a []struct (or []*struct) field with one element per clause
+func (ctx *Context) decodeClauses(dst reflect.Value, field reflect.StructField) error {
+	elemType := field.Type
+	if elemType.Kind() == reflect.Slice {
+		elemType = elemType.Elem()
+	}
+	isPtr := elemType.Kind() == reflect.Ptr
+	if isPtr {
+		elemType = elemType.Elem()
+	}
+	if field.Type.Kind() != reflect.Slice || elemType.Kind() != reflect.Struct {
+		return fmt.Errorf("decode: field %s: \",clauses\" needs a slice of structs, not %s", field.Name, field.Type)
+	}
+
+	out := reflect.MakeSlice(field.Type, len(ctx.Clauses), len(ctx.Clauses))
+	for i := range ctx.Clauses {
+		elem := reflect.New(elemType)
+		if err := ctx.decodeStruct(elem.Elem(), &ctx.Clauses[i]); err != nil {
+			return fmt.Errorf("clause %d: %w", i, err)
+		}
+		if isPtr {
+			out.Index(i).Set(elem)
+		} else {
+			out.Index(i).Set(elem.Elem())
+		}
+	}
+	dst.Set(out)
+	return nil
+}
+
+// decodeValue stores a parsed flag value in dst, converting between the
+// parser's representations ([]interface{}, map[string]interface{}, int,
+// float64, ...) and the field's type.
+func decodeValue(dst reflect.Value, value interface{}) error {
+	if value == nil {
+		return nil
+	}
+	if dst.Kind() == reflect.Ptr {
+		elem := reflect.New(dst.Type().Elem())
+		if err := decodeValue(elem.Elem(), value); err != nil {
+			return err
+		}
+		dst.Set(elem)
+		return nil
+	}
+
+	src := reflect.ValueOf(value)
+	if src.Type().AssignableTo(dst.Type()) {
+		dst.Set(src)
+		return nil
+	}
+
+	switch {
+	case src.Kind() == reflect.Slice && dst.Kind() == reflect.Slice:
+		out := reflect.MakeSlice(dst.Type(), src.Len(), src.Len())
+		for i := 0; i < src.Len(); i++ {
+			if err := decodeValue(out.Index(i), src.Index(i).Interface()); err != nil {
+				return fmt.Errorf("element %d: %v", i, err)
+			}
+		}
+		dst.Set(out)
+		return nil
+
+	case dst.Kind() == reflect.Slice:
+		// A single occurrence decoded into a slice field
+		out := reflect.MakeSlice(dst.Type(), 1, 1)
+		if err := decodeValue(out.Index(0), value); err != nil {
+			return err
+		}
+		dst.Set(out)
+		return nil
+
+	case src.Kind() == reflect.Map && dst.Kind() == reflect.Struct:
+		return decodeArgs(dst, value)
+
+	case src.Kind() == reflect.Map && dst.Kind() == reflect.Map:
+		if dst.Type().Key().Kind() != reflect.String || src.Type().Key().Kind() != reflect.String {
+			break
+		}
+		out := reflect.MakeMapWithSize(dst.Type(), src.Len())
+		iter := src.MapRange()
+		for iter.Next() {
+			elem := reflect.New(dst.Type().Elem()).Elem()
+			if err := decodeValue(elem, iter.Value().Interface()); err != nil {
+				return fmt.Errorf("%s: %v", iter.Key().String(), err)
+			}
+			out.SetMapIndex(iter.Key().Convert(dst.Type().Key()), elem)
+		}
+		dst.Set(out)
+		return nil
+	}
+
+	return decodeScalar(dst, src)
+}
+
+// decodeArgs fills a struct from a multi-argument flag's map, matching fields
+// by tag or case-insensitively by name against the argument names
+func decodeArgs(dst reflect.Value, value interface{}) error {
+	args, ok := value.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("cannot decode %T into %s", value, dst.Type())
+	}
+
+	t := dst.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		name := field.Tag.Get(decodeTag)
+		if name == "-" {
+			continue
+		}
+
+		var arg interface{}
+		var found bool
+		if name != "" {
+			arg, found = args[name]
+		} else {
+			for argName, argValue := range args {
+				if strings.EqualFold(argName, field.Name) {
+					arg, found = argValue, true
+					break
+				}
+			}
+			name = field.Name
+		}
+		if !found || arg == nil {
+			continue
+		}
+		if err := decodeValue(dst.Field(i), arg); err != nil {
+			return fmt.Errorf("argument %s: %v", name, err)
+		}
+	}
+	return nil
+}
+
+// decodeScalar converts between scalar kinds that can't lose meaning: named
+// types with the same kind, integer widths (checked for overflow) and float
+// widths. An integer is never silently turned into a time.Duration or string.
+func decodeScalar(dst reflect.Value, src reflect.Value) error {
+	dt, st := dst.Type(), src.Type()
+	mismatch := fmt.Errorf("cannot decode %s into %s", st, dt)
+
+	switch {
+	case isIntKind(st.Kind()) && isIntKind(dt.Kind()):
+		if (dt == durationType) != (st == durationType) {
+			return mismatch
+		}
+		if dst.OverflowInt(src.Int()) {
+			return fmt.Errorf("value %d overflows %s", src.Int(), dt)
+		}
+		dst.SetInt(src.Int())
+	case isIntKind(st.Kind()) && isUintKind(dt.Kind()):
+		if src.Int() < 0 || dst.OverflowUint(uint64(src.Int())) {
+			return fmt.Errorf("value %d overflows %s", src.Int(), dt)
+		}
+		dst.SetUint(uint64(src.Int()))
+	case isFloatKind(st.Kind()) && isFloatKind(dt.Kind()):
+		dst.SetFloat(src.Float())
+	case st.Kind() == dt.Kind() && (st.Kind() == reflect.String || st.Kind() == reflect.Bool):
+		dst.Set(src.Convert(dt))
+	default:
+		return mismatch
+	}
+	return nil
+}
+
+func isIntKind(k reflect.Kind) bool {
+	return k >= reflect.Int && k <= reflect.Int64
+}
+
+func isUintKind(k reflect.Kind) bool {
+	return k >= reflect.Uint && k <= reflect.Uint64
+}
+
+func isFloatKind(k reflect.Kind) bool {
+	return k == reflect.Float32 || k == reflect.Float64
+}
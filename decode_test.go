@@ -0,0 +1,141 @@
+package completionflags
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDecode_GlobalFlagsAndPositionals(t *testing.T) {
+	type options struct {
+		Input   string        `autocli:"-input"`
+		Limit   int64         `autocli:"-limit"`
+		Ratio   float32       `autocli:"-ratio"`
+		Timeout time.Duration `autocli:"-timeout"`
+		Verbose bool          `autocli:"-verbose"`
+		Output  *string       `autocli:"-output"`
+		Fields  []string      `autocli:"-field"`
+		Files   []string      `autocli:"FILES"`
+		Ignored string
+	}
+
+	var opts options
+	cmd := NewCommand("test").
+		Flag("-input").String().Global().Done().
+		Flag("-limit").Int().Global().Done().
+		Flag("-ratio").Float().Global().Done().
+		Flag("-timeout").Duration().Global().Default(30 * time.Second).Done().
+		Flag("-verbose").Bool().Global().Done().
+		Flag("-output").String().Global().Done().
+		Flag("-field").String().Accumulate().Global().Done().
+		Flag("FILES").String().Variadic().Global().Done().
+		Handler(func(ctx *Context) error {
+			return ctx.Decode(&opts)
+		}).
+		Build()
+
+	err := cmd.Execute([]string{"-input", "in.csv", "-limit", "5", "-ratio", "0.5", "-verbose",
+		"-field", "name", "-field", "uid", "a.txt", "b.txt"})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	if opts.Input != "in.csv" || opts.Limit != 5 || opts.Ratio != 0.5 || !opts.Verbose {
+		t.Errorf("unexpected scalars: %+v", opts)
+	}
+	if opts.Timeout != 30*time.Second {
+		t.Errorf("expected default timeout, got %v", opts.Timeout)
+	}
+	if opts.Output != nil {
+		t.Errorf("expected unset pointer field to stay nil, got %q", *opts.Output)
+	}
+	if strings.Join(opts.Fields, ",") != "name,uid" {
+		t.Errorf("unexpected fields: %v", opts.Fields)
+	}
+	if strings.Join(opts.Files, ",") != "a.txt,b.txt" {
+		t.Errorf("unexpected files: %v", opts.Files)
+	}
+}
+
+func TestDecode_MultiArgAndClauses(t *testing.T) {
+	type match struct {
+		Field string `autocli:"FIELD"`
+		Value string // matched case-insensitively against VALUE
+	}
+	type filter struct {
+		Sep     string  `autocli:",separator"`
+		Match   match   `autocli:"-match"`
+		Exclude []match `autocli:"-exclude"`
+	}
+	type options struct {
+		Format  string   `autocli:"-format"`
+		Filters []filter `autocli:",clauses"`
+	}
+
+	var opts options
+	cmd := NewCommand("test").
+		Separators("+", "-").
+		Flag("-format").String().Global().Done().
+		Flag("-match").Arg("FIELD").Done().Arg("VALUE").Done().Local().Done().
+		Flag("-exclude").Arg("FIELD").Done().Arg("VALUE").Done().Accumulate().Local().Done().
+		Handler(func(ctx *Context) error {
+			return ctx.Decode(&opts)
+		}).
+		Build()
+
+	err := cmd.Execute([]string{"-format", "json",
+		"-match", "status", "active", "-exclude", "role", "admin",
+		"+", "-match", "status", "pending"})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	if opts.Format != "json" {
+		t.Errorf("unexpected format: %q", opts.Format)
+	}
+	if len(opts.Filters) != 2 {
+		t.Fatalf("expected 2 clauses, got %d", len(opts.Filters))
+	}
+	first, second := opts.Filters[0], opts.Filters[1]
+	if first.Match != (match{"status", "active"}) || len(first.Exclude) != 1 || first.Exclude[0] != (match{"role", "admin"}) {
+		t.Errorf("unexpected first clause: %+v", first)
+	}
+	if second.Sep != "+" || second.Match != (match{"status", "pending"}) || second.Exclude != nil {
+		t.Errorf("unexpected second clause: %+v", second)
+	}
+}
+
+func TestDecode_TypeMismatch(t *testing.T) {
+	var decodeErr error
+	cmd := NewCommand("test").
+		Flag("-count").Int().Global().Done().
+		Flag("-delay").Int().Global().Done().
+		Handler(func(ctx *Context) error {
+			var bad struct {
+				Count string `autocli:"-count"`
+			}
+			decodeErr = ctx.Decode(&bad)
+			return nil
+		}).
+		Build()
+
+	if err := cmd.Execute([]string{"-count", "3"}); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if decodeErr == nil || !strings.Contains(decodeErr.Error(), "-count") || !strings.Contains(decodeErr.Error(), "Count") {
+		t.Errorf("expected error naming flag and field, got %v", decodeErr)
+	}
+
+	// An int flag never silently becomes a Duration
+	ctx := &Context{GlobalFlags: map[string]interface{}{"-delay": 5}}
+	var durations struct {
+		Delay time.Duration `autocli:"-delay"`
+	}
+	if err := ctx.Decode(&durations); err == nil {
+		t.Error("expected int -> time.Duration to fail")
+	}
+
+	if err := ctx.Decode(durations); err == nil {
+		t.Error("expected non-pointer target to fail")
+	}
+}
@@ -0,0 +1,222 @@
+package completionflags
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// DependentParser parses a flag's argument given the values of the flags it
+// depends on (see DependsOn), keyed by their primary names. A dependency
+// that wasn't given and has no default is absent from deps.
+type DependentParser func(value string, deps map[string]interface{}) (interface{}, error)
+
+// dependencies returns the flags spec's value depends on: its DependsOn
+// list and the TimeZoneFromFlag of a time flag
+func (spec *FlagSpec) dependencies() []string {
+	deps := spec.DependsOn
+	if spec.TimeZoneFromFlag != "" {
+		deps = append(append([]string{}, deps...), spec.TimeZoneFromFlag)
+	}
+	return deps
+}
+
+// isDependent reports whether spec's value is parsed only once every flag
+// it depends on has its final value
+func (spec *FlagSpec) isDependent() bool {
+	return spec.ArgCount == 1 && len(spec.dependencies()) > 0
+}
+
+// resolveFlagDependencies checks the DependsOn lists of flags at Build time:
+// every name must be a known flag (aliases are rewritten to primary names),
+// only single-argument flags may have dependencies, a global flag may only
+// depend on global flags, and there must be no cycles.
+func resolveFlagDependencies(flags []*FlagSpec, inherited []*FlagSpec) error {
+	all := append(append([]*FlagSpec{}, inherited...), flags...)
+	byName := make(map[string]*FlagSpec)
+	for _, spec := range all {
+		for _, name := range spec.Names {
+			byName[name] = spec
+		}
+	}
+
+	for _, spec := range flags {
+		if len(spec.DependsOn) == 0 {
+			continue
+		}
+		if spec.ArgCount != 1 {
+			return fmt.Errorf("flag %s: DependsOn requires a single-argument flag", spec.Names[0])
+		}
+		for i, name := range spec.DependsOn {
+			dep, ok := byName[name]
+			if !ok {
+				return fmt.Errorf("flag %s: DependsOn references unknown flag %s", spec.Names[0], name)
+			}
+			if spec.Scope == ScopeGlobal && dep.Scope != ScopeGlobal {
+				return fmt.Errorf("flag %s: global flag cannot depend on local flag %s", spec.Names[0], name)
+			}
+			spec.DependsOn[i] = dep.Names[0]
+		}
+	}
+
+	if _, err := dependencyOrder(all); err != nil {
+		return err
+	}
+	return nil
+}
+
+// dependencyOrder sorts flags so each comes after the flags it depends on,
+// reporting a cycle as an error
+func dependencyOrder(flags []*FlagSpec) ([]*FlagSpec, error) {
+	byName := make(map[string]*FlagSpec)
+	for _, spec := range flags {
+		for _, name := range spec.Names {
+			byName[name] = spec
+		}
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make(map[*FlagSpec]int)
+	order := make([]*FlagSpec, 0, len(flags))
+	var path []string
+
+	var visit func(spec *FlagSpec) error
+	visit = func(spec *FlagSpec) error {
+		switch state[spec] {
+		case done:
+			return nil
+		case visiting:
+			for i, name := range path {
+				if name == spec.Names[0] {
+					return fmt.Errorf("flag dependency cycle: %s -> %s", strings.Join(path[i:], " -> "), spec.Names[0])
+				}
+			}
+		}
+		state[spec] = visiting
+		path = append(path, spec.Names[0])
+		for _, name := range spec.dependencies() {
+			if dep, ok := byName[name]; ok {
+				if err := visit(dep); err != nil {
+					return err
+				}
+			}
+		}
+		path = path[:len(path)-1]
+		state[spec] = done
+		order = append(order, spec)
+		return nil
+	}
+
+	for _, spec := range flags {
+		if err := visit(spec); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
+
+// deferValue stores raw as a placeholder for a dependent flag's value in
+// target (GlobalFlags or a clause's Flags) and queues it for
+// resolveDeferredValues. The placeholder lets the layers and source
+// tracking see the flag as given.
+func (ctx *Context) deferValue(spec *FlagSpec, target map[string]interface{}, raw string) {
+	index := -1
+	if spec.IsSlice {
+		existing, _ := target[spec.Names[0]].([]interface{})
+		index = len(existing)
+	}
+	storeFlagValue(spec, target, raw)
+	ctx.deferredValues = append(ctx.deferredValues, &deferredValue{
+		rawString: raw,
+		spec:      spec,
+		target:    target,
+		index:     index,
+	})
+}
+
+// resolveDeferredValues parses the values of dependent flags once every
+// layer has been applied, in dependency order so a flag sees the parsed
+// values of the flags it depends on
+func (cmd *Command) resolveDeferredValues(ctx *Context) error {
+	order, err := dependencyOrder(cmd.flags)
+	if err != nil {
+		return err
+	}
+	rank := make(map[*FlagSpec]int, len(order))
+	for i, spec := range order {
+		rank[spec] = i
+	}
+	sort.SliceStable(ctx.deferredValues, func(i, j int) bool {
+		return rank[ctx.deferredValues[i].spec] < rank[ctx.deferredValues[j].spec]
+	})
+
+	// Root globals given before a subcommand name take precedence, as they
+	// do when parseSubcommand merges them
+	globals := ctx.GlobalFlags
+	if len(cmd.parentGlobals) > 0 {
+		globals = make(map[string]interface{}, len(ctx.GlobalFlags)+len(cmd.parentGlobals))
+		for k, v := range ctx.GlobalFlags {
+			globals[k] = v
+		}
+		for k, v := range cmd.parentGlobals {
+			if spec := cmd.findFlagSpec(k); spec == nil || !spec.isDependent() {
+				globals[k] = v
+			}
+		}
+	}
+
+	for _, deferred := range ctx.deferredValues {
+		spec := deferred.spec
+		name := spec.Names[0]
+		local := deferred.target
+		if spec.Scope == ScopeGlobal {
+			local = nil
+		}
+		deps := dependencyValues(spec, local, globals)
+
+		var value interface{}
+		var err error
+		if spec.ParseFunc != nil {
+			value, err = spec.ParseFunc(deferred.rawString, deps)
+			err = secretError(spec, err)
+		} else {
+			value, err = parseArgValue(deferred.rawString, spec, 0, deps)
+		}
+		if err != nil {
+			return ParseError{
+				Flag:    name,
+				Message: fmt.Sprintf("deferred parsing failed: %v", err),
+			}
+		}
+		if deferred.fromArgs {
+			value = cmd.getPrefixHandler()(name, deferred.plus, value)
+		}
+
+		if deferred.index >= 0 {
+			deferred.target[name].([]interface{})[deferred.index] = value
+		} else {
+			deferred.target[name] = value
+		}
+	}
+	ctx.resolved = append(ctx.resolved, ctx.deferredValues...)
+	ctx.deferredValues = nil
+	return nil
+}
+
+// dependencyValues collects the values of spec's dependencies, preferring
+// the clause's own value over the global one for local flags
+func dependencyValues(spec *FlagSpec, local, global map[string]interface{}) map[string]interface{} {
+	deps := make(map[string]interface{})
+	for _, name := range spec.dependencies() {
+		if v, ok := local[name]; ok {
+			deps[name] = v
+		} else if v, ok := global[name]; ok {
+			deps[name] = v
+		}
+	}
+	return deps
+}
@@ -0,0 +1,240 @@
+package completionflags
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+var unitSize = map[string]float64{"B": 1, "KB": 1e3, "MB": 1e6}
+
+func parseSize(value string, deps map[string]interface{}) (interface{}, error) {
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return nil, err
+	}
+	unit, _ := deps["-unit"].(string)
+	return n * unitSize[unit], nil
+}
+
+func parseTyped(value string, deps map[string]interface{}) (interface{}, error) {
+	switch deps["-type"] {
+	case "int":
+		return strconv.Atoi(value)
+	case "bool":
+		return strconv.ParseBool(value)
+	}
+	return value, nil
+}
+
+func TestDependsOn_ArgvOrder(t *testing.T) {
+	var ctx *Context
+	cmd := NewCommand("tool").
+		Flag("-size").DependsOn("-unit").ParseWith(parseSize).Global().Done().
+		Flag("-unit", "-u").Enum("B", "KB", "MB").Default("B").Global().Done().
+		Flag("-value").DependsOn("-type").ParseWith(parseTyped).Global().Done().
+		Flag("-type").Enum("string", "int", "bool").Default("string").Global().Done().
+		Handler(func(c *Context) error {
+			ctx = c
+			return nil
+		}).
+		Build()
+
+	tests := []struct {
+		args  []string
+		size  float64
+		value interface{}
+	}{
+		{[]string{"-size", "10", "-unit", "MB"}, 10e6, nil},
+		{[]string{"-u", "KB", "-size", "10"}, 10e3, nil},
+		{[]string{"-size", "10"}, 10, nil},
+		{[]string{"-value", "42", "-type", "int"}, 0, 42},
+		{[]string{"-value", "true", "-type", "bool"}, 0, true},
+		{[]string{"-value", "42"}, 0, "42"},
+	}
+	for _, tt := range tests {
+		if err := cmd.Execute(tt.args); err != nil {
+			t.Errorf("%v: %v", tt.args, err)
+			continue
+		}
+		if tt.size != 0 && ctx.GlobalFlags["-size"] != tt.size {
+			t.Errorf("%v: -size = %v, want %v", tt.args, ctx.GlobalFlags["-size"], tt.size)
+		}
+		if tt.value != nil && ctx.GlobalFlags["-value"] != tt.value {
+			t.Errorf("%v: -value = %#v, want %#v", tt.args, ctx.GlobalFlags["-value"], tt.value)
+		}
+	}
+
+	err := cmd.Execute([]string{"-value", "x", "-type", "int"})
+	if err == nil || !strings.Contains(err.Error(), "flag -value: deferred parsing failed") {
+		t.Errorf("expected deferred parse error, got %v", err)
+	}
+}
+
+func TestDependsOn_Clauses(t *testing.T) {
+	var ctx *Context
+	cmd := NewCommand("tool").
+		Flag("-unit").String().Default("B").Global().Done().
+		Flag("-size").DependsOn("-unit").ParseWith(parseSize).Accumulate().Local().Done().
+		Handler(func(c *Context) error {
+			ctx = c
+			return nil
+		}).
+		Build()
+
+	if err := cmd.Execute([]string{"-size", "1", "-size", "2", "+", "-size", "3", "-unit", "KB"}); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	first := fmt.Sprint(ctx.Clauses[0].Flags["-size"])
+	second := fmt.Sprint(ctx.Clauses[1].Flags["-size"])
+	if first != "[1000 2000]" || second != "[3000]" {
+		t.Errorf("sizes = %s, %s", first, second)
+	}
+}
+
+func TestDependsOn_LocalDependency(t *testing.T) {
+	var ctx *Context
+	cmd := NewCommand("tool").
+		Flag("-value").DependsOn("-type").ParseWith(parseTyped).Local().Done().
+		Flag("-type").String().Local().Done().
+		Handler(func(c *Context) error {
+			ctx = c
+			return nil
+		}).
+		Build()
+
+	if err := cmd.Execute([]string{"-value", "7", "-type", "int", "+", "-value", "7"}); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if ctx.Clauses[0].Flags["-value"] != 7 || ctx.Clauses[1].Flags["-value"] != "7" {
+		t.Errorf("values = %#v, %#v", ctx.Clauses[0].Flags["-value"], ctx.Clauses[1].Flags["-value"])
+	}
+}
+
+func TestDependsOn_PlusPrefix(t *testing.T) {
+	var ctx *Context
+	cmd := NewCommand("tool").
+		PrefixHandler(func(name string, hasPlus bool, value interface{}) interface{} {
+			if size, ok := value.(float64); ok && hasPlus {
+				return -size
+			}
+			return value
+		}).
+		Flag("-size").DependsOn("-unit").ParseWith(parseSize).Global().Done().
+		Flag("-unit").String().Default("B").Global().Done().
+		Handler(func(c *Context) error {
+			ctx = c
+			return nil
+		}).
+		Build()
+
+	// +size waits for -unit like -size does, then sees the prefix handler
+	if err := cmd.Execute([]string{"+size", "2", "-unit", "KB"}); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if ctx.GlobalFlags["-size"] != -2000.0 {
+		t.Errorf("-size = %#v, want -2000", ctx.GlobalFlags["-size"])
+	}
+}
+
+func TestDependsOn_SecretValueNotInError(t *testing.T) {
+	cmd := NewCommand("tool").
+		Flag("-key").DependsOn("-type").ParseWith(parseTyped).Secret().Global().Done().
+		Flag("-pin").DependsOn("-type").Int().Secret().Global().Done().
+		Flag("-type").String().Default("int").Global().Done().
+		Handler(func(c *Context) error { return nil }).
+		Build()
+
+	for _, args := range [][]string{
+		{"-key", "hunter2"},
+		{"-pin", "hunter2"},
+	} {
+		err := cmd.Execute(args)
+		if err == nil {
+			t.Fatalf("%v: expected a parse error", args)
+		}
+		if strings.Contains(err.Error(), "hunter2") {
+			t.Errorf("%v: error shows the secret value: %v", args, err)
+		}
+	}
+}
+
+func TestDependsOn_Subcommand(t *testing.T) {
+	var ctx *Context
+	cmd := NewCommand("tool").
+		Flag("-unit").String().Default("B").Global().Done().
+		Flag("-limit").DependsOn("-unit").ParseWith(parseSize).Global().Done().
+		Subcommand("push").
+		Flag("-size").DependsOn("-unit").ParseWith(parseSize).Done().
+		Handler(func(c *Context) error {
+			ctx = c
+			return nil
+		}).
+		Done().
+		Build()
+
+	if err := cmd.Execute([]string{"-limit", "5", "-unit", "KB", "push", "-size", "2"}); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if ctx.GlobalFlags["-limit"] != 5e3 || ctx.Clauses[0].Flags["-size"] != 2e3 {
+		t.Errorf("before subcommand: -limit = %v, -size = %v", ctx.GlobalFlags["-limit"], ctx.Clauses[0].Flags["-size"])
+	}
+
+	if err := cmd.Execute([]string{"-limit", "5", "push", "-size", "2", "-unit", "MB"}); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if ctx.GlobalFlags["-limit"] != 5e6 || ctx.Clauses[0].Flags["-size"] != 2e6 {
+		t.Errorf("after subcommand: -limit = %v, -size = %v", ctx.GlobalFlags["-limit"], ctx.Clauses[0].Flags["-size"])
+	}
+}
+
+func TestDependsOn_BuildErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		build func()
+		want  string
+	}{
+		{"cycle", func() {
+			NewCommand("tool").
+				Flag("-a").DependsOn("-b").ParseWith(parseTyped).Done().
+				Flag("-b").DependsOn("-c").ParseWith(parseTyped).Done().
+				Flag("-c").DependsOn("-a").ParseWith(parseTyped).Done().
+				Handler(func(ctx *Context) error { return nil }).
+				Build()
+		}, "flag dependency cycle: -a -> -b -> -c -> -a"},
+		{"unknown", func() {
+			NewCommand("tool").
+				Flag("-size").DependsOn("-unit").ParseWith(parseSize).Done().
+				Handler(func(ctx *Context) error { return nil }).
+				Build()
+		}, "unknown flag -unit"},
+		{"global on local", func() {
+			NewCommand("tool").
+				Flag("-unit").String().Local().Done().
+				Flag("-size").DependsOn("-unit").ParseWith(parseSize).Global().Done().
+				Handler(func(ctx *Context) error { return nil }).
+				Build()
+		}, "global flag cannot depend on local flag -unit"},
+		{"subcommand cycle", func() {
+			NewCommand("tool").
+				Subcommand("run").
+				Flag("-a").DependsOn("-b").ParseWith(parseTyped).Done().
+				Flag("-b").DependsOn("-a").ParseWith(parseTyped).Done().
+				Handler(func(ctx *Context) error { return nil }).
+				Done().
+				Build()
+		}, `subcommand "run" flag dependency validation failed`},
+	}
+	for _, tt := range tests {
+		func() {
+			defer func() {
+				r := recover()
+				if r == nil || !strings.Contains(fmt.Sprint(r), tt.want) {
+					t.Errorf("%s: panic = %v, want %q", tt.name, r, tt.want)
+				}
+			}()
+			tt.build()
+		}()
+	}
+}
@@ -0,0 +1,171 @@
+package completionflags
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEnv_FillsAbsentFlag(t *testing.T) {
+	t.Setenv("MYAPP_INPUT", "from-env.csv")
+	var input string
+	cmd := NewCommand("test").
+		Flag("-input").String().Global().Env("MYAPP_INPUT").Done().
+		Handler(func(ctx *Context) error {
+			input = ctx.GetString("-input", "")
+			return nil
+		}).
+		Build()
+
+	if err := cmd.Execute([]string{}); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if input != "from-env.csv" {
+		t.Errorf("expected env value, got %q", input)
+	}
+
+	// argv wins over env
+	if err := cmd.Execute([]string{"-input", "argv.csv"}); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if input != "argv.csv" {
+		t.Errorf("expected argv value, got %q", input)
+	}
+}
+
+func TestEnv_PrecedesDefault(t *testing.T) {
+	t.Setenv("MYAPP_TIMEOUT", "90s")
+	var timeout time.Duration
+	cmd := NewCommand("test").
+		Flag("-timeout").Duration().Global().Default(30 * time.Second).Env("MYAPP_TIMEOUT").Done().
+		Handler(func(ctx *Context) error {
+			timeout = ctx.GetDuration("-timeout", 0)
+			return nil
+		}).
+		Build()
+
+	if err := cmd.Execute([]string{}); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if timeout != 90*time.Second {
+		t.Errorf("expected 90s from env, got %v", timeout)
+	}
+}
+
+func TestEnv_InvalidValueIsParseError(t *testing.T) {
+	t.Setenv("MYAPP_COUNT", "many")
+	cmd := NewCommand("test").
+		Flag("-count").Int().Global().Env("MYAPP_COUNT").Done().
+		Handler(func(ctx *Context) error { return nil }).
+		Build()
+
+	err := cmd.Execute([]string{})
+	perr, ok := err.(ParseError)
+	if !ok {
+		t.Fatalf("expected ParseError, got %T: %v", err, err)
+	}
+	if perr.Flag != "-count" || !strings.Contains(perr.Message, "$MYAPP_COUNT") {
+		t.Errorf("unexpected error: %v", perr)
+	}
+}
+
+func TestEnv_SatisfiesRequired(t *testing.T) {
+	t.Setenv("MYAPP_USER", "alice")
+	cmd := NewCommand("test").
+		Flag("-user").String().Global().Required().Env("MYAPP_USER").Done().
+		Handler(func(ctx *Context) error { return nil }).
+		Build()
+
+	if err := cmd.Execute([]string{}); err != nil {
+		t.Fatalf("env var should satisfy Required(): %v", err)
+	}
+}
+
+func TestEnv_MultiArgAndBool(t *testing.T) {
+	t.Setenv("MYAPP_MATCH", "status active")
+	t.Setenv("MYAPP_VERBOSE", "true")
+	var match map[string]interface{}
+	var verbose bool
+	cmd := NewCommand("test").
+		Flag("-match").Arg("FIELD").Done().Arg("VALUE").Done().Global().Env("MYAPP_MATCH").Done().
+		Flag("-verbose").Bool().Global().Env("MYAPP_VERBOSE").Done().
+		Handler(func(ctx *Context) error {
+			match, _ = ctx.GlobalFlags["-match"].(map[string]interface{})
+			verbose = ctx.GetBool("-verbose", false)
+			return nil
+		}).
+		Build()
+
+	if err := cmd.Execute([]string{}); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if match["FIELD"] != "status" || match["VALUE"] != "active" {
+		t.Errorf("unexpected multi-arg env value: %v", match)
+	}
+	if !verbose {
+		t.Error("expected -verbose from env")
+	}
+}
+
+func TestEnv_TimeZoneFromFlag(t *testing.T) {
+	t.Setenv("MYAPP_TZ", "America/New_York")
+	t.Setenv("MYAPP_START", "2024-01-15 10:00")
+	var start time.Time
+	cmd := NewCommand("test").
+		Flag("-start").Time().TimeFormats("2006-01-02 15:04").TimeZoneFromFlag("-tz").Global().Env("MYAPP_START").Done().
+		Flag("-tz").String().Global().Env("MYAPP_TZ").Done().
+		Handler(func(ctx *Context) error {
+			start, _ = ctx.GlobalFlags["-start"].(time.Time)
+			return nil
+		}).
+		Build()
+
+	if err := cmd.Execute([]string{}); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if start.Location().String() != "America/New_York" {
+		t.Errorf("expected env-supplied zone, got %v", start.Location())
+	}
+}
+
+func TestEnv_Subcommand(t *testing.T) {
+	t.Setenv("MYAPP_FORMAT", "json")
+	var format string
+	cmd := NewCommand("app").
+		Subcommand("show").
+		Flag("-format").String().Global().Env("MYAPP_FORMAT").Done().
+		Handler(func(ctx *Context) error {
+			format = ctx.GetString("-format", "")
+			return nil
+		}).
+		Done().
+		Build()
+
+	if err := cmd.Execute([]string{"show"}); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if format != "json" {
+		t.Errorf("expected json from env, got %q", format)
+	}
+}
+
+func TestEnv_ShownInHelp(t *testing.T) {
+	cmd := NewCommand("test").
+		Flag("-input").String().Global().Env("MYAPP_INPUT").Help("Input file").Done().
+		Handler(func(ctx *Context) error { return nil }).
+		Build()
+
+	if help := cmd.GenerateHelp(); !strings.Contains(help, "Environment: $MYAPP_INPUT") {
+		t.Errorf("help missing env var:\n%s", help)
+	}
+	if man := cmd.GenerateManPage(); !strings.Contains(man, "MYAPP_INPUT") {
+		t.Errorf("man page missing env var:\n%s", man)
+	}
+	text, err := cmd.HelpAt([]string{"-input"}, 1)
+	if err != nil {
+		t.Fatalf("HelpAt: %v", err)
+	}
+	if !strings.Contains(text, "Environment: $MYAPP_INPUT") {
+		t.Errorf("HelpAt missing env var:\n%s", text)
+	}
+}
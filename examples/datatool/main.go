@@ -2,7 +2,6 @@ package main
 
 import (
 	"fmt"
-	"os"
 	"time"
 
 	cf "github.com/rosscartlidge/autocli/v4"
@@ -156,22 +155,16 @@ func main() {
 				fmt.Printf(":\n")
 
 				// Get filters from this clause
-				if filterVal, ok := clause.Flags["-filter"]; ok {
-					if filters, ok := filterVal.([]interface{}); ok {
-						for _, f := range filters {
-							if filterMap, ok := f.(map[string]interface{}); ok {
-								fmt.Printf("  Filter: %s %s %s\n",
-									filterMap["FIELD"],
-									filterMap["OPERATOR"],
-									filterMap["VALUE"])
-							}
-						}
-					}
+				for _, f := range clause.GetAll("-filter") {
+					fmt.Printf("  Filter: %s %s %s\n",
+						f.GetString("FIELD", ""),
+						f.GetString("OPERATOR", ""),
+						f.GetString("VALUE", ""))
 				}
 
 				// Get sort from this clause
-				if sortVal, ok := clause.Flags["-sort"]; ok {
-					fmt.Printf("  Sort by: %s\n", sortVal)
+				if sortBy := clause.GetString("-sort", ""); sortBy != "" {
+					fmt.Printf("  Sort by: %s\n", sortBy)
 				}
 
 				fmt.Println()
@@ -183,7 +176,8 @@ func main() {
 			//    - Apply filters
 			//    - Apply sort
 			//    - Collect results
-			// 3. Combine results (e.g., OR logic across clauses)
+			// 3. Combine results: ctx.ClauseSet().Match ORs the "+" clauses
+			//    and drops anything a "-" clause matches
 			// 4. Convert to format
 			// 5. Write to outputFile or stdout
 
@@ -194,8 +188,5 @@ func main() {
 
 		Build()
 
-	if err := cmd.Execute(os.Args[1:]); err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
-	}
+	cmd.Main()
 }
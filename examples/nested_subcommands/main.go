@@ -93,10 +93,7 @@ func main() {
 		Example("gitlike config set -global user.name \"John Doe\"", "Set global username").
 		Build()
 
-	if err := cmd.Execute(os.Args[1:]); err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
-	}
+	cmd.Main()
 }
 
 func handleCommand(ctx *cf.Context) error {
@@ -2,7 +2,6 @@ package main
 
 import (
 	"fmt"
-	"os"
 
 	cf "github.com/rosscartlidge/autocli/v4"
 )
@@ -47,8 +46,5 @@ func main() {
 		}).
 		Build()
 
-	if err := cmd.Execute(os.Args[1:]); err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
-	}
+	cmd.Main()
 }
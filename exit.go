@@ -0,0 +1,165 @@
+package completionflags
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Exit codes used by Main
+const (
+	ExitOK      = 0   // Success
+	ExitFailure = 1   // Runtime failure: the handler returned an error
+	ExitUsage   = 2   // Bad usage: unknown flag or command, invalid or missing value
+	ExitTimeout = 124 // The handler ran past its timeout, as with timeout(1)
+)
+
+// ExitError is an error a handler returns to choose the process exit code
+// Main uses. An empty Message prints nothing, for handlers that have
+// already reported the problem themselves.
+//
+//	return cf.ExitError{Code: 3, Message: "cluster unreachable", Hint: "check -endpoint"}
+type ExitError struct {
+	Code    int
+	Message string
+	Hint    string // Printed on its own line after the message (optional)
+	Err     error  // Underlying error, if any
+}
+
+func (e ExitError) Error() string {
+	switch {
+	case e.Message != "":
+		return e.Message
+	case e.Err != nil:
+		return e.Err.Error()
+	}
+	return fmt.Sprintf("exit status %d", e.Code)
+}
+
+// Unwrap returns the underlying error
+func (e ExitError) Unwrap() error {
+	return e.Err
+}
+
+// ExitCode returns the process exit code for err: ExitOK for nil, the Code
+// of an ExitError, 128 plus the signal number for an InterruptedError,
+// ExitTimeout for a TimeoutError, ExitUsage for parse, validation and unknown command errors, and
+// ExitFailure for anything else
+func ExitCode(err error) int {
+	if err == nil {
+		return ExitOK
+	}
+	var exit ExitError
+	if errors.As(err, &exit) {
+		return exit.Code
+	}
+	var interrupted InterruptedError
+	if errors.As(err, &interrupted) {
+		return interrupted.exitCode()
+	}
+	var timeout TimeoutError
+	if errors.As(err, &timeout) {
+		return ExitTimeout
+	}
+	if isUsageError(err) {
+		return ExitUsage
+	}
+	return ExitFailure
+}
+
+// isUsageError reports whether err means the command line was wrong
+func isUsageError(err error) bool {
+	var parseErr ParseError
+	var validationErr ValidationError
+	var unknown ErrUnknownCommand
+	return errors.As(err, &parseErr) || errors.As(err, &validationErr) || errors.As(err, &unknown)
+}
+
+// Main runs Execute(os.Args[1:]) and exits the process with ExitCode of the
+// result. Errors are printed to stderr as "Error: ..."; usage errors are
+// followed by the usage line of the subcommand the arguments resolved to.
+//
+//	func main() {
+//	    cf.NewCommand("tool").
+//	        // ...
+//	        Build().
+//	        Main()
+//	}
+func (cmd *Command) Main() {
+	os.Exit(cmd.run(os.Args[1:], os.Stderr))
+}
+
+// run executes args, reports any error to stderr and returns the exit code
+func (cmd *Command) run(args []string, stderr io.Writer) int {
+	err := cmd.Execute(args)
+	if err == nil {
+		return ExitOK
+	}
+	cmd.reportError(stderr, args, err)
+	return ExitCode(err)
+}
+
+// reportError prints err as Main does
+func (cmd *Command) reportError(w io.Writer, args []string, err error) {
+	var exit ExitError
+	if errors.As(err, &exit) {
+		if exit.Message == "" && exit.Err == nil {
+			return
+		}
+		fmt.Fprintf(w, "Error: %v\n", err)
+		if exit.Hint != "" {
+			fmt.Fprintf(w, "Hint: %s\n", exit.Hint)
+		}
+		return
+	}
+
+	fmt.Fprintf(w, "Error: %v\n", err)
+	if !isUsageError(err) {
+		return
+	}
+	help, name := cmd.resolvedHelp(args)
+	if usage := usageSection(help); usage != "" {
+		fmt.Fprintf(w, "\nUsage:\n%s\n", usage)
+	}
+	fmt.Fprintf(w, "Run '%s -help' for more information.\n", name)
+}
+
+// resolvedHelp returns the help text and full name of the deepest
+// subcommand args name, or of the root command
+func (cmd *Command) resolvedHelp(args []string) (string, string) {
+	_, remaining, err := cmd.parseRootGlobalFlags(args)
+	if err != nil {
+		remaining = nil
+	}
+
+	var leaf *Subcommand
+	var path []string
+	subcommands := cmd.subcommands
+	for _, word := range remaining {
+		subcmd, subcommandName := cmd.resolveSubcommand(subcommands, word)
+		if subcmd == nil {
+			break
+		}
+		leaf = subcmd
+		path = append(path, subcommandName)
+		subcommands = subcmd.Subcommands
+	}
+
+	if leaf == nil {
+		return cmd.GenerateHelp(), cmd.name
+	}
+	parent := strings.Join(append([]string{cmd.name}, path[:len(path)-1]...), " ")
+	return leaf.GenerateHelp(parent), parent + " " + leaf.Name
+}
+
+// usageSection extracts the lines under "USAGE:" from help text
+func usageSection(help string) string {
+	_, rest, ok := strings.Cut(help, "USAGE:\n")
+	if !ok {
+		return ""
+	}
+	usage, _, _ := strings.Cut(rest, "\n\n")
+	return strings.TrimRight(usage, "\n")
+}
@@ -0,0 +1,95 @@
+package completionflags
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{nil, ExitOK},
+		{errors.New("boom"), ExitFailure},
+		{ParseError{Flag: "-x", Message: "unknown flag"}, ExitUsage},
+		{ValidationError{Message: "missing"}, ExitUsage},
+		{UnknownCommandError{Name: "frob"}, ExitUsage},
+		{fmt.Errorf("wrapped: %w", ExitError{Code: 7}), 7},
+		{ExitError{Code: 3, Err: ParseError{Message: "inner"}}, 3},
+	}
+	for _, tt := range tests {
+		if got := ExitCode(tt.err); got != tt.want {
+			t.Errorf("ExitCode(%v) = %d, want %d", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestMain_Run(t *testing.T) {
+	cmd := NewCommand("tool").
+		Flag("-v").Bool().Global().Done().
+		Subcommand("remote").
+		Subcommand("add").
+		Flag("-name").String().Required().Done().
+		Handler(func(ctx *Context) error { return nil }).
+		Done().
+		Done().
+		Subcommand("fail").
+		Flag("-code").Int().Default(0).Global().Done().
+		Handler(func(ctx *Context) error {
+			if code := ctx.GetInt("-code", 0); code != 0 {
+				return ExitError{Code: code, Message: "unreachable", Hint: "check -endpoint"}
+			}
+			return errors.New("disk full")
+		}).
+		Done().
+		Build()
+
+	tests := []struct {
+		args []string
+		code int
+		want []string
+	}{
+		{[]string{"remote", "add", "-name", "x"}, ExitOK, nil},
+		{[]string{"fail"}, ExitFailure, []string{"Error: disk full\n"}},
+		{[]string{"fail", "-code", "4"}, 4, []string{"Error: unreachable\nHint: check -endpoint\n"}},
+		{[]string{"-v", "remote", "add"}, ExitUsage, []string{
+			"Error: validation failed for -name",
+			"Usage:\n    tool remote add [OPTIONS]",
+			"Run 'tool remote add -help' for more information.",
+		}},
+		{[]string{"frob"}, ExitUsage, []string{
+			`Error: unknown command: "frob"`,
+			"Usage:\n    tool [GLOBAL OPTIONS] <COMMAND>",
+			"Run 'tool -help'",
+		}},
+	}
+	for _, tt := range tests {
+		var stderr bytes.Buffer
+		if code := cmd.run(tt.args, &stderr); code != tt.code {
+			t.Errorf("%v: code = %d, want %d", tt.args, code, tt.code)
+		}
+		for _, want := range tt.want {
+			if !strings.Contains(stderr.String(), want) {
+				t.Errorf("%v: stderr missing %q:\n%s", tt.args, want, stderr.String())
+			}
+		}
+		if tt.want == nil && stderr.Len() > 0 {
+			t.Errorf("%v: unexpected stderr %q", tt.args, stderr.String())
+		}
+	}
+}
+
+func TestMain_SilentExitError(t *testing.T) {
+	cmd := NewCommand("tool").
+		Handler(func(ctx *Context) error { return ExitError{Code: 5} }).
+		Build()
+
+	var stderr bytes.Buffer
+	if code := cmd.run(nil, &stderr); code != 5 || stderr.Len() != 0 {
+		t.Errorf("code = %d, stderr = %q", code, stderr.String())
+	}
+}
@@ -0,0 +1,236 @@
+package completionflags
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// explanation is what -explain reports about a parsed command line
+type explanation struct {
+	Command        string            `json:"command"`
+	SubcommandPath []string          `json:"subcommand_path"`
+	Args           []string          `json:"args"`
+	GlobalFlags    []explainedFlag   `json:"global_flags"`
+	Clauses        []explainedClause `json:"clauses"`
+	Expression     string            `json:"expression,omitempty"`
+	RemainingArgs  []string          `json:"remaining_args"`
+}
+
+// explainedFlag is one flag or positional value and where it came from
+type explainedFlag struct {
+	Name       string      `json:"name"`
+	Value      interface{} `json:"value"`
+	Source     string      `json:"source"`
+	Positional bool        `json:"positional,omitempty"`
+	Secret     bool        `json:"secret,omitempty"`   // Value replaced by "********"
+	Deferred   bool        `json:"deferred,omitempty"` // Parsed after every layer (DependsOn/ParseWith)
+}
+
+// explainedClause is one clause with its separator
+type explainedClause struct {
+	Separator  string          `json:"separator"`
+	Flags      []explainedFlag `json:"flags"`
+	Positional []string        `json:"positional"` // Arguments no positional spec took
+}
+
+// explain parses args like ExecuteWith, through the subcommand walk,
+// environment, config files, defaults and validation, then describes the
+// result instead of running the handler. Nothing is prompted for.
+func (cmd *Command) explain(args []string, base *Context, asJSON bool) error {
+	return cmd.dispatch(args, base, nil, func(ctx *Context, chain []*Subcommand) error {
+		e := cmd.explanation(ctx, chain, args)
+		if asJSON {
+			enc := json.NewEncoder(base.Stdout())
+			enc.SetIndent("", "  ")
+			return enc.Encode(e)
+		}
+		e.writeText(base.Stdout())
+		return nil
+	})
+}
+
+// explanation collects the parsed values of ctx, whose handler is the
+// last subcommand in chain (or the root command when chain is empty). args
+// is the whole command line; a subcommand's RawArgs has only its own words.
+func (cmd *Command) explanation(ctx *Context, chain []*Subcommand, args []string) explanation {
+	specs := cmd.flags
+	if len(chain) > 0 {
+		specs = append(cmd.rootGlobalFlags(), chain[len(chain)-1].Flags...)
+	}
+	find := func(name string) *FlagSpec {
+		for _, spec := range specs {
+			if spec.Names[0] == name {
+				return spec
+			}
+		}
+		return nil
+	}
+
+	e := explanation{
+		Command:        strings.Join(append([]string{cmd.name}, ctx.SubcommandPath...), " "),
+		SubcommandPath: nonNil(ctx.SubcommandPath),
+		Args:           nonNil(cmd.RedactArgs(args)),
+		GlobalFlags:    explainFlags(ctx, ctx.GlobalFlags, ctx.sources, find),
+		Clauses:        []explainedClause{},
+		RemainingArgs:  nonNil(ctx.RemainingArgs),
+	}
+	for _, clause := range ctx.Clauses {
+		e.Clauses = append(e.Clauses, explainedClause{
+			Separator:  clause.Separator,
+			Flags:      explainFlags(ctx, clause.Flags, clause.sources, find),
+			Positional: nonNil(clause.Positional),
+		})
+	}
+	if ctx.ClauseTree != nil {
+		e.Expression = ctx.ClauseTree.String()
+	}
+	return e
+}
+
+// explainFlags lists the values in flags by name, with Secret values
+// redacted
+func explainFlags(ctx *Context, flags map[string]interface{}, sources map[string]ValueSource, find func(string) *FlagSpec) []explainedFlag {
+	names := make([]string, 0, len(flags))
+	for name := range flags {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	out := []explainedFlag{}
+	for _, name := range names {
+		f := explainedFlag{
+			Name:     name,
+			Value:    explainValue(flags[name]),
+			Source:   sources[name].String(),
+			Deferred: ctx.wasDeferred(name, flags),
+		}
+		if spec := find(name); spec != nil {
+			f.Positional = spec.isPositional()
+			if spec.Secret {
+				f.Value, f.Secret = redacted, true
+			}
+		}
+		out = append(out, f)
+	}
+	return out
+}
+
+// wasDeferred reports whether resolveDeferredValues parsed flag name in
+// target (GlobalFlags or a clause's Flags)
+func (ctx *Context) wasDeferred(name string, target map[string]interface{}) bool {
+	for _, deferred := range ctx.resolved {
+		if deferred.spec.Names[0] == name && reflect.ValueOf(deferred.target).Pointer() == reflect.ValueOf(target).Pointer() {
+			return true
+		}
+	}
+	return false
+}
+
+// explainValue converts a parsed value to one that reads well as text and
+// JSON: scalars and slices stay as they are, anything else (durations,
+// times, IPs, ...) becomes its string form
+func explainValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case nil, string, bool, int, int64, float64:
+		return v
+	case time.Duration:
+		return v.String()
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, item := range v {
+			out[i] = explainValue(item)
+		}
+		return out
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			out[k] = explainValue(item)
+		}
+		return out
+	case []string:
+		return v
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// writeText prints the explanation for a person
+func (e explanation) writeText(w io.Writer) {
+	fmt.Fprintf(w, "Command: %s\n", e.Command)
+	fmt.Fprintf(w, "Arguments: %s\n", strings.Join(e.Args, " "))
+
+	writeFlags := func(title string, flags []explainedFlag) {
+		fmt.Fprintf(w, "%s:\n", title)
+		if len(flags) == 0 {
+			fmt.Fprintln(w, "  (none)")
+			return
+		}
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		for _, f := range flags {
+			source := f.Source
+			if f.Positional {
+				source += ", positional"
+			}
+			if f.Deferred {
+				source += ", deferred"
+			}
+			value := formatExplainValue(f.Value)
+			if f.Secret {
+				value = redacted
+			}
+			fmt.Fprintf(tw, "  %s\t%s\t%s\n", f.Name, value, source)
+		}
+		tw.Flush()
+	}
+
+	writeFlags("Global flags", e.GlobalFlags)
+	for i, clause := range e.Clauses {
+		// Numbered from 0 like clause errors (see clauseLabel)
+		title := fmt.Sprintf("Clause %d", i)
+		if clause.Separator != "" {
+			title += fmt.Sprintf(" (after %s)", strconv.Quote(clause.Separator))
+		}
+		writeFlags(title, clause.Flags)
+		if len(clause.Positional) > 0 {
+			fmt.Fprintf(w, "  unbound positional: %s\n", strings.Join(clause.Positional, " "))
+		}
+	}
+	if e.Expression != "" {
+		fmt.Fprintf(w, "Expression: %s\n", e.Expression)
+	}
+	if len(e.RemainingArgs) > 0 {
+		fmt.Fprintf(w, "Remaining arguments: %s\n", strings.Join(e.RemainingArgs, " "))
+	}
+	fmt.Fprintln(w, "Handler not run (-explain).")
+}
+
+// formatExplainValue quotes strings so empty and spaced values show
+func formatExplainValue(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return fmt.Sprintf("%q", v)
+	case []interface{}:
+		parts := make([]string, len(v))
+		for i, item := range v {
+			parts[i] = formatExplainValue(item)
+		}
+		return "[" + strings.Join(parts, " ") + "]"
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// nonNil returns s, or an empty slice for nil so JSON shows []
+func nonNil(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
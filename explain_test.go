@@ -0,0 +1,110 @@
+package completionflags
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestExplain_Text(t *testing.T) {
+	var ran bool
+	cmd := NewCommand("tool").
+		Flag("-verbose").Bool().Global().Done().
+		Flag("-token").String().Secret().Global().Done().
+		Subcommand("query").
+		Flag("-unit").String().Default("KB").Global().Done().
+		Flag("-size").DependsOn("-unit").ParseWith(parseSize).Global().Done().
+		Flag("-filter").String().Local().Done().
+		Flag("TABLE").String().Global().Done().
+		Handler(func(ctx *Context) error {
+			ran = true
+			return nil
+		}).
+		Done().
+		Build()
+
+	var out bytes.Buffer
+	args := []string{"-explain", "-token", "hunter2", "query", "-size", "2", "users", "-filter", "a", "+", "-filter", "b c", "--", "x"}
+	if err := cmd.ExecuteWith(args, (&Context{}).SetStdout(&out)); err != nil {
+		t.Fatal(err)
+	}
+	if ran {
+		t.Error("handler ran under -explain")
+	}
+	text := strings.Join(strings.Fields(out.String()), " ") // Ignore column padding
+	for _, want := range []string{
+		"Command: tool query",
+		"Arguments: -token ******** query -size 2 users",
+		"-size 2000 argv, deferred",
+		"-token ******** argv",
+		`-unit "KB" default`,
+		`TABLE "users" argv, positional`,
+		`Clause 0: -filter "a" argv`,
+		`Clause 1 (after "+"): -filter "b c" argv`,
+		"Remaining arguments: x",
+		"Handler not run",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("output missing %q:\n%s", want, text)
+		}
+	}
+	if strings.Contains(text, "hunter2") {
+		t.Errorf("secret in output:\n%s", text)
+	}
+}
+
+func TestExplain_JSON(t *testing.T) {
+	var ran bool
+	cmd := NewCommand("tool").
+		Subcommand("query").
+		Flag("-unit").String().Default("KB").Global().Done().
+		Flag("-size").DependsOn("-unit").ParseWith(parseSize).Global().Done().
+		Flag("-filter").String().Local().Done().
+		Handler(func(ctx *Context) error {
+			ran = true
+			return nil
+		}).
+		Done().
+		Build()
+
+	var out bytes.Buffer
+	args := []string{"-explain-json", "query", "-size", "2", "-filter", "a"}
+	if err := cmd.ExecuteWith(args, (&Context{}).SetStdout(&out)); err != nil {
+		t.Fatal(err)
+	}
+
+	var e explanation
+	if err := json.Unmarshal(out.Bytes(), &e); err != nil {
+		t.Fatalf("%v:\n%s", err, out.String())
+	}
+	if ran || e.Command != "tool query" || len(e.SubcommandPath) != 1 || len(e.Clauses) != 1 {
+		t.Errorf("explanation = %+v", e)
+	}
+	var size *explainedFlag
+	for i := range e.GlobalFlags {
+		if e.GlobalFlags[i].Name == "-size" {
+			size = &e.GlobalFlags[i]
+		}
+	}
+	if size == nil || size.Value != 2000.0 || !size.Deferred || size.Source != "argv" {
+		t.Errorf("-size = %+v", size)
+	}
+	if f := e.Clauses[0].Flags; len(f) != 1 || f[0].Name != "-filter" || f[0].Value != "a" {
+		t.Errorf("clause flags = %+v", f)
+	}
+}
+
+func TestExplain_ReportsErrors(t *testing.T) {
+	cmd := NewCommand("tool").
+		Subcommand("query").
+		Flag("-filter").String().Local().Done().
+		Handler(func(ctx *Context) error { return nil }).
+		Done().
+		Build()
+
+	err := cmd.ExecuteWith([]string{"-explain", "query", "-bogus"}, (&Context{}).SetStdout(&bytes.Buffer{}))
+	if err == nil || !strings.Contains(err.Error(), "-bogus") {
+		t.Errorf("error = %v", err)
+	}
+}
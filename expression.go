@@ -0,0 +1,352 @@
+package completionflags
+
+import (
+	"fmt"
+	"strings"
+)
+
+// exprSyntax holds the operator spellings of expression clause mode
+// (ExpressionClauses). The clause separators stay operators too: "-" is
+// AND NOT and every other separator is OR.
+type exprSyntax struct {
+	and string
+	or  string
+	not string
+}
+
+// Group tokens of expression clause mode
+const (
+	exprOpen  = "("
+	exprClose = ")"
+)
+
+// isToken reports whether s is an operator or parenthesis
+func (e *exprSyntax) isToken(s string) bool {
+	return s == exprOpen || s == exprClose || s == e.and || s == e.or || s == e.not
+}
+
+// ClauseOp is the kind of a ClauseNode
+type ClauseOp int
+
+const (
+	ClauseLeaf ClauseOp = iota // A single clause (Clause is set)
+	ClauseAnd                  // Every child matches (-and)
+	ClauseOr                   // Some child matches (-or, +)
+	ClauseNot                  // The only child doesn't match (-not)
+)
+
+// String returns the operator's name
+func (op ClauseOp) String() string {
+	switch op {
+	case ClauseLeaf:
+		return "clause"
+	case ClauseAnd:
+		return "and"
+	case ClauseOr:
+		return "or"
+	case ClauseNot:
+		return "not"
+	}
+	return fmt.Sprintf("ClauseOp(%d)", int(op))
+}
+
+// ClauseNode is a node of the expression built in ExpressionClauses mode.
+// Leaves point at an element of Context.Clauses; "a - b" is stored as
+// and(a, not(b)).
+type ClauseNode struct {
+	Op       ClauseOp
+	Clause   *Clause       // For ClauseLeaf
+	Children []*ClauseNode // For ClauseAnd, ClauseOr and ClauseNot
+}
+
+// Eval evaluates the expression, calling match for the leaves it needs. A
+// nil tree (no clauses given) matches everything.
+func (n *ClauseNode) Eval(match func(Clause) bool) bool {
+	if n == nil {
+		return true
+	}
+	switch n.Op {
+	case ClauseLeaf:
+		return match(*n.Clause)
+	case ClauseAnd:
+		for _, child := range n.Children {
+			if !child.Eval(match) {
+				return false
+			}
+		}
+		return true
+	case ClauseOr:
+		for _, child := range n.Children {
+			if child.Eval(match) {
+				return true
+			}
+		}
+		return false
+	case ClauseNot:
+		return !n.Children[0].Eval(match)
+	}
+	return false
+}
+
+// String renders the expression with one letter per leaf clause in order
+// (a, b, c, ...), e.g. "and(or(a, b), not(c))"
+func (n *ClauseNode) String() string {
+	if n == nil {
+		return "<all>"
+	}
+	leaves := 0
+	var render func(*ClauseNode) string
+	render = func(n *ClauseNode) string {
+		if n.Op == ClauseLeaf {
+			leaves++
+			return string(rune('a' + (leaves-1)%26))
+		}
+		parts := make([]string, len(n.Children))
+		for i, child := range n.Children {
+			parts[i] = render(child)
+		}
+		return n.Op.String() + "(" + strings.Join(parts, ", ") + ")"
+	}
+	return render(n)
+}
+
+// exprToken is one element of the token stream recovered from the clause
+// segments: an operator or parenthesis, or (leaf >= 0) a non-empty clause
+type exprToken struct {
+	text string
+	leaf int
+}
+
+// buildClauseTree turns the segments Parse split at operators and
+// parentheses into ctx.ClauseTree. Segments without flags or positionals
+// (such as the one before a leading "(") are dropped, so ctx.Clauses ends
+// up holding only the leaves; each leaf's Separator is the binary operator
+// before it. The segment still open at the end of args is kept for
+// completion.
+func (cmd *Command) buildClauseTree(ctx *Context) error {
+	segments := ctx.Clauses
+	trailing := segments[len(segments)-1]
+	ctx.trailing = &trailing
+
+	var tokens []exprToken
+	var leaves []Clause
+	operator := ""
+	for i, segment := range segments {
+		if i > 0 {
+			tokens = append(tokens, exprToken{text: segment.Separator, leaf: -1})
+			if segment.Separator != exprOpen && segment.Separator != exprClose && segment.Separator != cmd.expr.not {
+				operator = segment.Separator
+			}
+		}
+		if segment.isEmpty() {
+			continue
+		}
+		segment.Separator = operator
+		operator = ""
+		tokens = append(tokens, exprToken{leaf: len(leaves)})
+		leaves = append(leaves, segment)
+	}
+
+	if len(leaves) == 0 {
+		ctx.Clauses = []Clause{{Flags: make(map[string]interface{}), Positional: []string{}}}
+	} else {
+		ctx.Clauses = leaves
+		if !segments[len(segments)-1].isEmpty() {
+			ctx.trailing = &ctx.Clauses[len(ctx.Clauses)-1]
+		}
+	}
+
+	p := exprParser{cmd: cmd, tokens: tokens, clauses: ctx.Clauses}
+	if len(tokens) == 0 {
+		return nil
+	}
+	tree, err := p.parseOr()
+	if err == nil && p.pos < len(tokens) {
+		err = p.errorf("unexpected %q", tokens[p.pos].text)
+	}
+	if err != nil {
+		return err
+	}
+	ctx.ClauseTree = tree
+	return nil
+}
+
+// exprParser is a recursive-descent parser over the token stream:
+//
+//	or     = and { (or-op | separator) and }
+//	and    = factor { and-op factor }
+//	factor = not-op factor | "(" or ")" | clause
+type exprParser struct {
+	cmd     *Command
+	tokens  []exprToken
+	clauses []Clause
+	pos     int
+}
+
+func (p *exprParser) errorf(format string, args ...interface{}) error {
+	return ParseError{Message: "clause expression: " + fmt.Sprintf(format, args...)}
+}
+
+// peekOp returns the operator at the cursor, or "" for a clause or the end
+func (p *exprParser) peekOp() string {
+	if p.pos >= len(p.tokens) || p.tokens[p.pos].leaf >= 0 {
+		return ""
+	}
+	return p.tokens[p.pos].text
+}
+
+func (p *exprParser) parseOr() (*ClauseNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op := p.peekOp()
+		if op != p.cmd.expr.or && !p.cmd.isClauseSeparator(op) {
+			return left, nil
+		}
+		p.pos++
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		if op == "-" {
+			left = joinClauseNodes(ClauseAnd, left, &ClauseNode{Op: ClauseNot, Children: []*ClauseNode{right}})
+		} else {
+			left = joinClauseNodes(ClauseOr, left, right)
+		}
+	}
+}
+
+func (p *exprParser) parseAnd() (*ClauseNode, error) {
+	left, err := p.parseFactor()
+	if err != nil {
+		return nil, err
+	}
+	for p.peekOp() == p.cmd.expr.and {
+		p.pos++
+		right, err := p.parseFactor()
+		if err != nil {
+			return nil, err
+		}
+		left = joinClauseNodes(ClauseAnd, left, right)
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseFactor() (*ClauseNode, error) {
+	if p.pos >= len(p.tokens) {
+		return nil, p.errorf("missing clause at end")
+	}
+	tok := p.tokens[p.pos]
+	p.pos++
+	switch {
+	case tok.leaf >= 0:
+		return &ClauseNode{Op: ClauseLeaf, Clause: &p.clauses[tok.leaf]}, nil
+	case tok.text == p.cmd.expr.not:
+		child, err := p.parseFactor()
+		if err != nil {
+			return nil, err
+		}
+		return &ClauseNode{Op: ClauseNot, Children: []*ClauseNode{child}}, nil
+	case tok.text == exprOpen:
+		if p.peekOp() == exprClose {
+			return nil, p.errorf("empty group")
+		}
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.peekOp() != exprClose {
+			return nil, p.errorf("missing %q", exprClose)
+		}
+		p.pos++
+		return inner, nil
+	}
+	return nil, p.errorf("expected a clause before %q", tok.text)
+}
+
+// joinClauseNodes combines left and right under op, flattening a left
+// operand that already has the same operator: a -or b -or c is or(a, b, c)
+func joinClauseNodes(op ClauseOp, left, right *ClauseNode) *ClauseNode {
+	if left.Op == op && op != ClauseNot {
+		left.Children = append(left.Children, right)
+		return left
+	}
+	return &ClauseNode{Op: op, Children: []*ClauseNode{left, right}}
+}
+
+// isClauseSeparator reports whether s is one of the command's clause
+// separators, not counting expression operators
+func (cmd *Command) isClauseSeparator(s string) bool {
+	for _, sep := range cmd.separators {
+		if s == sep {
+			return true
+		}
+	}
+	return false
+}
+
+// groupDepth returns how many "(" are still open in args
+func (cmd *Command) groupDepth(args []string) int {
+	if cmd.expr == nil {
+		return 0
+	}
+	depth := 0
+	for _, arg := range args {
+		switch arg {
+		case "--":
+			return depth
+		case exprOpen:
+			depth++
+		case exprClose:
+			if depth > 0 {
+				depth--
+			}
+		}
+	}
+	return depth
+}
+
+// exprCompletions offers the operators spelled like flags that start with
+// partial, and ")" when a group is open
+func (cmd *Command) exprCompletions(args []string, partial string) []string {
+	if cmd.expr == nil {
+		return nil
+	}
+	var matches []string
+	for _, op := range []string{cmd.expr.and, cmd.expr.or, cmd.expr.not} {
+		if partial != "" && strings.HasPrefix(op, partial) {
+			matches = append(matches, op)
+		}
+	}
+	if (partial == "" || partial == exprClose) && cmd.groupDepth(args) > 0 {
+		matches = append(matches, exprClose)
+	}
+	return matches
+}
+
+// exprHelpAt describes the operator or parenthesis under the cursor
+func (cmd *Command) exprHelpAt(word string) (string, bool) {
+	if cmd.expr == nil {
+		return "", false
+	}
+	var text string
+	switch {
+	case word == exprOpen || word == exprClose:
+		text = "Groups clauses: ( a " + cmd.expr.or + " b ) " + cmd.expr.and + " c"
+	case word == cmd.expr.and:
+		text = "Both sides must match"
+	case word == cmd.expr.or:
+		text = "Either side may match"
+	case word == cmd.expr.not:
+		text = "The clause or group that follows must not match"
+	case word == "-" && cmd.isClauseSeparator(word):
+		text = "Matches the left side except what the right side matches"
+	case cmd.isClauseSeparator(word):
+		text = "Either side may match"
+	default:
+		return "", false
+	}
+	return word + "\n    " + text + "\n", true
+}
@@ -0,0 +1,186 @@
+package completionflags
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExpressionClauses_Tree(t *testing.T) {
+	var ctx *Context
+	cmd := NewCommand("tool").
+		ExpressionClauses().
+		Flag("-limit").Int().Global().Done().
+		Flag("-f").
+		Arg("FIELD").Completer(&StaticCompleter{Options: []string{"status", "role"}}).Done().
+		Arg("VALUE").Done().
+		Accumulate().
+		Local().
+		Done().
+		Handler(func(c *Context) error {
+			ctx = c
+			return nil
+		}).
+		Build()
+
+	tests := []struct {
+		args string
+		want string
+	}{
+		{"-f status a", "a"},
+		{"( -f status a + -f role b ) - -f role c", "and(or(a, b), not(c))"},
+		{"-limit 5 -f status a -or -f role b -or -f role c", "or(a, b, c)"},
+		{"-f status a -or -f role b -and -not -f role c", "or(a, and(b, not(c)))"},
+		{"-not ( -f status a -and -f role b )", "not(and(a, b))"},
+	}
+	for _, tt := range tests {
+		if err := cmd.Execute(strings.Fields(tt.args)); err != nil {
+			t.Errorf("%s: %v", tt.args, err)
+			continue
+		}
+		if got := ctx.ClauseTree.String(); got != tt.want {
+			t.Errorf("%s: tree = %s, want %s", tt.args, got, tt.want)
+		}
+	}
+
+	// Clauses holds only the leaves, in order
+	if err := cmd.Execute(strings.Fields("-limit 5 ( -f status a + -f role b ) - -f role c")); err != nil {
+		t.Fatal(err)
+	}
+	if len(ctx.Clauses) != 3 || ctx.Clauses[2].Separator != "-" || ctx.GetInt("-limit", 0) != 5 {
+		t.Errorf("clauses = %+v", ctx.Clauses)
+	}
+
+	rows := []map[string]string{
+		{"status": "a", "role": "x"},
+		{"status": "z", "role": "b"},
+		{"status": "a", "role": "c"},
+	}
+	var kept []int
+	for i, row := range rows {
+		if ctx.ClauseTree.Eval(func(c Clause) bool {
+			for _, f := range c.GetAll("-f") {
+				if row[f.GetString("FIELD", "")] != f.GetString("VALUE", "") {
+					return false
+				}
+			}
+			return true
+		}) {
+			kept = append(kept, i)
+		}
+	}
+	if !reflect.DeepEqual(kept, []int{0, 1}) {
+		t.Errorf("Eval kept rows %v, want [0 1]", kept)
+	}
+}
+
+func TestExpressionClauses_Errors(t *testing.T) {
+	var ctx *Context
+	cmd := NewCommand("tool").
+		ExpressionClauses().
+		Flag("-f").Args(2).Accumulate().Local().Done().
+		Handler(func(c *Context) error { return nil }).
+		Build()
+
+	for _, args := range []string{
+		"( -f status a",
+		"-f status a )",
+		"-f status a -and",
+		"( ) -f status a",
+		"-or -f status a",
+	} {
+		err := cmd.Execute(strings.Fields(args))
+		if err == nil || !strings.Contains(err.Error(), "clause expression") {
+			t.Errorf("%s: got %v, want a clause expression error", args, err)
+		}
+		if parsed, err := cmd.Parse(strings.Fields(args)); parsed != nil || err == nil {
+			t.Errorf("%s: Parse returned %v, %v; want nil and an error", args, parsed, err)
+		}
+	}
+
+	// Without opting in the tokens are ordinary words
+	plain := NewCommand("tool").
+		Flag("-x").Bool().Local().Done().
+		Handler(func(c *Context) error {
+			ctx = c
+			return nil
+		}).
+		Build()
+	if err := plain.Execute([]string{"-x", "(", "+", "-x"}); err != nil {
+		t.Fatal(err)
+	}
+	if ctx.ClauseTree != nil || len(ctx.Clauses) != 2 || ctx.Clauses[0].Positional[0] != "(" {
+		t.Errorf("flat mode changed: %+v", ctx.Clauses)
+	}
+}
+
+func TestExpressionClauses_Completion(t *testing.T) {
+	cmd := NewCommand("tool").
+		ExpressionClauses().
+		Flag("-limit").Int().Global().Done().
+		Flag("-f").
+		Arg("FIELD").Completer(&StaticCompleter{Options: []string{"status", "role"}}).Done().
+		Arg("VALUE").Done().
+		Accumulate().
+		Local().
+		Done().
+		Handler(func(ctx *Context) error { return nil }).
+		Build()
+
+	// Operators are offered alongside the flags
+	matches, _ := cmd.Complete([]string{"-f", "status", "a", "-and", "(", "-"}, 6)
+	if !reflect.DeepEqual(matches, []string{"-limit", "-f", "--help", "-and", "-or", "-not"}) {
+		t.Errorf("flags in new group: got %v", matches)
+	}
+	matches, _ = cmd.Complete([]string{"(", "-f", ""}, 3)
+	if !reflect.DeepEqual(matches, []string{"status", "role"}) {
+		t.Errorf("flag argument inside group: got %v", matches)
+	}
+	matches, _ = cmd.Complete([]string{"(", "-f", "status", "a", ""}, 5)
+	if !contains(matches, ")") {
+		t.Errorf("expected ) inside an open group, got %v", matches)
+	}
+	matches, _ = cmd.Complete([]string{"-a"}, 1)
+	if !reflect.DeepEqual(matches, []string{"-and"}) {
+		t.Errorf("operator: got %v", matches)
+	}
+
+	text, err := cmd.HelpAt([]string{"-f", "status", "a", "-not"}, 4)
+	if err != nil || !strings.Contains(text, "must not match") {
+		t.Errorf("help at operator: %q, %v", text, err)
+	}
+	text, _ = cmd.HelpAt([]string{"(", "-f", ""}, 3)
+	if !strings.Contains(text, "FIELD") {
+		t.Errorf("help at flag argument inside group:\n%s", text)
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+func TestExpressionClauses_Subcommand(t *testing.T) {
+	var ctx *Context
+	cmd := NewCommand("tool").
+		ExpressionClauses().
+		Subcommand("find").
+		Flag("-name").String().Local().Done().
+		Handler(func(c *Context) error {
+			ctx = c
+			return nil
+		}).
+		Done().
+		Build()
+
+	if err := cmd.Execute(strings.Fields("find -not ( -name a -or -name b )")); err != nil {
+		t.Fatal(err)
+	}
+	if got := ctx.ClauseTree.String(); got != "not(or(a, b))" {
+		t.Errorf("tree = %s", got)
+	}
+}
@@ -2,9 +2,13 @@ package completionflags
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"net"
+	"net/url"
 	"os"
+	"regexp"
 	"strings"
 	"time"
 )
@@ -24,6 +28,41 @@ type Command struct {
 	prefixHandler PrefixHandler
 	examples      []Example
 	subcommands   map[string]*Subcommand // Subcommands for this command
+	groups        []FlagGroup            // Constraints across flags (MutuallyExclusive, ...)
+
+	// Configuration files (see ConfigFile). configPath, configTree,
+	// configRootFlags and configOverride are set on the temporary
+	// per-subcommand Command built for parsing: the subcommand path selecting
+	// the config sections, the root's subcommands telling sections from flag
+	// values, the root global flags every section on it configures, and a
+	// -config value given before the subcommand name.
+	configFiles     []string
+	configPath      []string
+	configTree      map[string]*Subcommand
+	configRootFlags []*FlagSpec
+	configOverride  string
+
+	allowPrefixMatch bool        // Resolve unambiguous subcommand prefixes (AllowPrefixMatch)
+	syntax           flagSyntax  // GNU-style flag spellings (AllowEquals, ...)
+	responseFiles    bool        // Expand @file arguments (ResponseFiles)
+	expr             *exprSyntax // Operators of expression clause mode (ExpressionClauses)
+	clauseRules      ClauseRules // Clause count limits and validators
+
+	contextValidators []ContextValidatorFunc // Cross-flag checks (ValidateContext)
+
+	// Hooks around every handler in the tree (Before, After, Use)
+	before     []BeforeFunc
+	after      []AfterFunc
+	middleware []Middleware
+
+	shutdownTimeout time.Duration // Grace period after SIGINT/SIGTERM (ShutdownTimeout)
+	timeoutFlag     bool          // Add the root-global -timeout flag (TimeoutFlag)
+	promptMissing   bool          // Ask for missing required flags (PromptForMissing)
+	yesFlag         bool          // Add the root-global -yes flag (YesFlag)
+
+	// parentGlobals holds, on the temporary per-subcommand Command, the root
+	// globals given before the subcommand name
+	parentGlobals map[string]interface{}
 }
 
 // FlagSpec defines a flag with 0 or more arguments
@@ -40,25 +79,45 @@ type FlagSpec struct {
 
 	// Accumulation
 	IsSlice     bool          // Accumulate multiple values (for Accumulate() method)
+	IsCounter   bool          // Value is the number of occurrences (for Count() method)
+
+	// Optional argument (for OptionalValue() method)
+	OptionalArg bool          // The single argument may be omitted
+	BareValue   interface{}   // Value stored when it is
 
 	// Positional arguments
 	IsVariadic  bool          // Consumes all remaining positional args (must be last)
 
 	// Validation and defaults
 	Required    bool
+	RequiredIf  []Condition   // Required when another flag has a given value
+	RequiredInEachClause bool // Local flag must appear in every clause
+	MaxPerClause         int  // Most occurrences in one clause (0 = no limit)
 	Default     interface{}
 	Validator   ValidatorFunc
 
+	// Dependencies (for DependsOn() and ParseWith() methods)
+	DependsOn []string        // Flags whose values are needed to parse this one
+	ParseFunc DependentParser // Parses the argument given those values
+
 	// Time parsing (for ArgTime type)
 	TimeFormats      []string // Multiple formats to try in order (uses time.ParseInLocation)
 	TimeZone         string   // IANA timezone name or "Local" for formats without TZ info
 	TimeZoneFromFlag string   // Flag name to get timezone from (must be Global flag, e.g., "-timezone")
 
+	// Enumerations (for ArgEnum type)
+	EnumValues [][]string // Allowed values of each ArgEnum argument, by index
+
 	// Field completion (for data file field names)
 	FieldsFromFlag   string   // Flag name to get file path from (e.g., "-input") for field completion
 
+	// Environment fallback
+	EnvVar      string        // Environment variable consulted when the flag is absent from argv
+
 	// Display
 	Hidden      bool          // Hide from help/man (for internal flags)
+	Secret      bool          // Sensitive value: redacted from RawArgs, help and errors, not echoed when prompted for
+	SecretFile  bool          // Also readable from a file named by -NAME-file (implies Secret)
 
 	// demoted marks a flag as "background" for completion: it is offered
 	// only when the typed prefix specifically matches it, not on a broad
@@ -66,9 +125,14 @@ type FlagSpec struct {
 	// subcommand's flag set, so a subcommand's own options aren't drowned
 	// out by global meta-flags. Completion-only; ignored by help/man/exec.
 	demoted bool
+
+	// secretFor, on the -NAME-file flag added by SecretFile, is the Secret
+	// flag whose value the file holds
+	secretFor *FlagSpec
 }
 
-// ArgType represents the type of a flag argument
+// ArgType represents the type of a flag argument. The constants below are
+// built in; RegisterArgType adds more.
 type ArgType int
 
 const (
@@ -78,6 +142,12 @@ const (
 	ArgBool
 	ArgDuration // time.Duration parsed with time.ParseDuration
 	ArgTime     // time.Time parsed with time.ParseInLocation
+	ArgBytes    // int64 byte count such as "512MiB" or "1.5G"
+	ArgIP       // net.IP
+	ArgCIDR     // *net.IPNet parsed with net.ParseCIDR
+	ArgURL      // *url.URL; must be absolute
+	ArgRegexp   // *regexp.Regexp compiled with regexp.Compile
+	ArgEnum     // string restricted to the argument's FlagSpec.EnumValues
 )
 
 // Scope determines if flag is global or per-clause
@@ -88,11 +158,49 @@ const (
 	ScopeLocal                // Applies within each clause
 )
 
+// ValueSource identifies the layer that supplied a flag's value
+type ValueSource int
+
+const (
+	SourceUnset   ValueSource = iota // Flag has no value
+	SourceArgs                       // Given on the command line
+	SourceEnv                        // Read from the flag's environment variable
+	SourceConfig                     // Read from a configuration file
+	SourceDefault                    // Filled in from Default
+	SourcePrompt                     // Asked for interactively (PromptForMissing)
+)
+
+// String returns the layer name: "unset", "argv", "env", "config",
+// "default" or "prompt"
+func (s ValueSource) String() string {
+	switch s {
+	case SourceArgs:
+		return "argv"
+	case SourceEnv:
+		return "env"
+	case SourceConfig:
+		return "config"
+	case SourceDefault:
+		return "default"
+	case SourcePrompt:
+		return "prompt"
+	default:
+		return "unset"
+	}
+}
+
 // Clause represents a group of arguments separated by + or -
 type Clause struct {
 	Separator  string                    // "+" or "-" that started this clause (empty for first)
 	Flags      map[string]interface{}    // Parsed flag values for this clause
 	Positional []string                  // Unparsed positional arguments in this clause
+
+	sources map[string]ValueSource // Which layer supplied each entry in Flags
+}
+
+// Source reports which layer supplied a local flag's value in this clause
+func (c Clause) Source(name string) ValueSource {
+	return c.sources[name]
 }
 
 // Context is passed to handler with all parsed clauses
@@ -100,14 +208,19 @@ type Context struct {
 	Command        *Command
 	SubcommandPath []string                  // Path of nested subcommands (e.g., ["remote", "add"]). Empty for root.
 	Clauses        []Clause                  // All parsed clauses
+	ClauseTree     *ClauseNode               // How the clauses combine (ExpressionClauses mode only)
 	GlobalFlags    map[string]interface{}    // Flags marked as global (apply to all clauses)
 	RemainingArgs  []string                  // Arguments after -- (everything after -- is literal)
-	RawArgs        []string                  // Original arguments
-	deferredValues map[string]*deferredValue // Values that need re-parsing after all flags known
+	RawArgs        []string                  // Original arguments (before @file expansion)
+	deferredValues []*deferredValue          // Values that need re-parsing after all flags known
+	resolved       []*deferredValue          // Deferred values already parsed, for -explain
+	sources        map[string]ValueSource    // Which layer supplied each entry in GlobalFlags
+	trailing       *Clause                   // Clause open at the end of args, even if empty (ExpressionClauses)
 
 	// Optional fields for embedded callers (autocli-shell, SSH service consoles,
 	// tests). Zero values are equivalent to os.Stdin/os.Stdout/os.Stderr +
-	// context.Background() + nil State, which is what the bash-CLI path uses.
+	// context.Background() + nil State. The bash-CLI path (Execute) sets only
+	// ctx, cancelled on SIGINT/SIGTERM.
 	// Read via the Stdin/Stdout/Stderr/Ctx methods — they fall back to defaults
 	// so handlers don't need nil checks.
 	stdin  io.Reader
@@ -115,6 +228,10 @@ type Context struct {
 	stderr io.Writer
 	ctx    context.Context
 
+	// prompter asks the user questions (SetPrompter); nil when the session
+	// isn't interactive
+	prompter Prompter
+
 	// State is an arbitrary value supplied by the caller (typically a service
 	// holding live data) for handlers to type-assert. Untouched by the dispatch
 	// machinery — purely a passthrough.
@@ -169,12 +286,22 @@ func (c *Context) SetCtx(x context.Context) *Context {
 	return c
 }
 
+// SetPrompter supplies the Prompter used to ask the user for input, marking
+// the session as interactive. Embedded shells pass their line editor;
+// Execute falls back to the terminal on Stdin when it is one.
+func (c *Context) SetPrompter(p Prompter) *Context {
+	c.prompter = p
+	return c
+}
+
 // deferredValue tracks a value that needs re-parsing after all flags are available
 type deferredValue struct {
-	rawString   string
-	spec        *FlagSpec
-	isGlobal    bool
-	clauseIndex int // For local flags, which clause
+	rawString string
+	spec      *FlagSpec
+	target    map[string]interface{} // GlobalFlags or the clause's Flags
+	index     int                    // Position within an accumulated value, or -1
+	fromArgs  bool                   // Given on the command line, so the prefix handler sees it
+	plus      bool                   // Given as +flag
 }
 
 // Context helper methods for type-safe flag value extraction
@@ -229,6 +356,56 @@ func (ctx *Context) GetDuration(name string, defaultValue time.Duration) time.Du
 	return defaultValue
 }
 
+// GetBytes retrieves a byte-size flag value from GlobalFlags, returning defaultValue if not found or nil
+func (ctx *Context) GetBytes(name string, defaultValue int64) int64 {
+	if v, ok := ctx.GlobalFlags[name]; ok && v != nil {
+		if n, ok := v.(int64); ok {
+			return n
+		}
+	}
+	return defaultValue
+}
+
+// GetIP retrieves an IP address flag value from GlobalFlags, returning defaultValue if not found or nil
+func (ctx *Context) GetIP(name string, defaultValue net.IP) net.IP {
+	if v, ok := ctx.GlobalFlags[name]; ok && v != nil {
+		if ip, ok := v.(net.IP); ok {
+			return ip
+		}
+	}
+	return defaultValue
+}
+
+// GetCIDR retrieves a CIDR block flag value from GlobalFlags, returning defaultValue if not found or nil
+func (ctx *Context) GetCIDR(name string, defaultValue *net.IPNet) *net.IPNet {
+	if v, ok := ctx.GlobalFlags[name]; ok && v != nil {
+		if n, ok := v.(*net.IPNet); ok {
+			return n
+		}
+	}
+	return defaultValue
+}
+
+// GetURL retrieves a URL flag value from GlobalFlags, returning defaultValue if not found or nil
+func (ctx *Context) GetURL(name string, defaultValue *url.URL) *url.URL {
+	if v, ok := ctx.GlobalFlags[name]; ok && v != nil {
+		if u, ok := v.(*url.URL); ok {
+			return u
+		}
+	}
+	return defaultValue
+}
+
+// GetRegexp retrieves a regular expression flag value from GlobalFlags, returning defaultValue if not found or nil
+func (ctx *Context) GetRegexp(name string, defaultValue *regexp.Regexp) *regexp.Regexp {
+	if v, ok := ctx.GlobalFlags[name]; ok && v != nil {
+		if re, ok := v.(*regexp.Regexp); ok {
+			return re
+		}
+	}
+	return defaultValue
+}
+
 // RequireString retrieves a string flag value from GlobalFlags, returning an error if not found
 func (ctx *Context) RequireString(name string) (string, error) {
 	v, ok := ctx.GlobalFlags[name]
@@ -294,6 +471,46 @@ func (ctx *Context) RequireDuration(name string) (time.Duration, error) {
 	return d, nil
 }
 
+// Source reports which layer (argv, env, config or default) supplied a flag's
+// value. Global flags are looked up first; for a local flag the first clause
+// holding it answers (use Clause.Source for a specific clause).
+func (ctx *Context) Source(name string) ValueSource {
+	if src, ok := ctx.sources[name]; ok {
+		return src
+	}
+	for _, clause := range ctx.Clauses {
+		if src, ok := clause.sources[name]; ok {
+			return src
+		}
+	}
+	return SourceUnset
+}
+
+// markSources records src as the source of every value that doesn't have
+// one yet. Parse calls it after each layer so the first layer to set a value
+// is the one reported.
+func (ctx *Context) markSources(src ValueSource) {
+	if ctx.sources == nil {
+		ctx.sources = make(map[string]ValueSource)
+	}
+	for name := range ctx.GlobalFlags {
+		if _, ok := ctx.sources[name]; !ok {
+			ctx.sources[name] = src
+		}
+	}
+	for i := range ctx.Clauses {
+		clause := &ctx.Clauses[i]
+		if clause.sources == nil {
+			clause.sources = make(map[string]ValueSource)
+		}
+		for name := range clause.Flags {
+			if _, ok := clause.sources[name]; !ok {
+				clause.sources[name] = src
+			}
+		}
+	}
+}
+
 // IsSubcommand checks if the command is using a specific first-level subcommand
 func (ctx *Context) IsSubcommand(name string) bool {
 	return len(ctx.SubcommandPath) > 0 && ctx.SubcommandPath[0] == name
@@ -333,6 +550,27 @@ type PrefixHandler func(flagName string, hasPlus bool, value interface{}) interf
 // ValidatorFunc validates a parsed value
 type ValidatorFunc func(value interface{}) error
 
+// ContextValidatorFunc validates the parsed flags together, for rules that
+// involve more than one flag
+type ContextValidatorFunc func(ctx *Context) error
+
+// runContextValidators runs the ValidateContext hooks in order, wrapping
+// their errors as ValidationErrors
+func runContextValidators(validators []ContextValidatorFunc, ctx *Context) error {
+	for _, fn := range validators {
+		err := fn(ctx)
+		if err == nil {
+			continue
+		}
+		var verr ValidationError
+		if errors.As(err, &verr) {
+			return err
+		}
+		return ValidationError{Message: err.Error(), Err: err}
+	}
+	return nil
+}
+
 // Example represents a usage example for help and man pages
 type Example struct {
 	Command     string
@@ -341,21 +579,24 @@ type Example struct {
 
 // ParseError represents an error during argument parsing
 type ParseError struct {
-	Flag    string
-	Message string
+	Flag        string
+	Message     string
+	Suggestions []string // Similar flag names, for "unknown flag" errors
 }
 
 func (e ParseError) Error() string {
+	hint := didYouMean(e.Suggestions, func(s string) string { return s })
 	if e.Flag != "" {
-		return fmt.Sprintf("flag %s: %s", e.Flag, e.Message)
+		return fmt.Sprintf("flag %s: %s%s", e.Flag, e.Message, hint)
 	}
-	return e.Message
+	return e.Message + hint
 }
 
 // ValidationError represents a validation error
 type ValidationError struct {
 	Flag    string
 	Message string
+	Err     error // Underlying error, if any (from ValidateContext)
 }
 
 func (e ValidationError) Error() string {
@@ -365,6 +606,11 @@ func (e ValidationError) Error() string {
 	return fmt.Sprintf("validation failed: %s", e.Message)
 }
 
+// Unwrap returns the underlying error
+func (e ValidationError) Unwrap() error {
+	return e.Err
+}
+
 // ErrUnknownCommand is returned from ExecuteWith when args[0] looks
 // like a subcommand attempt (a non-flag token) but doesn't match any
 // registered subcommand, and the root command has no handler that
@@ -391,6 +637,33 @@ func (e ErrUnknownCommand) Error() string {
 	return fmt.Sprintf("unknown command: %q (try -help)", string(e))
 }
 
+// UnknownCommandError is the error ExecuteWith actually returns for an
+// unknown command. It carries "did you mean" candidates from the subcommands
+// visible at that point in the tree, and unwraps to ErrUnknownCommand so
+// existing errors.As checks keep working:
+//
+//	var unknown UnknownCommandError
+//	if errors.As(err, &unknown) && len(unknown.Suggestions) > 0 {
+//	    fmt.Fprintf(os.Stderr, "did you mean %q?\n", unknown.Suggestions[0])
+//	}
+type UnknownCommandError struct {
+	Name        string   // The unrecognised command, including any parent path ("remote ad")
+	Suggestions []string // Similar commands, full paths ("remote add")
+}
+
+func (e UnknownCommandError) Error() string {
+	if len(e.Suggestions) == 0 {
+		return ErrUnknownCommand(e.Name).Error()
+	}
+	quote := func(s string) string { return fmt.Sprintf("%q", s) }
+	return fmt.Sprintf("unknown command: %q%s", e.Name, didYouMean(e.Suggestions, quote))
+}
+
+// Unwrap returns the equivalent ErrUnknownCommand
+func (e UnknownCommandError) Unwrap() error {
+	return ErrUnknownCommand(e.Name)
+}
+
 // defaultPrefixHandler is used when no custom prefix handler is set
 // It simply returns the value unchanged, ignoring the prefix
 func defaultPrefixHandler(flagName string, hasPlus bool, value interface{}) interface{} {
@@ -402,14 +675,13 @@ func defaultSeparators() []string {
 	return []string{"+", "-"}
 }
 
-// isSeparator checks if a string is a clause separator
+// isSeparator checks if a string is a clause separator, or in expression
+// clause mode an operator or parenthesis
 func (cmd *Command) isSeparator(s string) bool {
-	for _, sep := range cmd.separators {
-		if s == sep {
-			return true
-		}
+	if cmd.isClauseSeparator(s) {
+		return true
 	}
-	return false
+	return cmd.expr != nil && cmd.expr.isToken(s)
 }
 
 // findFlagSpec finds a flag spec by any of its names
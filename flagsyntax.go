@@ -0,0 +1,238 @@
+package completionflags
+
+import (
+	"strconv"
+	"strings"
+)
+
+// flagSyntax holds the GNU-style spellings a Command accepts on top of the
+// native "-flag value" form. All are off by default so clause separators
+// and flags whose names contain "=" keep their meaning.
+type flagSyntax struct {
+	equals   bool // -flag=value, --flag=value and --flag (AllowEquals)
+	bundling bool // -vq for -v -q (AllowBundling)
+	negation bool // -no-verbose for Bool flag -verbose (AllowNegation)
+}
+
+// flagWord is a command-line word resolved against a flag set
+type flagWord struct {
+	name    string // Flag name as matched, with "+" normalised to "-"
+	plus    bool   // Written with a leading "+"
+	value   string // Text after "="
+	inline  bool   // Value given as -flag=value
+	negated bool   // -no-X form of Bool flag -X
+}
+
+// resolveFlagWord finds the flag word refers to, trying the native spelling
+// first and then each GNU-style form the command allows. -flag=value is
+// always accepted for an OptionalValue flag. find looks a name up in the
+// flag set in scope.
+func (cmd *Command) resolveFlagWord(word string, find func(string) *FlagSpec) (*FlagSpec, flagWord) {
+	w := flagWord{name: word}
+	if strings.HasPrefix(word, "+") {
+		w.plus = true
+		w.name = "-" + word[1:]
+	}
+	if spec := find(w.name); spec != nil {
+		return spec, w
+	}
+
+	if name, value, ok := strings.Cut(w.name, "="); ok && strings.TrimLeft(name, "-") != "" {
+		if cmd.syntax.equals {
+			w.name, w.value, w.inline = name, value, true
+		} else if spec := find(name); spec != nil && spec.OptionalArg {
+			// An OptionalValue flag's value is unambiguous after "="
+			w.name, w.value, w.inline = name, value, true
+			return spec, w
+		}
+	}
+	if spec, name := cmd.findFlagSpelling(w.name, find); spec != nil {
+		w.name = name
+		return spec, w
+	}
+
+	if cmd.syntax.negation {
+		for _, prefix := range []string{"-no-", "--no-"} {
+			base, ok := strings.CutPrefix(w.name, prefix)
+			if !ok {
+				continue
+			}
+			if spec, name := cmd.findFlagSpelling("-"+base, find); spec != nil && spec.ArgCount == 0 {
+				w.name, w.negated = name, true
+				return spec, w
+			}
+		}
+	}
+	return nil, w
+}
+
+// findFlagSpelling looks name up, also accepting "--name" for a flag
+// declared as "-name" when AllowEquals is on
+func (cmd *Command) findFlagSpelling(name string, find func(string) *FlagSpec) (*FlagSpec, string) {
+	if spec := find(name); spec != nil {
+		return spec, name
+	}
+	if cmd.syntax.equals && strings.HasPrefix(name, "--") {
+		if spec := find(name[1:]); spec != nil {
+			return spec, name[1:]
+		}
+	}
+	return nil, name
+}
+
+// splitBundle expands a word such as "-vqo" into the single-letter flags
+// -v, -q and -o when AllowBundling is on. Every flag but the last must be
+// a Bool; the last may take arguments from the following words. Returns
+// nil when word is not a bundle.
+func (cmd *Command) splitBundle(word string, find func(string) *FlagSpec) []*FlagSpec {
+	if !cmd.syntax.bundling || len(word) < 3 || word[0] != '-' || word[1] == '-' {
+		return nil
+	}
+	letters := bundleLetters(word)
+	if len(letters) < 2 {
+		return nil
+	}
+	specs := make([]*FlagSpec, 0, len(letters))
+	for i, letter := range letters {
+		spec := find(letter)
+		if spec == nil || (spec.ArgCount > 0 && i < len(letters)-1) {
+			return nil
+		}
+		specs = append(specs, spec)
+	}
+	return specs
+}
+
+// bundleLetters splits a bundled word such as "-vqo" into its flag names,
+// one per rune: "-v", "-q", "-o"
+func bundleLetters(word string) []string {
+	var letters []string
+	for _, r := range word[1:] {
+		letters = append(letters, "-"+string(r))
+	}
+	return letters
+}
+
+// boolWordValue returns the value a Bool flag word sets: true, false for
+// the -no-X form, or the parsed -flag=value
+func boolWordValue(word flagWord) (bool, error) {
+	if !word.inline {
+		return !word.negated, nil
+	}
+	b, err := strconv.ParseBool(word.value)
+	if err != nil {
+		return false, ParseError{
+			Flag:    word.name,
+			Message: "invalid boolean value " + strconv.Quote(word.value),
+		}
+	}
+	return b != word.negated, nil
+}
+
+// splitEqualsWords undoes bash's word splitting at "=" (COMP_WORDBREAKS) so
+// "-format", "=", "js" completes like "-format js". An "=" under the cursor
+// becomes an empty partial. An OptionalValue flag only takes its value after
+// "=", so its words are joined back into "-color=al"; split reports that the
+// value under the cursor was split off this way and completes without the
+// "-color=" prefix. Other flags are left alone unless AllowEquals is on.
+func (cmd *Command) splitEqualsWords(args []string, pos int) (out []string, newPos int, split bool) {
+	cursor := pos - 1
+	out = make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg != "=" || i == 0 || !cmd.takesEquals(args[i-1]) {
+			out = append(out, arg)
+			continue
+		}
+
+		if cmd.findOptionalValueFlag(args[i-1]) != nil {
+			last := len(out) - 1
+			out[last] += "="
+			removed := 1
+			if i != cursor && i+1 < len(args) {
+				out[last] += args[i+1]
+				removed = 2
+			}
+			if cursor >= i && cursor < i+removed {
+				pos, split = len(out), true
+			} else if cursor > i {
+				pos -= removed
+			}
+			i += removed - 1
+			continue
+		}
+
+		if i == cursor {
+			out = append(out, "")
+			continue
+		}
+		if i < cursor {
+			pos--
+		}
+	}
+	return out, pos, split
+}
+
+// takesEquals reports whether word is a flag that may be followed by "="
+func (cmd *Command) takesEquals(word string) bool {
+	if len(word) < 2 || (word[0] != '-' && word[0] != '+') {
+		return false
+	}
+	return cmd.syntax.equals || cmd.findOptionalValueFlag(word) != nil
+}
+
+// findOptionalValueFlag finds the OptionalValue flag named word among the
+// flags of cmd and of every subcommand below it
+func (cmd *Command) findOptionalValueFlag(word string) *FlagSpec {
+	name := "-" + strings.TrimLeft(word, "-+")
+	var find func(flags []*FlagSpec, subcommands map[string]*Subcommand) *FlagSpec
+	find = func(flags []*FlagSpec, subcommands map[string]*Subcommand) *FlagSpec {
+		for _, spec := range flags {
+			for _, n := range spec.Names {
+				if n == name && spec.OptionalArg {
+					return spec
+				}
+			}
+		}
+		for _, sub := range subcommands {
+			if spec := find(sub.Flags, sub.Subcommands); spec != nil {
+				return spec
+			}
+		}
+		return nil
+	}
+	return find(cmd.flags, cmd.subcommands)
+}
+
+// inlineValuePartial recognises a "-flag=partial" word under the cursor,
+// returning the flag and the text before and after the "="
+func (cmd *Command) inlineValuePartial(partial string) (*FlagSpec, string, string, bool) {
+	name, value, ok := strings.Cut(partial, "=")
+	if !ok {
+		return nil, "", "", false
+	}
+	spec, word := cmd.resolveFlagWord(partial, cmd.findFlagSpec)
+	if spec == nil || !word.inline || spec.ArgCount != 1 {
+		return nil, "", "", false
+	}
+	return spec, name + "=", value, true
+}
+
+// negationCompletions offers "-no-X" for the Bool flags in flags once the
+// partial has started spelling one
+func (cmd *Command) negationCompletions(flags []*FlagSpec, partial string) []string {
+	if !cmd.syntax.negation || !strings.HasPrefix(partial, "-n") {
+		return nil
+	}
+	var matches []string
+	for _, spec := range flags {
+		if spec.Hidden || spec.ArgCount != 0 || spec.isPositional() {
+			continue
+		}
+		name := "-no-" + strings.TrimLeft(spec.Names[0], "-")
+		if strings.HasPrefix(name, partial) {
+			matches = append(matches, name)
+		}
+	}
+	return matches
+}
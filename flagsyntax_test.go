@@ -0,0 +1,161 @@
+package completionflags
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFlagSyntax_Parse(t *testing.T) {
+	var ctx *Context
+	cmd := NewCommand("tool").
+		AllowEquals().AllowBundling().AllowNegation().
+		Flag("-format").Enum("json", "csv").Global().Done().
+		Flag("-verbose", "-v").Bool().Global().Default(true).Done().
+		Flag("-q").Bool().Global().Done().
+		Flag("-o").String().Options("out.txt").Global().Done().
+		Flag("-range").Args(2).Global().Done().
+		Handler(func(c *Context) error {
+			ctx = c
+			return nil
+		}).
+		Build()
+
+	tests := []struct {
+		args []string
+		want map[string]interface{}
+	}{
+		{[]string{"-format=csv"}, map[string]interface{}{"-format": "csv"}},
+		{[]string{"--format=csv"}, map[string]interface{}{"-format": "csv"}},
+		{[]string{"--format", "csv"}, map[string]interface{}{"-format": "csv"}},
+		{[]string{"-no-verbose"}, map[string]interface{}{"-verbose": false}},
+		{[]string{"--verbose=false"}, map[string]interface{}{"-verbose": false}},
+		{[]string{"-vq"}, map[string]interface{}{"-verbose": true, "-q": true}},
+		{[]string{"-qo", "out.txt"}, map[string]interface{}{"-q": true, "-o": "out.txt"}},
+	}
+	for _, tt := range tests {
+		ctx = nil
+		if err := cmd.Execute(tt.args); err != nil {
+			t.Errorf("%v: %v", tt.args, err)
+			continue
+		}
+		for name, want := range tt.want {
+			if got := ctx.GlobalFlags[name]; !reflect.DeepEqual(got, want) {
+				t.Errorf("%v: %s = %v, want %v", tt.args, name, got, want)
+			}
+		}
+	}
+
+	for _, bad := range [][]string{
+		{"-format=yaml"},
+		{"-range=1"},
+		{"-verbose=maybe"},
+		{"-vx"},
+	} {
+		if err := cmd.Execute(bad); err == nil {
+			t.Errorf("%v: expected error", bad)
+		}
+	}
+}
+
+func TestFlagSyntax_OptIn(t *testing.T) {
+	cmd := NewCommand("tool").
+		Flag("-format").String().Done().
+		Flag("-v").Bool().Done().
+		Flag("-q").Bool().Done().
+		Handler(func(ctx *Context) error { return nil }).
+		Build()
+
+	for _, args := range [][]string{{"-format=csv"}, {"-vq"}, {"-no-v"}} {
+		if err := cmd.Execute(args); err == nil || !strings.Contains(err.Error(), "unknown flag") {
+			t.Errorf("%v: expected unknown flag without opting in, got %v", args, err)
+		}
+	}
+}
+
+func TestFlagSyntax_Subcommand(t *testing.T) {
+	var ctx *Context
+	cmd := NewCommand("tool").
+		AllowEquals().AllowBundling().
+		Flag("-v").Bool().Global().Done().
+		Flag("-profile").String().Global().Done().
+		Subcommand("run").
+		Flag("-n").Int().Done().
+		Handler(func(c *Context) error {
+			ctx = c
+			return nil
+		}).
+		Done().
+		Build()
+
+	if err := cmd.Execute([]string{"-profile=prod", "run", "-n=3", "-v"}); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if ctx.GlobalFlags["-profile"] != "prod" || ctx.Clauses[0].Flags["-n"] != 3 {
+		t.Errorf("unexpected values: %v %v", ctx.GlobalFlags, ctx.Clauses[0].Flags)
+	}
+}
+
+func TestFlagSyntax_BundleNonASCII(t *testing.T) {
+	var ctx *Context
+	cmd := NewCommand("tool").
+		AllowBundling().
+		Flag("-µ").Bool().Global().Done().
+		Flag("-n").Int().Global().Done().
+		Subcommand("run").
+		Flag("-é").Bool().Done().
+		Flag("-k").String().Done().
+		Handler(func(c *Context) error {
+			ctx = c
+			return nil
+		}).
+		Done().
+		Build()
+
+	if err := cmd.Execute([]string{"-µn", "3", "run", "-ék", "x"}); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if ctx.GlobalFlags["-µ"] != true || ctx.GlobalFlags["-n"] != 3 {
+		t.Errorf("unexpected globals: %v", ctx.GlobalFlags)
+	}
+	if ctx.Clauses[0].Flags["-é"] != true || ctx.Clauses[0].Flags["-k"] != "x" {
+		t.Errorf("unexpected flags: %v", ctx.Clauses[0].Flags)
+	}
+}
+
+func TestFlagSyntax_Completion(t *testing.T) {
+	cmd := NewCommand("tool").
+		AllowEquals().AllowBundling().AllowNegation().
+		Flag("-format").Enum("json", "csv").Global().Done().
+		Flag("-verbose", "-v").Bool().Global().Done().
+		Flag("-o").String().Options("out.txt").Global().Done().
+		Handler(func(ctx *Context) error { return nil }).
+		Build()
+
+	// Word under the cursor kept whole (embedded shells)
+	matches, _ := cmd.Complete([]string{"-format=j"}, 1)
+	if !reflect.DeepEqual(matches, []string{"-format=json"}) {
+		t.Errorf("joined: got %v", matches)
+	}
+
+	// Bash splits at "=": COMP_WORDS = [tool -format = j]
+	matches, _ = cmd.Complete([]string{"-format", "=", "j"}, 3)
+	if !reflect.DeepEqual(matches, []string{"json"}) {
+		t.Errorf("bash split: got %v", matches)
+	}
+	matches, _ = cmd.Complete([]string{"-format", "="}, 2)
+	if !reflect.DeepEqual(matches, []string{"json", "csv"}) {
+		t.Errorf("bash split, empty value: got %v", matches)
+	}
+
+	// A bundle's arguments belong to its last flag
+	matches, _ = cmd.Complete([]string{"-vo", ""}, 2)
+	if !reflect.DeepEqual(matches, []string{"out.txt"}) {
+		t.Errorf("bundle: got %v", matches)
+	}
+
+	matches, _ = cmd.Complete([]string{"-no-v"}, 1)
+	if !reflect.DeepEqual(matches, []string{"-no-verbose"}) {
+		t.Errorf("negation: got %v", matches)
+	}
+}
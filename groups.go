@@ -0,0 +1,273 @@
+package completionflags
+
+import (
+	"fmt"
+	"strings"
+)
+
+// GroupKind identifies the rule a FlagGroup enforces
+type GroupKind int
+
+const (
+	GroupMutuallyExclusive GroupKind = iota // At most one of the flags may be given
+	GroupRequiredTogether                   // Either all of the flags are given or none
+	GroupOneRequired                        // At least one of the flags must be given
+)
+
+// FlagGroup is a constraint across several flags, declared with
+// MutuallyExclusive, RequiredTogether or OneRequired
+type FlagGroup struct {
+	Kind  GroupKind
+	Flags []string // Primary flag names
+}
+
+// String describes the rule for help text and man pages
+func (g FlagGroup) String() string {
+	names := strings.Join(g.Flags, ", ")
+	switch g.Kind {
+	case GroupMutuallyExclusive:
+		return "At most one of: " + names
+	case GroupRequiredTogether:
+		return "All or none of: " + names
+	default:
+		return "At least one of: " + names
+	}
+}
+
+// Condition makes a flag required when another flag has a given value
+// (see FlagBuilder.RequiredIf)
+type Condition struct {
+	Flag  string
+	Value interface{}
+}
+
+// String describes the condition, e.g. "-mode is remote"
+func (c Condition) String() string {
+	return fmt.Sprintf("%s is %v", c.Flag, c.Value)
+}
+
+// matches reports whether a parsed value satisfies the condition. Values are
+// compared by their printed form so RequiredIf("-port", 22) matches an Int()
+// flag; any element of an accumulated value may match.
+func (c Condition) matches(value interface{}, exists bool) bool {
+	if !exists {
+		return false
+	}
+	if values, ok := value.([]interface{}); ok {
+		for _, v := range values {
+			if fmt.Sprint(v) == fmt.Sprint(c.Value) {
+				return true
+			}
+		}
+		return false
+	}
+	return fmt.Sprint(value) == fmt.Sprint(c.Value)
+}
+
+// check applies the group's rule, using given to test each flag. It returns
+// the failure naming the involved flags, if any.
+func (g FlagGroup) check(given func(name string) bool) (ValidationError, bool) {
+	var present, missing []string
+	for _, name := range g.Flags {
+		if given(name) {
+			present = append(present, name)
+		} else {
+			missing = append(missing, name)
+		}
+	}
+
+	all := strings.Join(g.Flags, ", ")
+	switch g.Kind {
+	case GroupMutuallyExclusive:
+		if len(present) > 1 {
+			return ValidationError{
+				Flag:    strings.Join(present, ", "),
+				Message: fmt.Sprintf("flags are mutually exclusive (at most one of %s)", all),
+			}, true
+		}
+	case GroupRequiredTogether:
+		if len(present) > 0 && len(missing) > 0 {
+			return ValidationError{
+				Flag:    strings.Join(missing, ", "),
+				Message: fmt.Sprintf("required together with %s (all or none of %s)", strings.Join(present, ", "), all),
+			}, true
+		}
+	case GroupOneRequired:
+		if len(present) == 0 {
+			return ValidationError{
+				Flag:    all,
+				Message: "at least one of these flags is required",
+			}, true
+		}
+	}
+	return ValidationError{}, false
+}
+
+// validateGroups enforces the command's flag groups. A group naming a
+// per-clause flag is checked in every clause (global flags count in each
+// clause); otherwise it is checked once against the global flags. Values
+// filled in from Default don't count as given.
+func (cmd *Command) validateGroups(ctx *Context) error {
+	globalGiven := func(name string) bool {
+		return isGiven(ctx.GlobalFlags, ctx.sources, name)
+	}
+
+	for _, group := range cmd.groups {
+		if !cmd.hasLocalFlag(group.Flags) {
+			if err, failed := group.check(globalGiven); failed {
+				return err
+			}
+			continue
+		}
+
+		for i := range ctx.Clauses {
+			clause := &ctx.Clauses[i]
+			err, failed := group.check(func(name string) bool {
+				return isGiven(clause.Flags, clause.sources, name) || globalGiven(name)
+			})
+			if failed {
+				err.Flag = fmt.Sprintf("%s (clause %d)", err.Flag, i)
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// validateRequiredIf enforces spec's RequiredIf conditions. A per-clause flag
+// is required in each clause where the condition holds (the condition flag
+// is looked up in the clause, then globally); a global flag is required when
+// the condition holds globally or in any clause.
+func (cmd *Command) validateRequiredIf(ctx *Context, spec *FlagSpec) error {
+	name := spec.Names[0]
+	for _, cond := range spec.RequiredIf {
+		if spec.Scope == ScopeGlobal {
+			if _, exists := ctx.GlobalFlags[name]; exists {
+				continue
+			}
+			value, exists := ctx.GlobalFlags[cond.Flag]
+			met := cond.matches(value, exists)
+			for _, clause := range ctx.Clauses {
+				value, exists := clause.Flags[cond.Flag]
+				met = met || cond.matches(value, exists)
+			}
+			if met {
+				return ValidationError{
+					Flag:    name,
+					Message: fmt.Sprintf("required when %s", cond),
+				}
+			}
+			continue
+		}
+
+		for i, clause := range ctx.Clauses {
+			if _, exists := clause.Flags[name]; exists {
+				continue
+			}
+			value, exists := clause.Flags[cond.Flag]
+			if !exists {
+				value, exists = ctx.GlobalFlags[cond.Flag]
+			}
+			if cond.matches(value, exists) {
+				return ValidationError{
+					Flag:    fmt.Sprintf("%s (clause %d)", name, i),
+					Message: fmt.Sprintf("required when %s", cond),
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// isGiven reports whether a flag has a value that didn't come from Default
+func isGiven(flags map[string]interface{}, sources map[string]ValueSource, name string) bool {
+	_, exists := flags[name]
+	return exists && sources[name] != SourceDefault
+}
+
+// hasLocalFlag reports whether any of the named flags is per-clause
+func (cmd *Command) hasLocalFlag(names []string) bool {
+	for _, name := range names {
+		if spec := cmd.findFlagSpec(name); spec != nil && spec.Scope == ScopeLocal {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveGroupFlags checks that every flag named by groups and RequiredIf
+// conditions is defined in flags (or inherited), and rewrites aliases to
+// primary names since parsed values are stored under those.
+func resolveGroupFlags(groups []FlagGroup, flags []*FlagSpec, inherited []*FlagSpec) error {
+	primary := make(map[string]string)
+	for _, spec := range append(append([]*FlagSpec{}, inherited...), flags...) {
+		for _, name := range spec.Names {
+			primary[name] = spec.Names[0]
+		}
+	}
+
+	for _, group := range groups {
+		if len(group.Flags) < 2 {
+			return fmt.Errorf("flag group %q needs at least two flags", group)
+		}
+		for i, name := range group.Flags {
+			p, ok := primary[name]
+			if !ok {
+				return fmt.Errorf("flag group %q: unknown flag %s", group, name)
+			}
+			group.Flags[i] = p
+		}
+	}
+
+	for _, spec := range flags {
+		for i, cond := range spec.RequiredIf {
+			p, ok := primary[cond.Flag]
+			if !ok {
+				return fmt.Errorf("flag %s: RequiredIf references unknown flag %s", spec.Names[0], cond.Flag)
+			}
+			spec.RequiredIf[i].Flag = p
+		}
+	}
+	return nil
+}
+
+// globalGroups returns the root groups that only involve global flags; they
+// also apply when a subcommand runs
+func (cmd *Command) globalGroups() []FlagGroup {
+	var groups []FlagGroup
+	for _, group := range cmd.groups {
+		if !cmd.hasLocalFlag(group.Flags) {
+			groups = append(groups, group)
+		}
+	}
+	return groups
+}
+
+// formatGroups renders the CONSTRAINTS section of help text
+func formatGroups(groups []FlagGroup) string {
+	if len(groups) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString("CONSTRAINTS:\n")
+	for _, group := range groups {
+		sb.WriteString(fmt.Sprintf("    %s\n", group))
+	}
+	sb.WriteString("\n")
+	return sb.String()
+}
+
+// formatManGroups renders the CONSTRAINTS section of a man page
+func formatManGroups(groups []FlagGroup) string {
+	if len(groups) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString(".SH CONSTRAINTS\n")
+	for _, group := range groups {
+		sb.WriteString(".PP\n")
+		sb.WriteString(escapeGroff(group.String()))
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
@@ -0,0 +1,136 @@
+package completionflags
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGroups_Global(t *testing.T) {
+	cmd := NewCommand("test").
+		Flag("-json").Bool().Global().Done().
+		Flag("-csv").Bool().Global().Done().
+		Flag("-table").Bool().Global().Done().
+		Flag("-user").String().Global().Done().
+		Flag("-password").String().Global().Done().
+		Flag("-file").String().Global().Done().
+		Flag("-url").String().Global().Default("https://example.com").Done().
+		MutuallyExclusive("-json", "-csv", "-table").
+		RequiredTogether("-user", "-password").
+		OneRequired("-file", "-url").
+		Handler(func(ctx *Context) error { return nil }).
+		Build()
+
+	tests := []struct {
+		args    []string
+		wantErr string // substring; empty means success
+	}{
+		{[]string{"-file", "a", "-json"}, ""},
+		{[]string{"-file", "a", "-json", "-table"}, "-json, -table"},
+		{[]string{"-file", "a", "-user", "bob"}, "-password: required together with -user"},
+		{[]string{"-file", "a", "-user", "bob", "-password", "x"}, ""},
+		// -url's Default doesn't count as given
+		{[]string{}, "-file, -url: at least one"},
+		{[]string{"-url", "https://other"}, ""},
+	}
+
+	for _, tt := range tests {
+		err := cmd.Execute(tt.args)
+		if tt.wantErr == "" {
+			if err != nil {
+				t.Errorf("%v: unexpected error: %v", tt.args, err)
+			}
+			continue
+		}
+		if _, ok := err.(ValidationError); !ok || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%v: expected ValidationError containing %q, got %T: %v", tt.args, tt.wantErr, err, err)
+		}
+	}
+}
+
+func TestGroups_PerClause(t *testing.T) {
+	cmd := NewCommand("test").
+		Separators("+").
+		Flag("-eq").String().Local().Done().
+		Flag("-ne").String().Local().Done().
+		MutuallyExclusive("-eq", "-ne").
+		Handler(func(ctx *Context) error { return nil }).
+		Build()
+
+	if err := cmd.Execute([]string{"-eq", "a", "+", "-ne", "b"}); err != nil {
+		t.Fatalf("one flag per clause should pass: %v", err)
+	}
+	err := cmd.Execute([]string{"-eq", "a", "+", "-eq", "b", "-ne", "c"})
+	if err == nil || !strings.Contains(err.Error(), "(clause 1)") {
+		t.Errorf("expected clause 1 violation, got %v", err)
+	}
+}
+
+func TestGroups_RequiredIf(t *testing.T) {
+	cmd := NewCommand("app").
+		Subcommand("deploy").
+		Flag("-mode").String().Global().Default("local").Done().
+		Flag("-host").String().Global().RequiredIf("-mode", "remote").Done().
+		Handler(func(ctx *Context) error { return nil }).
+		Done().
+		Build()
+
+	if err := cmd.Execute([]string{"deploy"}); err != nil {
+		t.Errorf("local mode should not need -host: %v", err)
+	}
+	err := cmd.Execute([]string{"deploy", "-mode", "remote"})
+	if verr, ok := err.(ValidationError); !ok || verr.Flag != "-host" || !strings.Contains(verr.Message, "-mode is remote") {
+		t.Errorf("expected -host ValidationError, got %T: %v", err, err)
+	}
+	if err := cmd.Execute([]string{"deploy", "-mode", "remote", "-host", "h1"}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestGroups_SubcommandInheritsRootGlobalGroups(t *testing.T) {
+	cmd := NewCommand("app").
+		Flag("-json").Bool().Global().Done().
+		Flag("-csv").Bool().Global().Done().
+		MutuallyExclusive("-json", "-csv").
+		Subcommand("list").
+		Handler(func(ctx *Context) error { return nil }).
+		Done().
+		Build()
+
+	if err := cmd.Execute([]string{"-json", "list", "-csv"}); err == nil {
+		t.Error("expected root group to apply to subcommand")
+	}
+}
+
+func TestGroups_ShownInHelp(t *testing.T) {
+	cmd := NewCommand("test").
+		Flag("-mode").String().Global().Done().
+		Flag("-host", "-H").String().Global().RequiredIf("-mode", "remote").Done().
+		Flag("-json").Bool().Global().Done().
+		Flag("-csv").Bool().Global().Done().
+		MutuallyExclusive("-json", "-csv").
+		Handler(func(ctx *Context) error { return nil }).
+		Build()
+
+	help := cmd.GenerateHelp()
+	for _, want := range []string{"CONSTRAINTS:", "At most one of: -json, -csv", "Required when: -mode is remote"} {
+		if !strings.Contains(help, want) {
+			t.Errorf("help missing %q:\n%s", want, help)
+		}
+	}
+	if man := cmd.GenerateManPage(); !strings.Contains(man, ".SH CONSTRAINTS") {
+		t.Errorf("man page missing constraints:\n%s", man)
+	}
+}
+
+func TestGroups_UnknownFlagPanics(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Error("expected panic for unknown flag in group")
+		}
+	}()
+	NewCommand("test").
+		Flag("-json").Bool().Global().Done().
+		MutuallyExclusive("-json", "-xml").
+		Handler(func(ctx *Context) error { return nil }).
+		Build()
+}
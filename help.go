@@ -93,6 +93,9 @@ func (cmd *Command) generateHelp(embedded bool) string {
 		}
 	}
 
+	// Constraints across flags
+	sb.WriteString(formatGroups(cmd.groups))
+
 	// Check if there are any local-scoped flags
 	hasLocalFlags := false
 	for _, spec := range namedFlags {
@@ -168,6 +171,9 @@ func (cmd *Command) generateHelpWithSubcommands(embedded bool) string {
 		}
 	}
 
+	// Constraints across global flags
+	sb.WriteString(formatGroups(cmd.globalGroups()))
+
 	// Examples
 	if len(cmd.examples) > 0 {
 		sb.WriteString("EXAMPLES:\n")
@@ -216,21 +222,7 @@ func (cmd *Command) formatPositional(spec *FlagSpec) string {
 
 	// Type information
 	if len(spec.ArgTypes) > 0 && spec.ArgTypes[0] != ArgString {
-		var typeName string
-		switch spec.ArgTypes[0] {
-		case ArgInt:
-			typeName = "integer"
-		case ArgFloat:
-			typeName = "float"
-		case ArgBool:
-			typeName = "boolean"
-		case ArgDuration:
-			typeName = "duration"
-		case ArgTime:
-			typeName = "time"
-		default:
-			typeName = "string"
-		}
+		typeName := argLabel(spec, 0)
 		sb.WriteString(fmt.Sprintf("        Type: %s\n", typeName))
 	}
 
@@ -242,10 +234,15 @@ func (cmd *Command) formatPositional(spec *FlagSpec) string {
 	}
 
 	// Default value
-	if spec.Default != nil {
+	if spec.Default != nil && !spec.Secret {
 		sb.WriteString(fmt.Sprintf("        Default: %v\n", spec.Default))
 	}
 
+	// Environment fallback
+	if spec.EnvVar != "" {
+		sb.WriteString(fmt.Sprintf("        Environment: $%s\n", spec.EnvVar))
+	}
+
 	// Required
 	if spec.Required {
 		sb.WriteString("        Required\n")
@@ -263,7 +260,9 @@ func (cmd *Command) formatFlag(spec *FlagSpec) string {
 	sb.WriteString(strings.Join(spec.Names, ", "))
 
 	// Arguments
-	if spec.ArgCount > 0 {
+	if spec.OptionalArg {
+		sb.WriteString(optionalArgLabel(spec))
+	} else if spec.ArgCount > 0 {
 		for i := 0; i < spec.ArgCount; i++ {
 			sb.WriteString(" ")
 			if i < len(spec.ArgNames) {
@@ -289,23 +288,62 @@ func (cmd *Command) formatFlag(spec *FlagSpec) string {
 	}
 
 	// Default value
-	if spec.Default != nil {
+	if spec.Default != nil && !spec.Secret {
 		sb.WriteString(fmt.Sprintf("        Default: %v\n", spec.Default))
 	}
 
+	// Environment fallback
+	if spec.EnvVar != "" {
+		sb.WriteString(fmt.Sprintf("        Environment: $%s\n", spec.EnvVar))
+	}
+
 	// Required
 	if spec.Required {
 		sb.WriteString("        Required: yes\n")
 	}
+	for _, cond := range spec.RequiredIf {
+		sb.WriteString(fmt.Sprintf("        Required when: %s\n", cond))
+	}
 
 	// Multi-value
 	if spec.IsSlice {
 		sb.WriteString("        Can be specified multiple times\n")
 	}
+	for _, note := range formNotes(spec) {
+		sb.WriteString("        " + note + "\n")
+	}
 
 	return sb.String()
 }
 
+// optionalArgLabel renders the argument of an OptionalValue flag as "[=WHEN]"
+func optionalArgLabel(spec *FlagSpec) string {
+	name := "ARG0"
+	if len(spec.ArgNames) > 0 && spec.ArgNames[0] != "" {
+		name = spec.ArgNames[0]
+	}
+	return "[=" + name + "]"
+}
+
+// formNotes describes how a flag may be written: Count and OptionalValue
+// forms, and per-clause limits
+func formNotes(spec *FlagSpec) []string {
+	var notes []string
+	if spec.RequiredInEachClause {
+		notes = append(notes, "Required in every clause")
+	}
+	if spec.MaxPerClause > 0 {
+		notes = append(notes, fmt.Sprintf("At most %d per clause", spec.MaxPerClause))
+	}
+	if spec.IsCounter {
+		notes = append(notes, "Repeat to increase the count")
+	}
+	if spec.OptionalArg {
+		notes = append(notes, fmt.Sprintf("Value optional; without one: %v", spec.BareValue))
+	}
+	return notes
+}
+
 // formatSubcommands recursively formats subcommands with proper indentation for nested hierarchies
 func (cmd *Command) formatSubcommands(sb *strings.Builder, subcommands map[string]*Subcommand, depth int) {
 	// Calculate indentation based on depth
@@ -333,10 +371,10 @@ func (cmd *Command) formatSubcommands(sb *strings.Builder, subcommands map[strin
 		prefix := indent + "    "
 		if depth > 0 {
 			// For nested subcommands, add a visual indicator
-			sb.WriteString(fmt.Sprintf("%s%-15s %s\n", prefix, name, subcmd.Description))
+			sb.WriteString(fmt.Sprintf("%s%-15s %s\n", prefix, subcmd.label(), subcmd.Description))
 		} else {
 			// Top-level subcommands
-			sb.WriteString(fmt.Sprintf("%s%-15s %s\n", prefix, name, subcmd.Description))
+			sb.WriteString(fmt.Sprintf("%s%-15s %s\n", prefix, subcmd.label(), subcmd.Description))
 		}
 
 		// Recursively format nested subcommands
@@ -23,6 +23,8 @@ import (
 // flag, HelpAt returns flag-focused help; otherwise it falls back to the
 // resolved command's full help. Side-effect free; safe to call concurrently.
 func (cmd *Command) HelpAt(args []string, pos int) (string, error) {
+	args, pos, _ = cmd.splitEqualsWords(args, pos)
+
 	// No subcommands: analyze directly against this command.
 	if len(cmd.subcommands) == 0 {
 		ctx := cmd.analyzeCompletionContext(args, pos)
@@ -50,11 +52,11 @@ func (cmd *Command) HelpAt(args []string, pos int) (string, error) {
 	var leafSubcmd *Subcommand
 	argIndex := 0
 	for argIndex < len(remaining) && argIndex < remainingPos {
-		subcmd := currentSubcommands[remaining[argIndex]]
+		subcmd, name := cmd.resolveSubcommand(currentSubcommands, remaining[argIndex])
 		if subcmd == nil {
 			break // not a confirmed subcommand at this level
 		}
-		path = append(path, remaining[argIndex])
+		path = append(path, name)
 		leafSubcmd = subcmd
 		argIndex++
 		if subcmd.Subcommands != nil {
@@ -75,6 +77,8 @@ func (cmd *Command) HelpAt(args []string, pos int) (string, error) {
 		name:       leafSubcmd.Name,
 		flags:      append(cmd.rootGlobalFlags(), leafSubcmd.Flags...),
 		separators: leafSubcmd.Separators,
+		syntax:     cmd.syntax,
+		expr:       cmd.expr,
 	}
 	subArgs := remaining[argIndex:]
 	subPos := remainingPos - argIndex + 1
@@ -100,7 +104,10 @@ func (cmd *Command) flagHelpAt(ctx CompletionContext) (string, bool) {
 		if spec := cmd.findFlagByName(ctx.Partial); spec != nil {
 			return cmd.renderFlagHelp(spec, -1), true
 		}
-		return "", false
+		return cmd.exprHelpAt(ctx.Partial)
+	}
+	if ctx.Partial == exprOpen || ctx.Partial == exprClose {
+		return cmd.exprHelpAt(ctx.Partial)
 	}
 	// Case 2: cursor is on an argument of a flag (FlagName set by analyze).
 	if ctx.FlagName != "" {
@@ -135,12 +142,16 @@ func (cmd *Command) renderFlagHelp(spec *FlagSpec, currentArg int) string {
 
 	// Signature line: "-sum, -s FIELD RESULT"
 	sb.WriteString(strings.Join(spec.Names, ", "))
-	for i := 0; i < spec.ArgCount; i++ {
-		sb.WriteString(" ")
-		if i < len(spec.ArgNames) && spec.ArgNames[i] != "" {
-			sb.WriteString(spec.ArgNames[i])
-		} else {
-			sb.WriteString(fmt.Sprintf("ARG%d", i))
+	if spec.OptionalArg {
+		sb.WriteString(optionalArgLabel(spec))
+	} else {
+		for i := 0; i < spec.ArgCount; i++ {
+			sb.WriteString(" ")
+			if i < len(spec.ArgNames) && spec.ArgNames[i] != "" {
+				sb.WriteString(spec.ArgNames[i])
+			} else {
+				sb.WriteString(fmt.Sprintf("ARG%d", i))
+			}
 		}
 	}
 	sb.WriteString("\n")
@@ -159,11 +170,7 @@ func (cmd *Command) renderFlagHelp(spec *FlagSpec, currentArg int) string {
 		if i == currentArg {
 			marker = "→ "
 		}
-		typeName := "string"
-		if i < len(spec.ArgTypes) {
-			typeName = argTypeName(spec.ArgTypes[i])
-		}
-		sb.WriteString(fmt.Sprintf("    %s%s (%s)\n", marker, name, typeName))
+		sb.WriteString(fmt.Sprintf("    %s%s (%s)\n", marker, name, argLabel(spec, i)))
 	}
 
 	// Scope / required / multi-value, mirroring formatFlag's vocabulary.
@@ -175,35 +182,25 @@ func (cmd *Command) renderFlagHelp(spec *FlagSpec, currentArg int) string {
 	if spec.Required {
 		sb.WriteString("    Required: yes\n")
 	}
+	for _, cond := range spec.RequiredIf {
+		sb.WriteString(fmt.Sprintf("    Required when: %s\n", cond))
+	}
 	if spec.IsSlice {
 		sb.WriteString("    Can be specified multiple times\n")
 	}
-	if spec.Default != nil {
+	for _, note := range formNotes(spec) {
+		sb.WriteString("    " + note + "\n")
+	}
+	if spec.Default != nil && !spec.Secret {
 		sb.WriteString(fmt.Sprintf("    Default: %v\n", spec.Default))
 	}
+	if spec.EnvVar != "" {
+		sb.WriteString(fmt.Sprintf("    Environment: $%s\n", spec.EnvVar))
+	}
 
 	return sb.String()
 }
 
-// argTypeName renders an ArgType as a human label, matching the vocabulary
-// used by formatPositional / formatPositionalForSubcommand.
-func argTypeName(t ArgType) string {
-	switch t {
-	case ArgInt:
-		return "integer"
-	case ArgFloat:
-		return "float"
-	case ArgBool:
-		return "boolean"
-	case ArgDuration:
-		return "duration"
-	case ArgTime:
-		return "time"
-	default:
-		return "string"
-	}
-}
-
 // handleHelpAtTo implements the bash `-help-at` protocol: args is
 // [position, word1, word2, ...] (same shape as -complete). It writes the
 // rendered help for the cursor to w. The io.Writer-aware form lets embedded
@@ -0,0 +1,60 @@
+package completionflags
+
+// BeforeFunc runs before a handler, once flags are parsed and validated.
+// An error stops dispatch: neither the handler nor deeper hooks run.
+type BeforeFunc func(ctx *Context) error
+
+// AfterFunc runs after a handler with the error it returned and returns the
+// error to report, so it can wrap, replace or clear it
+type AfterFunc func(ctx *Context, err error) error
+
+// Middleware wraps a handler, for concerns such as logging, timing, auth
+// checks and panic recovery:
+//
+//	func timing(next cf.ClauseHandlerFunc) cf.ClauseHandlerFunc {
+//	    return func(ctx *cf.Context) error {
+//	        start := time.Now()
+//	        err := next(ctx)
+//	        fmt.Fprintf(ctx.Stderr(), "took %v\n", time.Since(start))
+//	        return err
+//	    }
+//	}
+type Middleware func(next ClauseHandlerFunc) ClauseHandlerFunc
+
+// wrapHandler wraps next in one level's hooks: the middleware, outermost
+// first, around the Before hooks, next and the After hooks. After hooks run
+// in the order added, each given the error returned by the one before; they
+// don't run if one of this level's Before hooks fails.
+func wrapHandler(next ClauseHandlerFunc, before []BeforeFunc, after []AfterFunc, middleware []Middleware) ClauseHandlerFunc {
+	if len(before) == 0 && len(after) == 0 && len(middleware) == 0 {
+		return next
+	}
+	h := func(ctx *Context) error {
+		for _, fn := range before {
+			if err := fn(ctx); err != nil {
+				return err
+			}
+		}
+		err := next(ctx)
+		for _, fn := range after {
+			err = fn(ctx, err)
+		}
+		return err
+	}
+	for i := len(middleware) - 1; i >= 0; i-- {
+		h = middleware[i](h)
+	}
+	return h
+}
+
+// subcommandHandler returns the handler of the last subcommand in chain
+// wrapped in the hooks of every subcommand on the path and of the root, so
+// root hooks wrap subcommand hooks wrap the handler
+func (cmd *Command) subcommandHandler(chain []*Subcommand) ClauseHandlerFunc {
+	h := chain[len(chain)-1].Handler
+	for i := len(chain) - 1; i >= 0; i-- {
+		sub := chain[i]
+		h = wrapHandler(h, sub.Before, sub.After, sub.Middleware)
+	}
+	return wrapHandler(h, cmd.before, cmd.after, cmd.middleware)
+}
@@ -0,0 +1,172 @@
+package completionflags
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestHooks_Order(t *testing.T) {
+	var log []string
+	record := func(s string) { log = append(log, s) }
+	before := func(name string) BeforeFunc {
+		return func(ctx *Context) error {
+			record("before " + name)
+			return nil
+		}
+	}
+	after := func(name string) AfterFunc {
+		return func(ctx *Context, err error) error {
+			record(fmt.Sprintf("after %s: %v", name, err))
+			return err
+		}
+	}
+	use := func(name string) Middleware {
+		return func(next ClauseHandlerFunc) ClauseHandlerFunc {
+			return func(ctx *Context) error {
+				record("enter " + name)
+				err := next(ctx)
+				record("leave " + name)
+				return err
+			}
+		}
+	}
+
+	cmd := NewCommand("tool").
+		Before(before("root")).
+		After(after("root")).
+		Use(use("outer"), use("inner")).
+		Subcommand("remote").
+		Before(before("remote")).
+		After(after("remote")).
+		Subcommand("add").
+		Before(before("add")).
+		Handler(func(ctx *Context) error {
+			record("handler")
+			return errors.New("boom")
+		}).
+		Done().
+		Done().
+		Subcommand("plain").
+		Handler(func(ctx *Context) error {
+			record("plain")
+			return nil
+		}).
+		Done().
+		Build()
+
+	err := cmd.Execute([]string{"remote", "add"})
+	if err == nil || err.Error() != "boom" {
+		t.Fatalf("Execute error = %v, want boom", err)
+	}
+	want := []string{
+		"enter outer", "enter inner", "before root",
+		"before remote", "before add",
+		"handler",
+		"after remote: boom",
+		"after root: boom", "leave inner", "leave outer",
+	}
+	if !reflect.DeepEqual(log, want) {
+		t.Errorf("order:\n got %q\nwant %q", log, want)
+	}
+
+	log = nil
+	if err := cmd.Execute([]string{"plain"}); err != nil {
+		t.Fatal(err)
+	}
+	want = []string{"enter outer", "enter inner", "before root", "plain", "after root: <nil>", "leave inner", "leave outer"}
+	if !reflect.DeepEqual(log, want) {
+		t.Errorf("sibling:\n got %q\nwant %q", log, want)
+	}
+}
+
+func TestHooks_BeforeStopsDispatch(t *testing.T) {
+	ran := false
+	cmd := NewCommand("tool").
+		Flag("-token").String().Global().Done().
+		Before(func(ctx *Context) error {
+			if ctx.GetString("-token", "") == "" {
+				return errors.New("not logged in")
+			}
+			return nil
+		}).
+		After(func(ctx *Context, err error) error {
+			t.Error("After ran although Before failed")
+			return err
+		}).
+		Handler(func(ctx *Context) error {
+			ran = true
+			return nil
+		}).
+		Build()
+
+	if err := cmd.Execute(nil); err == nil || err.Error() != "not logged in" {
+		t.Errorf("error = %v", err)
+	}
+	if ran {
+		t.Error("handler ran although Before failed")
+	}
+}
+
+func TestHooks_AfterReplacesError(t *testing.T) {
+	cmd := NewCommand("tool").
+		After(func(ctx *Context, err error) error {
+			return nil
+		}).
+		Handler(func(ctx *Context) error { return errors.New("ignored") }).
+		Build()
+
+	if err := cmd.Execute(nil); err != nil {
+		t.Errorf("error = %v, want nil", err)
+	}
+}
+
+func TestHooks_RecoverMiddleware(t *testing.T) {
+	recoverPanics := func(next ClauseHandlerFunc) ClauseHandlerFunc {
+		return func(ctx *Context) (err error) {
+			defer func() {
+				if r := recover(); r != nil {
+					err = fmt.Errorf("panic: %v", r)
+				}
+			}()
+			return next(ctx)
+		}
+	}
+	cmd := NewCommand("tool").
+		Use(recoverPanics).
+		Subcommand("crash").
+		Handler(func(ctx *Context) error { panic("oops") }).
+		Done().
+		Build()
+
+	err := cmd.Execute([]string{"crash"})
+	if err == nil || !strings.Contains(err.Error(), "panic: oops") {
+		t.Errorf("error = %v", err)
+	}
+}
+
+func TestHooks_NotRunOnParseError(t *testing.T) {
+	var log []string
+	cmd := NewCommand("tool").
+		Before(func(ctx *Context) error {
+			log = append(log, "before")
+			return nil
+		}).
+		After(func(ctx *Context, err error) error {
+			log = append(log, "after")
+			return err
+		}).
+		Subcommand("plain").
+		Handler(func(ctx *Context) error { return nil }).
+		Done().
+		Build()
+
+	if err := cmd.Execute([]string{"plain", "-nope"}); err == nil {
+		t.Fatal("expected error")
+	}
+	if len(log) != 0 {
+		t.Errorf("hooks ran on a parse error: %q", log)
+	}
+}
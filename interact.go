@@ -0,0 +1,138 @@
+package completionflags
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// yesFlagName is the root-global flag added by YesFlag
+const yesFlagName = "-yes"
+
+// ErrNotInteractive is returned by the Context prompting methods when an
+// answer is needed but nobody can be asked: stdin isn't a terminal (a
+// script, a pipeline stage) or -yes was given and there is no default.
+var ErrNotInteractive = errors.New("input required but the session is not interactive")
+
+// YesFlag adds a root-global -yes flag for unattended runs: Confirm answers
+// yes, Prompt returns its default, and required flags are never prompted
+// for (PromptForMissing).
+func (cb *CommandBuilder) YesFlag() *CommandBuilder {
+	cb.cmd.yesFlag = true
+	return cb
+}
+
+// addYesFlag registers the -yes flag when YesFlag was called and the
+// command doesn't already define the flag itself
+func (cmd *Command) addYesFlag() {
+	if !cmd.yesFlag || cmd.findFlagSpec(yesFlagName) != nil {
+		return
+	}
+	cmd.flags = append(cmd.flags, &FlagSpec{
+		Names:       []string{yesFlagName},
+		Description: "Answer yes to confirmations and accept defaults without prompting",
+		Scope:       ScopeGlobal,
+		ArgCount:    0,
+	})
+}
+
+// assumeYes reports whether -yes was given
+func (cmd *Command) assumeYes(ctx *Context) bool {
+	if cmd == nil || !cmd.yesFlag {
+		return false
+	}
+	yes, _ := ctx.GlobalFlags[yesFlagName].(bool)
+	return yes
+}
+
+// Interactive reports whether the handler can ask the user questions:
+// the session has a Prompter and -yes wasn't given
+func (c *Context) Interactive() bool {
+	return c.prompter != nil && !c.Command.assumeYes(c)
+}
+
+// Confirm asks a yes/no question, re-asking until the answer is one. It
+// returns true under -yes, and false with ErrNotInteractive when the
+// session isn't interactive, so destructive work is never done unasked.
+//
+//	if ok, err := ctx.Confirm("Drop table users?"); !ok {
+//	    return err
+//	}
+func (c *Context) Confirm(question string) (bool, error) {
+	if c.Command.assumeYes(c) {
+		return true, nil
+	}
+	if c.prompter == nil {
+		return false, ErrNotInteractive
+	}
+	for {
+		answer, err := c.prompter.ReadLine(question + " [y/N]: ")
+		if err != nil {
+			return false, err
+		}
+		if strings.TrimSpace(answer) == "" {
+			return false, nil
+		}
+		if yes, ok := parseYesNo(answer); ok {
+			return yes, nil
+		}
+		fmt.Fprintln(c.Stdout(), "Please answer y or n")
+	}
+}
+
+// Prompt asks for a line of text. An empty answer gives def. Under -yes,
+// or when the session isn't interactive, def is returned without asking;
+// with no def that is ErrNotInteractive.
+func (c *Context) Prompt(label, def string) (string, error) {
+	if !c.Interactive() {
+		if def == "" {
+			return "", ErrNotInteractive
+		}
+		return def, nil
+	}
+	prompt := label + ": "
+	if def != "" {
+		prompt = fmt.Sprintf("%s [%s]: ", label, def)
+	}
+	answer, err := c.prompter.ReadLine(prompt)
+	if err != nil {
+		return "", err
+	}
+	if answer = strings.TrimSpace(answer); answer == "" {
+		return def, nil
+	}
+	return answer, nil
+}
+
+// Select offers options as a numbered menu and returns the one chosen, by
+// number or by name. It has no default, so it returns ErrNotInteractive
+// under -yes or when the session isn't interactive.
+func (c *Context) Select(label string, options []string) (string, error) {
+	if len(options) == 0 {
+		return "", fmt.Errorf("%s: no options to choose from", label)
+	}
+	if !c.Interactive() {
+		return "", ErrNotInteractive
+	}
+	for {
+		answer, err := promptMenu(label, options, c.prompter, c.Stdout())
+		if err != nil {
+			return "", err
+		}
+		for _, option := range options {
+			if answer == option {
+				return option, nil
+			}
+		}
+		fmt.Fprintf(c.Stdout(), "Please choose 1-%d\n", len(options))
+	}
+}
+
+// Password asks for a secret without echoing it. It returns
+// ErrNotInteractive under -yes or when the session isn't interactive.
+func (c *Context) Password(label string) (string, error) {
+	if !c.Interactive() {
+		return "", ErrNotInteractive
+	}
+	return c.prompter.ReadPassword(label + ": ")
+}
@@ -0,0 +1,121 @@
+package completionflags
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestContextPrompting(t *testing.T) {
+	var confirmed bool
+	var name, color, secret string
+	cmd := NewCommand("tool").
+		Subcommand("run").
+		Handler(func(ctx *Context) error {
+			var err error
+			if confirmed, err = ctx.Confirm("Proceed?"); err != nil {
+				return err
+			}
+			if name, err = ctx.Prompt("Name", "anon"); err != nil {
+				return err
+			}
+			if color, err = ctx.Select("Color", []string{"red", "green"}); err != nil {
+				return err
+			}
+			secret, err = ctx.Password("Token")
+			return err
+		}).
+		Done().
+		Build()
+
+	var out bytes.Buffer
+	p := &scriptedPrompter{answers: []string{"maybe", "yes", "", "blue", "2", "t0k"}}
+	if err := cmd.ExecuteWith([]string{"run"}, (&Context{}).SetStdout(&out).SetPrompter(p)); err != nil {
+		t.Fatal(err)
+	}
+	if !confirmed || name != "anon" || color != "green" || secret != "t0k" {
+		t.Errorf("got %v %q %q %q", confirmed, name, color, secret)
+	}
+	want := []string{"Proceed? [y/N]: ", "Proceed? [y/N]: ", "Name [anon]: ", "Choose 1-2: ", "Choose 1-2: ", "Token: "}
+	if len(p.asked) != len(want) {
+		t.Fatalf("asked %q, want %q", p.asked, want)
+	}
+	for i := range want {
+		if p.asked[i] != want[i] {
+			t.Errorf("asked[%d] = %q, want %q", i, p.asked[i], want[i])
+		}
+	}
+	if !p.secret[5] {
+		t.Error("Password should not echo")
+	}
+}
+
+func TestContextPrompting_Yes(t *testing.T) {
+	var confirmed bool
+	var name string
+	var selectErr, passwordErr error
+	cmd := NewCommand("tool").
+		YesFlag().
+		Subcommand("run").
+		Handler(func(ctx *Context) error {
+			confirmed, _ = ctx.Confirm("Proceed?")
+			name, _ = ctx.Prompt("Name", "anon")
+			_, selectErr = ctx.Select("Color", []string{"red"})
+			_, passwordErr = ctx.Password("Token")
+			return nil
+		}).
+		Done().
+		Build()
+
+	p := &scriptedPrompter{answers: []string{"n", "bob"}}
+	if err := cmd.ExecuteWith([]string{"-yes", "run"}, (&Context{}).SetStdout(io.Discard).SetPrompter(p)); err != nil {
+		t.Fatal(err)
+	}
+	if !confirmed || name != "anon" || len(p.asked) != 0 {
+		t.Errorf("confirmed = %v, name = %q, asked %q", confirmed, name, p.asked)
+	}
+	if !errors.Is(selectErr, ErrNotInteractive) || !errors.Is(passwordErr, ErrNotInteractive) {
+		t.Errorf("select: %v, password: %v", selectErr, passwordErr)
+	}
+}
+
+func TestContextPrompting_NotInteractive(t *testing.T) {
+	cmd := NewCommand("tool").
+		Subcommand("run").
+		Handler(func(ctx *Context) error {
+			if ctx.Interactive() {
+				t.Error("Interactive() with no prompter")
+			}
+			if name, err := ctx.Prompt("Name", "anon"); name != "anon" || err != nil {
+				t.Errorf("Prompt = %q, %v", name, err)
+			}
+			ok, err := ctx.Confirm("Proceed?")
+			if ok {
+				t.Error("Confirm answered yes without asking")
+			}
+			return err
+		}).
+		Done().
+		Build()
+
+	err := cmd.ExecuteWith([]string{"run"}, (&Context{}).SetStdin(bytes.NewReader(nil)))
+	if !errors.Is(err, ErrNotInteractive) {
+		t.Errorf("error = %v, want ErrNotInteractive", err)
+	}
+}
+
+func TestYesFlag_SkipsPromptForMissing(t *testing.T) {
+	cmd := NewCommand("tool").
+		YesFlag().
+		PromptForMissing().
+		Flag("-name").String().Required().Global().Done().
+		Handler(func(ctx *Context) error { return nil }).
+		Build()
+
+	p := &scriptedPrompter{answers: []string{"x"}}
+	err := cmd.ExecuteWith([]string{"-yes"}, (&Context{}).SetPrompter(p))
+	if err == nil || len(p.asked) != 0 {
+		t.Errorf("error = %v, asked %q", err, p.asked)
+	}
+}
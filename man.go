@@ -92,6 +92,9 @@ func (cmd *Command) GenerateManPage() string {
 		}
 	}
 
+	// CONSTRAINTS section
+	sb.WriteString(formatManGroups(cmd.groups))
+
 	// CLAUSES section
 	if len(cmd.separators) > 0 {
 		sb.WriteString(".SH CLAUSES\n")
@@ -152,21 +155,7 @@ func (cmd *Command) formatManPositional(spec *FlagSpec) string {
 
 	// Type information
 	if len(spec.ArgTypes) > 0 && spec.ArgTypes[0] != ArgString {
-		var typeName string
-		switch spec.ArgTypes[0] {
-		case ArgInt:
-			typeName = "integer"
-		case ArgFloat:
-			typeName = "float"
-		case ArgBool:
-			typeName = "boolean"
-		case ArgDuration:
-			typeName = "duration"
-		case ArgTime:
-			typeName = "time"
-		default:
-			typeName = "string"
-		}
+		typeName := argLabel(spec, 0)
 		details = append(details, fmt.Sprintf("Type: %s", typeName))
 	}
 
@@ -176,10 +165,14 @@ func (cmd *Command) formatManPositional(spec *FlagSpec) string {
 		details = append(details, "Scope: per-clause")
 	}
 
-	if spec.Default != nil {
+	if spec.Default != nil && !spec.Secret {
 		details = append(details, fmt.Sprintf("Default: %v", spec.Default))
 	}
 
+	if spec.EnvVar != "" {
+		details = append(details, fmt.Sprintf("Environment: $%s", spec.EnvVar))
+	}
+
 	if spec.Required {
 		details = append(details, "Required")
 	}
@@ -207,7 +200,10 @@ func (cmd *Command) formatManFlag(spec *FlagSpec) string {
 	}
 	flagNames := strings.Join(escapedNames, "|")
 
-	if spec.ArgCount > 0 {
+	if spec.OptionalArg {
+		// Optional argument: .BI \-flag1|\-flag2 "[=ARG]"
+		sb.WriteString(fmt.Sprintf(".BI %s \"%s\"\n", flagNames, optionalArgLabel(spec)))
+	} else if spec.ArgCount > 0 {
 		// Flags with arguments: .BI \-flag1|\-flag2 " ARG1 ARG2"
 		args := make([]string, spec.ArgCount)
 		for j := 0; j < spec.ArgCount; j++ {
@@ -240,17 +236,26 @@ func (cmd *Command) formatManFlag(spec *FlagSpec) string {
 		details = append(details, "Scope: per-clause")
 	}
 
-	if spec.Default != nil {
+	if spec.Default != nil && !spec.Secret {
 		details = append(details, fmt.Sprintf("Default: %v", spec.Default))
 	}
 
+	if spec.EnvVar != "" {
+		details = append(details, fmt.Sprintf("Environment: $%s", spec.EnvVar))
+	}
+
 	if spec.Required {
 		details = append(details, "Required")
 	}
 
+	for _, cond := range spec.RequiredIf {
+		details = append(details, fmt.Sprintf("Required when: %s", cond))
+	}
+
 	if spec.IsSlice {
 		details = append(details, "Can be specified multiple times")
 	}
+	details = append(details, formNotes(spec)...)
 
 	if len(details) > 0 {
 		sb.WriteString(".RS\n")
@@ -289,7 +294,11 @@ func (cmd *Command) formatManSubcommands(sb *strings.Builder, subcommands map[st
 		}
 
 		sb.WriteString(".TP\n")
-		sb.WriteString(fmt.Sprintf(".B %s\n", escapeGroff(fullName)))
+		label := fullName
+		if len(subcmd.Aliases) > 0 {
+			label = fmt.Sprintf("%s (%s)", fullName, strings.Join(subcmd.Aliases, ", "))
+		}
+		sb.WriteString(fmt.Sprintf(".B %s\n", escapeGroff(label)))
 		if subcmd.Description != "" {
 			sb.WriteString(fmt.Sprintf("%s\n", escapeGroff(subcmd.Description)))
 		}
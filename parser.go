@@ -2,16 +2,19 @@ package completionflags
 
 import (
 	"fmt"
+	"io"
+	"os"
 	"strconv"
 	"strings"
 	"time"
 )
 
 // Execute parses arguments and runs the handler, using os.Stdin/Stdout/Stderr
-// + context.Background() + nil State for any handler context it builds. This
-// is the bash-CLI entrypoint; embedded callers should use ExecuteWith.
+// + a context cancelled on SIGINT/SIGTERM + nil State for any handler context
+// it builds. This is the bash-CLI entrypoint; embedded callers should use
+// ExecuteWith.
 func (cmd *Command) Execute(args []string) error {
-	return cmd.ExecuteWith(args, nil)
+	return cmd.executeInterruptible(args)
 }
 
 // ExecuteWith parses arguments and runs the handler against the supplied
@@ -51,9 +54,27 @@ func (cmd *Command) ExecuteWith(args []string, base *Context) error {
 		case "-completion-script":
 			fmt.Fprint(base.Stdout(), cmd.GenerateCompletionScript())
 			return nil
+		case "-schema":
+			return cmd.writeSchema(base.Stdout())
+		case "-explain", "-explain-json":
+			return cmd.explain(args[1:], base, args[0] == "-explain-json")
 		}
 	}
 
+	return cmd.dispatch(args, base, base.interactivePrompter(), cmd.runHandler)
+}
+
+// dispatch parses args through the subcommand walk and hands the parsed
+// Context to run with the chain of subcommands on its path (nil for the
+// root handler). ExecuteWith runs the handler; -explain describes it.
+func (cmd *Command) dispatch(args []string, base *Context, prompter Prompter, run func(ctx *Context, chain []*Subcommand) error) error {
+	// Expand @file arguments; the handler's RawArgs keeps them as given
+	rawArgs := args
+	args, widths, err := cmd.expandResponseFiles(args)
+	if err != nil {
+		return err
+	}
+
 	// Parse root global flags
 	rootGlobalFlags, remaining, err := cmd.parseRootGlobalFlags(args)
 	if err != nil {
@@ -65,12 +86,12 @@ func (cmd *Command) ExecuteWith(args []string, base *Context) error {
 		path := []string{}
 		currentSubcommands := cmd.subcommands
 		var leafSubcmd *Subcommand
+		var chain []*Subcommand // Subcommands on the path, for their hooks
 		argIndex := 0
 
 		// Walk the tree
 		for argIndex < len(remaining) {
-			subcommandName := remaining[argIndex]
-			subcmd := currentSubcommands[subcommandName]
+			subcmd, subcommandName := cmd.resolveSubcommand(currentSubcommands, remaining[argIndex])
 
 			if subcmd == nil {
 				// Not a subcommand at this level - stop walking
@@ -80,6 +101,7 @@ func (cmd *Command) ExecuteWith(args []string, base *Context) error {
 			// Found a subcommand
 			path = append(path, subcommandName)
 			leafSubcmd = subcmd
+			chain = append(chain, subcmd)
 			argIndex++
 
 			// Check for help flags immediately after this subcommand. The
@@ -114,6 +136,17 @@ func (cmd *Command) ExecuteWith(args []string, base *Context) error {
 		if leafSubcmd != nil {
 			// Check if this is an intermediate node with no handler
 			if leafSubcmd.Handler == nil && len(leafSubcmd.Subcommands) > 0 {
+				// A word after it is a mistyped nested subcommand
+				if argIndex < len(remaining) {
+					next := remaining[argIndex]
+					if !strings.HasPrefix(next, "-") && !strings.HasPrefix(next, "+") {
+						return UnknownCommandError{
+							Name:        strings.Join(append(append([]string{}, path...), next), " "),
+							Suggestions: subcommandSuggestions(next, leafSubcmd.Subcommands, path),
+						}
+					}
+				}
+
 				// Intermediate node with no handler - show help (full parent path)
 				parent := cmd.name
 				if len(path) > 1 {
@@ -129,28 +162,40 @@ func (cmd *Command) ExecuteWith(args []string, base *Context) error {
 			}
 
 			// Parse subcommand arguments (everything after the subcommand path)
-			ctx, err := cmd.parseSubcommand(leafSubcmd, rootGlobalFlags, remaining[argIndex:])
+			ctx, err := cmd.parseSubcommand(leafSubcmd, path, rootGlobalFlags, remaining[argIndex:], prompter, base.Stdout())
 			if err != nil {
 				return err
 			}
 
+			// RawArgs holds the words after the subcommand path
 			ctx.SubcommandPath = path
+			ctx.RawArgs = unexpandedTail(cmd.RedactArgs(rawArgs), cmd.RedactArgs(args), widths, len(remaining)-argIndex)
 			inheritFromBase(ctx, base)
+			ctx.prompter = prompter
 
-			// Validate
+			// Validate, then run the ValidateContext hooks of the root and
+			// of each subcommand on the path, outermost first like Before
 			if err := cmd.validateSubcommand(leafSubcmd, ctx); err != nil {
 				return err
 			}
+			if err := runContextValidators(cmd.contextValidators, ctx); err != nil {
+				return err
+			}
+			for _, sub := range chain {
+				if err := runContextValidators(sub.ContextValidators, ctx); err != nil {
+					return err
+				}
+			}
 
-			// Execute subcommand handler
-			return leafSubcmd.Handler(ctx)
+			return run(ctx, chain)
 		}
 	}
 
 	// No subcommand. Two cases:
 	//   1) args[0] looks like a subcommand attempt (not a flag, has
 	//      content) and there's no root handler → unknown command.
-	//      Return ErrUnknownCommand so callers can react (embedded
+	//      Return UnknownCommandError (which unwraps to
+	//      ErrUnknownCommand) so callers can react (embedded
 	//      shells, structured logs, exit code), per the CLAUDE.md
 	//      "fail loudly on invalid input" rule. The bash CLI
 	//      entry-point catches this and falls back to printing help
@@ -160,7 +205,10 @@ func (cmd *Command) ExecuteWith(args []string, base *Context) error {
 	if cmd.handler == nil && len(remaining) > 0 {
 		first := remaining[0]
 		if !strings.HasPrefix(first, "-") && !strings.HasPrefix(first, "+") {
-			return ErrUnknownCommand(first)
+			return UnknownCommandError{
+				Name:        first,
+				Suggestions: subcommandSuggestions(first, cmd.subcommands, nil),
+			}
 		}
 	}
 
@@ -171,21 +219,40 @@ func (cmd *Command) ExecuteWith(args []string, base *Context) error {
 		return nil
 	}
 
-	// Parse into clauses (standard parsing)
-	ctx, err := cmd.Parse(args)
+	// Parse into clauses (standard parsing). This is Parse with missing
+	// required flags asked for before validation.
+	ctx, err := cmd.parse(args)
 	if err != nil {
 		return err
 	}
+	if err := cmd.promptForMissing(cmd.flags, ctx, prompter, base.Stdout()); err != nil {
+		return err
+	}
 
+	ctx.RawArgs = cmd.RedactArgs(rawArgs)
 	inheritFromBase(ctx, base)
+	ctx.prompter = prompter
 
-	// Validate
+	// Validate, then run the ValidateContext hooks
 	if err := cmd.validate(ctx); err != nil {
 		return err
 	}
+	if err := runContextValidators(cmd.contextValidators, ctx); err != nil {
+		return err
+	}
 
-	// Execute handler
-	return cmd.handler(ctx)
+	return run(ctx, nil)
+}
+
+// runHandler runs the handler of the root command (chain nil) or of the
+// last subcommand in chain, inside the hooks of the path and its timeout
+func (cmd *Command) runHandler(ctx *Context, chain []*Subcommand) error {
+	if len(chain) == 0 {
+		handler := wrapHandler(cmd.handler, cmd.before, cmd.after, cmd.middleware)
+		return runWithTimeout(ctx, cmd.handlerTimeout(ctx, nil), cmd.name, handler)
+	}
+	name := cmd.name + " " + strings.Join(ctx.SubcommandPath, " ")
+	return runWithTimeout(ctx, cmd.handlerTimeout(ctx, chain), name, cmd.subcommandHandler(chain))
 }
 
 // inheritFromBase copies IO+State+Ctx from a caller-supplied base Context
@@ -199,17 +266,42 @@ func inheritFromBase(target, base *Context) {
 	target.stdout = base.stdout
 	target.stderr = base.stderr
 	target.ctx = base.ctx
+	target.prompter = base.prompter
 	target.State = base.State
 }
 
-// Parse breaks arguments into clauses
+// Parse breaks arguments into clauses and fills in values from the
+// environment, configuration files and defaults. It doesn't validate them or
+// run the ValidateContext hooks; Execute does both.
 func (cmd *Command) Parse(args []string) (*Context, error) {
+	ctx, err := cmd.parse(args)
+	if err != nil {
+		return nil, err
+	}
+	return ctx, nil
+}
+
+// parse parses args through every layer
+func (cmd *Command) parse(args []string) (*Context, error) {
+	return cmd.parseArgs(args, false)
+}
+
+// parseForCompletion parses the words before the cursor. Completion runs on
+// every TAB, so it reads no files: SecretFile values and configuration files
+// are skipped, and the clauses taken from argv are kept when a later layer
+// fails.
+func (cmd *Command) parseForCompletion(args []string) *Context {
+	ctx, _ := cmd.parseArgs(args, true)
+	return ctx
+}
+
+// parseArgs parses args and fills in the layers beneath them
+func (cmd *Command) parseArgs(args []string, completing bool) (*Context, error) {
 	ctx := &Context{
-		Command:        cmd,
-		Clauses:        []Clause{},
-		GlobalFlags:    make(map[string]interface{}),
-		RawArgs:        args,
-		deferredValues: make(map[string]*deferredValue),
+		Command:     cmd,
+		Clauses:     []Clause{},
+		GlobalFlags: make(map[string]interface{}),
+		RawArgs:     cmd.RedactArgs(args),
 	}
 
 	currentClause := Clause{
@@ -218,6 +310,16 @@ func (cmd *Command) Parse(args []string) (*Context, error) {
 		Positional: []string{},
 	}
 
+	// Dependent root globals given before the subcommand name are parsed
+	// here, where everything they depend on is known
+	for name, value := range cmd.parentGlobals {
+		if spec := cmd.findFlagSpec(name); spec != nil && spec.isDependent() {
+			if raw, ok := value.(string); ok {
+				ctx.deferValue(spec, ctx.GlobalFlags, raw)
+			}
+		}
+	}
+
 	i := 0
 	for i < len(args) {
 		arg := args[i]
@@ -265,18 +367,58 @@ func (cmd *Command) Parse(args []string) (*Context, error) {
 	// Save final clause
 	ctx.Clauses = append(ctx.Clauses, currentClause)
 
-	// Resolve deferred values now that all flags are parsed
-	if err := cmd.resolveDeferredValues(ctx); err != nil {
+	// Match positional arguments to positional flag specs
+	if err := cmd.matchPositionals(ctx); err != nil {
 		return nil, err
 	}
 
-	// Match positional arguments to positional flag specs
-	if err := cmd.matchPositionals(ctx); err != nil {
+	// Combine the clauses into an expression. The context is returned
+	// along with the error so completion can work inside an unfinished one.
+	if cmd.expr != nil {
+		if err := cmd.buildClauseTree(ctx); err != nil {
+			return ctx, err
+		}
+	}
+
+	// Completion keeps what argv gave when a later layer fails
+	fail := func(err error) (*Context, error) {
+		if completing {
+			return ctx, err
+		}
 		return nil, err
 	}
 
+	// Values of SecretFile flags given as -NAME-file
+	if !completing {
+		if err := cmd.readSecretFiles(ctx, cmd.flags); err != nil {
+			return nil, err
+		}
+	}
+
+	ctx.markSources(SourceArgs)
+
+	// Fill flags absent from argv from their environment variables, then
+	// from configuration files
+	if err := cmd.applyEnv(ctx); err != nil {
+		return fail(err)
+	}
+	ctx.markSources(SourceEnv)
+
+	if !completing {
+		if err := cmd.applyConfig(ctx); err != nil {
+			return nil, err
+		}
+		ctx.markSources(SourceConfig)
+	}
+
 	// Apply defaults
 	cmd.applyDefaults(ctx)
+	ctx.markSources(SourceDefault)
+
+	// Parse values that depend on other flags, now that every layer is in
+	if err := cmd.resolveDeferredValues(ctx); err != nil {
+		return fail(err)
+	}
 
 	return ctx, nil
 }
@@ -284,21 +426,19 @@ func (cmd *Command) Parse(args []string) (*Context, error) {
 // parseFlag handles both -flag and +flag
 func (cmd *Command) parseFlag(args []string, pos int, clause *Clause, ctx *Context) (int, error) {
 	flagArg := args[pos]
-	hasPlus := strings.HasPrefix(flagArg, "+")
 
-	// Normalize to find flag spec (remove prefix)
-	normalized := flagArg
-	if hasPlus {
-		normalized = "-" + flagArg[1:]
-	}
-
-	spec := cmd.findFlagSpec(normalized)
+	spec, word := cmd.resolveFlagWord(flagArg, cmd.findFlagSpec)
 	if spec == nil {
+		if bundle := cmd.splitBundle(flagArg, cmd.findFlagSpec); bundle != nil {
+			return cmd.parseBundle(bundle, args, pos, clause, ctx)
+		}
 		return 0, ParseError{
-			Flag:    flagArg,
-			Message: "unknown flag",
+			Flag:        flagArg,
+			Message:     "unknown flag",
+			Suggestions: cmd.flagSuggestions(word.name),
 		}
 	}
+	hasPlus := word.plus
 
 	// Determine target storage based on scope
 	var target map[string]interface{}
@@ -308,15 +448,62 @@ func (cmd *Command) parseFlag(args []string, pos int, clause *Clause, ctx *Conte
 		target = clause.Flags
 	}
 
+	// -flag=value: parse as if the value were the next word
+	if word.inline && spec.ArgCount > 0 {
+		if spec.ArgCount > 1 {
+			return 0, ParseError{
+				Flag:    word.name,
+				Message: fmt.Sprintf("requires %d argument(s); only one can follow '='", spec.ArgCount),
+			}
+		}
+		if spec.OptionalArg {
+			value, err := parseArgValue(word.value, spec, 0, ctx.GlobalFlags)
+			if err != nil {
+				return 0, ParseError{
+					Flag:    word.name,
+					Message: fmt.Sprintf("invalid argument: %v", err),
+				}
+			}
+			storeFlagValue(spec, target, cmd.getPrefixHandler()(spec.Names[0], hasPlus, value))
+			return 1, nil
+		}
+		prefix := "-"
+		if hasPlus {
+			prefix = "+"
+		}
+		if _, err := cmd.parseFlag([]string{prefix + word.name[1:], word.value}, 0, clause, ctx); err != nil {
+			return 0, err
+		}
+		return 1, nil
+	}
+
 	// Parse based on argument count
 	if spec.ArgCount == 0 {
+		if spec.IsCounter {
+			return 1, countOccurrence(spec, target, word)
+		}
+
 		// Boolean flag - no arguments
-		value := true
+		value, err := boolWordValue(word)
+		if err != nil {
+			return 0, err
+		}
 		finalValue := cmd.getPrefixHandler()(spec.Names[0], hasPlus, value)
 		target[spec.Names[0]] = finalValue
 		return 1, nil
 	}
 
+	// An OptionalValue flag takes the next word when it is a value for it
+	if spec.OptionalArg {
+		value, ok := cmd.optionalValue(spec, args, pos, ctx.GlobalFlags)
+		width := 2
+		if !ok {
+			value, width = spec.BareValue, 1
+		}
+		storeFlagValue(spec, target, cmd.getPrefixHandler()(spec.Names[0], hasPlus, value))
+		return width, nil
+	}
+
 	// Check we have enough arguments
 	if pos+spec.ArgCount >= len(args) {
 		return 0, ParseError{
@@ -325,26 +512,19 @@ func (cmd *Command) parseFlag(args []string, pos int, clause *Clause, ctx *Conte
 		}
 	}
 
+	// A value depending on other flags is parsed once they're all known,
+	// then goes through the prefix handler like any other
+	if spec.ArgCount == 1 && spec.isDependent() {
+		ctx.deferValue(spec, target, args[pos+1])
+		deferred := ctx.deferredValues[len(ctx.deferredValues)-1]
+		deferred.fromArgs, deferred.plus = true, hasPlus
+		return 1 + spec.ArgCount, nil
+	}
+
 	// Parse arguments
 	if spec.ArgCount == 1 && !hasPlus {
-		// Check if we need deferred parsing
-		needsDeferred := spec.ArgTypes[0] == ArgTime && spec.TimeZoneFromFlag != ""
-
-		if needsDeferred {
-			// Store for deferred parsing
-			clauseIdx := len(ctx.Clauses) // Current clause index
-			ctx.deferredValues[spec.Names[0]] = &deferredValue{
-				rawString:   args[pos+1],
-				spec:        spec,
-				isGlobal:    spec.Scope == ScopeGlobal,
-				clauseIndex: clauseIdx,
-			}
-			// Don't parse yet, just consume the argument
-			return 1 + spec.ArgCount, nil
-		}
-
 		// Single argument - parse immediately
-		value, err := parseArgValue(args[pos+1], spec.ArgTypes[0], spec, ctx.GlobalFlags)
+		value, err := parseArgValue(args[pos+1], spec, 0, ctx.GlobalFlags)
 		if err != nil {
 			return 0, ParseError{
 				Flag:    flagArg,
@@ -355,18 +535,7 @@ func (cmd *Command) parseFlag(args []string, pos int, clause *Clause, ctx *Conte
 		// Apply prefix handler
 		finalValue := cmd.getPrefixHandler()(spec.Names[0], hasPlus, value)
 
-		// Handle slices (accumulate) vs single values (replace)
-		if spec.IsSlice {
-			existing, ok := target[spec.Names[0]]
-			if !ok {
-				target[spec.Names[0]] = []interface{}{finalValue}
-			} else {
-				slice := existing.([]interface{})
-				target[spec.Names[0]] = append(slice, finalValue)
-			}
-		} else {
-			target[spec.Names[0]] = finalValue
-		}
+		storeFlagValue(spec, target, finalValue)
 
 		return 1 + spec.ArgCount, nil
 	}
@@ -374,7 +543,7 @@ func (cmd *Command) parseFlag(args []string, pos int, clause *Clause, ctx *Conte
 	// Multi-argument flag
 	argMap := make(map[string]interface{})
 	for i := 0; i < spec.ArgCount; i++ {
-		value, err := parseArgValue(args[pos+1+i], spec.ArgTypes[i], spec, ctx.GlobalFlags)
+		value, err := parseArgValue(args[pos+1+i], spec, i, ctx.GlobalFlags)
 		if err != nil {
 			return 0, ParseError{
 				Flag:    flagArg,
@@ -387,40 +556,87 @@ func (cmd *Command) parseFlag(args []string, pos int, clause *Clause, ctx *Conte
 	// Apply prefix handler
 	finalValue := cmd.getPrefixHandler()(spec.Names[0], hasPlus, argMap)
 
-	// Handle slices (accumulate) vs single values (replace)
+	storeFlagValue(spec, target, finalValue)
+
+	return 1 + spec.ArgCount, nil
+}
+
+// storeFlagValue records one occurrence of a flag: accumulating flags
+// (slices) append, everything else replaces
+func storeFlagValue(spec *FlagSpec, target map[string]interface{}, value interface{}) {
 	if spec.IsSlice {
 		existing, ok := target[spec.Names[0]]
 		if !ok {
-			target[spec.Names[0]] = []interface{}{finalValue}
+			target[spec.Names[0]] = []interface{}{value}
 		} else {
 			slice := existing.([]interface{})
-			target[spec.Names[0]] = append(slice, finalValue)
+			target[spec.Names[0]] = append(slice, value)
 		}
 	} else {
-		target[spec.Names[0]] = finalValue
+		target[spec.Names[0]] = value
 	}
-
-	return 1 + spec.ArgCount, nil
 }
 
-// parseArgValue converts a string to the appropriate type
-func parseArgValue(value string, argType ArgType, spec *FlagSpec, globalFlags map[string]interface{}) (interface{}, error) {
-	switch argType {
-	case ArgString:
-		return value, nil
-	case ArgInt:
-		return strconv.Atoi(value)
-	case ArgFloat:
-		return strconv.ParseFloat(value, 64)
-	case ArgBool:
-		return strconv.ParseBool(value)
-	case ArgDuration:
-		return time.ParseDuration(value)
-	case ArgTime:
-		return parseTimeValue(value, spec, globalFlags)
+// countOccurrence updates a Count flag: each occurrence adds one, the -no-X
+// form resets it and -X=N sets it
+func countOccurrence(spec *FlagSpec, target map[string]interface{}, word flagWord) error {
+	name := spec.Names[0]
+	switch {
+	case word.inline:
+		n, err := strconv.Atoi(word.value)
+		if err != nil || n < 0 {
+			return ParseError{
+				Flag:    word.name,
+				Message: "invalid count " + strconv.Quote(word.value),
+			}
+		}
+		target[name] = n
+	case word.negated:
+		target[name] = 0
 	default:
-		return value, nil
+		n, _ := target[name].(int)
+		target[name] = n + 1
 	}
+	return nil
+}
+
+// optionalValue returns the value of the OptionalValue flag at args[pos]
+// taken from the word after it. That word is the flag's value unless it is a
+// flag, a clause separator or a subcommand name, or doesn't parse as the
+// flag's argument type; the flag then stands alone with its BareValue.
+func (cmd *Command) optionalValue(spec *FlagSpec, args []string, pos int, globalFlags map[string]interface{}) (interface{}, bool) {
+	if pos+1 >= len(args) || spec.isDependent() {
+		return nil, false
+	}
+	next := args[pos+1]
+	if strings.HasPrefix(next, "-") || strings.HasPrefix(next, "+") || cmd.isSeparator(next) || cmd.hasSubcommand(next) {
+		return nil, false
+	}
+	value, err := parseArgValue(next, spec, 0, globalFlags)
+	if err != nil {
+		return nil, false
+	}
+	return value, true
+}
+
+// parseBundle parses the flags of a bundled word such as "-vqo" in order.
+// The last flag takes any arguments from the words after the bundle.
+func (cmd *Command) parseBundle(bundle []*FlagSpec, args []string, pos int, clause *Clause, ctx *Context) (int, error) {
+	letters := bundleLetters(args[pos])
+	for i, spec := range bundle {
+		name := letters[i]
+		if i < len(bundle)-1 {
+			if _, err := cmd.parseFlag([]string{name}, 0, clause, ctx); err != nil {
+				return 0, err
+			}
+			continue
+		}
+		if spec.ArgCount == 0 {
+			return cmd.parseFlag([]string{name}, 0, clause, ctx)
+		}
+		return cmd.parseFlag(append([]string{name}, args[pos+1:]...), 0, clause, ctx)
+	}
+	return 1, nil
 }
 
 // parseTimeValue parses a time string using the spec's time configuration
@@ -472,30 +688,196 @@ func parseTimeValue(value string, spec *FlagSpec, globalFlags map[string]interfa
 	return time.Time{}, fmt.Errorf("could not parse %q with any format: %w", value, lastErr)
 }
 
-// resolveDeferredValues parses values that were deferred because they depend on other flags
-func (cmd *Command) resolveDeferredValues(ctx *Context) error {
-	for flagName, deferred := range ctx.deferredValues {
-		// Now that all flags are parsed, we can resolve dependencies
-		value, err := parseArgValue(deferred.rawString, deferred.spec.ArgTypes[0],
-			deferred.spec, ctx.GlobalFlags)
-		if err != nil {
-			return ParseError{
-				Flag:    flagName,
-				Message: fmt.Sprintf("deferred parsing failed: %v", err),
+// applyEnv fills flags that weren't specified on the command line from their
+// EnvVar. Values are parsed exactly like argv values, with those that depend
+// on other flags deferred to resolveDeferredValues.
+func (cmd *Command) applyEnv(ctx *Context) error {
+	for _, spec := range cmd.flags {
+		if spec.EnvVar == "" {
+			continue
+		}
+		if err := cmd.applyEnvFlag(ctx, spec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyEnvFlag applies a single flag's environment value to GlobalFlags or to
+// every clause that lacks the flag, mirroring applyDefaults.
+func (cmd *Command) applyEnvFlag(ctx *Context, spec *FlagSpec) error {
+	raw, ok := os.LookupEnv(spec.EnvVar)
+	if !ok || raw == "" || !needsLayerValue(ctx, spec) {
+		return nil
+	}
+
+	occurrences, err := envOccurrences(raw, spec)
+	if err == nil {
+		err = cmd.fillFromLayer(ctx, spec, occurrences)
+	}
+	if err != nil {
+		return ParseError{
+			Flag:    spec.Names[0],
+			Message: fmt.Sprintf("invalid value in $%s: %v", spec.EnvVar, err),
+		}
+	}
+	return nil
+}
+
+// needsLayerValue reports whether a flag is still unset globally, or in at
+// least one clause for local flags, and so can take a value from a fallback
+// layer (environment or configuration file). A root global given before the
+// subcommand name is set, though it's merged in after parsing.
+func needsLayerValue(ctx *Context, spec *FlagSpec) bool {
+	name := spec.Names[0]
+	if spec.Scope == ScopeGlobal {
+		_, exists := ctx.GlobalFlags[name]
+		_, given := ctx.Command.parentGlobals[name] // Before the subcommand name
+		return !exists && !given
+	}
+	for i := range ctx.Clauses {
+		if _, exists := ctx.Clauses[i].Flags[name]; !exists {
+			return true
+		}
+	}
+	return false
+}
+
+// fillFromLayer parses argv-style word groups for a flag and stores the
+// result wherever the flag is still unset.
+func (cmd *Command) fillFromLayer(ctx *Context, spec *FlagSpec, occurrences [][]string) error {
+	if !needsLayerValue(ctx, spec) {
+		return nil
+	}
+	if spec.isDependent() {
+		deferLayerValue(ctx, spec, occurrences)
+		return nil
+	}
+	value, err := layerValue(spec, occurrences, ctx.GlobalFlags)
+	if err != nil || value == nil {
+		return err
+	}
+
+	name := spec.Names[0]
+	if spec.Scope == ScopeGlobal {
+		ctx.GlobalFlags[name] = value
+		return nil
+	}
+	for i := range ctx.Clauses {
+		if _, exists := ctx.Clauses[i].Flags[name]; !exists {
+			ctx.Clauses[i].Flags[name] = value
+		}
+	}
+	return nil
+}
+
+// deferLayerValue queues each occurrence of a dependent flag for
+// resolveDeferredValues wherever the flag is still unset
+func deferLayerValue(ctx *Context, spec *FlagSpec, occurrences [][]string) {
+	targets := []map[string]interface{}{ctx.GlobalFlags}
+	if spec.Scope != ScopeGlobal {
+		targets = nil
+		for i := range ctx.Clauses {
+			if _, exists := ctx.Clauses[i].Flags[spec.Names[0]]; !exists {
+				targets = append(targets, ctx.Clauses[i].Flags)
+			}
+		}
+	}
+	for _, target := range targets {
+		for _, words := range occurrences {
+			if len(words) == 1 {
+				ctx.deferValue(spec, target, words[0])
 			}
 		}
+	}
+}
 
-		// Store in correct location
-		if deferred.isGlobal {
-			ctx.GlobalFlags[flagName] = value
-		} else {
-			// For local flags, store in the appropriate clause
-			if deferred.clauseIndex < len(ctx.Clauses) {
-				ctx.Clauses[deferred.clauseIndex].Flags[flagName] = value
+// envOccurrences splits an environment string into argv-style word groups,
+// one group per occurrence of the flag:
+//   - boolean flags accept strconv.ParseBool forms; false yields no occurrence
+//   - counting flags take the count itself
+//   - single-argument, non-accumulating flags use the whole string
+//   - everything else is split on whitespace and consumed ArgCount words at a
+//     time
+func envOccurrences(raw string, spec *FlagSpec) ([][]string, error) {
+	if spec.IsCounter {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid count %q", raw)
+		}
+		return make([][]string, n), nil
+	}
+	if spec.ArgCount == 0 {
+		b, err := strconv.ParseBool(raw)
+		if err != nil || !b {
+			return nil, err
+		}
+		return [][]string{{}}, nil
+	}
+
+	if spec.ArgCount == 1 && !spec.IsSlice {
+		return [][]string{{raw}}, nil
+	}
+
+	words := strings.Fields(raw)
+	if len(words) == 0 || len(words)%spec.ArgCount != 0 {
+		return nil, fmt.Errorf("expected a multiple of %d value(s), got %d", spec.ArgCount, len(words))
+	}
+	if !spec.IsSlice && len(words) != spec.ArgCount {
+		return nil, fmt.Errorf("expected %d value(s), got %d", spec.ArgCount, len(words))
+	}
+
+	var occurrences [][]string
+	for start := 0; start < len(words); start += spec.ArgCount {
+		occurrences = append(occurrences, words[start:start+spec.ArgCount])
+	}
+	return occurrences, nil
+}
+
+// layerValue parses word groups through parseArgValue into the value the flag
+// would have had on the command line: true for boolean flags, the count for
+// counting flags, a single value or map[string]interface{} per occurrence,
+// collected into []interface{} for accumulating flags. Returns nil when there
+// are no occurrences.
+func layerValue(spec *FlagSpec, occurrences [][]string, globalFlags map[string]interface{}) (interface{}, error) {
+	if len(occurrences) == 0 {
+		return nil, nil
+	}
+	if spec.IsCounter {
+		return len(occurrences), nil
+	}
+	if spec.ArgCount == 0 {
+		return true, nil
+	}
+
+	var values []interface{}
+	for _, words := range occurrences {
+		if len(words) != spec.ArgCount {
+			return nil, fmt.Errorf("expected %d value(s), got %d", spec.ArgCount, len(words))
+		}
+		if spec.ArgCount == 1 {
+			value, err := parseArgValue(words[0], spec, 0, globalFlags)
+			if err != nil {
+				return nil, err
 			}
+			values = append(values, value)
+			continue
 		}
+		argMap := make(map[string]interface{})
+		for j := 0; j < spec.ArgCount; j++ {
+			value, err := parseArgValue(words[j], spec, j, globalFlags)
+			if err != nil {
+				return nil, fmt.Errorf("argument %d: %v", j, err)
+			}
+			argMap[spec.ArgNames[j]] = value
+		}
+		values = append(values, argMap)
 	}
-	return nil
+
+	if spec.IsSlice {
+		return values, nil
+	}
+	return values[len(values)-1], nil
 }
 
 // applyDefaults applies default values to flags that weren't specified
@@ -522,6 +904,10 @@ func (cmd *Command) applyDefaults(ctx *Context) {
 
 // validate checks required flags and runs custom validators
 func (cmd *Command) validate(ctx *Context) error {
+	if err := cmd.validateClauseCount(ctx); err != nil {
+		return err
+	}
+
 	for _, spec := range cmd.flags {
 		if spec.Required {
 			if spec.Scope == ScopeGlobal {
@@ -549,6 +935,16 @@ func (cmd *Command) validate(ctx *Context) error {
 			}
 		}
 
+		// Conditionally required
+		if err := cmd.validateRequiredIf(ctx, spec); err != nil {
+			return err
+		}
+
+		// Per-clause presence and occurrence limits
+		if err := validatePerClause(ctx, spec); err != nil {
+			return err
+		}
+
 		// Run custom validator if provided
 		if spec.Validator != nil {
 			if spec.Scope == ScopeGlobal {
@@ -556,7 +952,7 @@ func (cmd *Command) validate(ctx *Context) error {
 					if err := spec.Validator(value); err != nil {
 						return ValidationError{
 							Flag:    spec.Names[0],
-							Message: err.Error(),
+							Message: secretError(spec, err).Error(),
 						}
 					}
 				}
@@ -567,7 +963,7 @@ func (cmd *Command) validate(ctx *Context) error {
 						if err := spec.Validator(value); err != nil {
 							return ValidationError{
 								Flag:    fmt.Sprintf("%s (clause %d)", spec.Names[0], i),
-								Message: err.Error(),
+								Message: secretError(spec, err).Error(),
 							}
 						}
 					}
@@ -576,7 +972,13 @@ func (cmd *Command) validate(ctx *Context) error {
 		}
 	}
 
-	return nil
+	// Whole-clause validators
+	if err := cmd.runClauseValidators(ctx); err != nil {
+		return err
+	}
+
+	// Constraints across flags
+	return cmd.validateGroups(ctx)
 }
 
 // matchPositionals matches positional arguments to positional flag specs
@@ -635,7 +1037,7 @@ func (cmd *Command) matchPositionalsToSpecs(specs []*FlagSpec, args []string, ta
 				remaining := args[argIndex:]
 				values := make([]interface{}, len(remaining))
 				for i, arg := range remaining {
-					val, err := parseArgValue(arg, spec.ArgTypes[0], spec, nil)
+					val, err := parseArgValue(arg, spec, 0, nil)
 					if err != nil {
 						return argIndex, ParseError{
 							Flag:    spec.Names[0],
@@ -653,7 +1055,7 @@ func (cmd *Command) matchPositionalsToSpecs(specs []*FlagSpec, args []string, ta
 
 		// Non-variadic: consume one arg
 		if argIndex < len(args) {
-			val, err := parseArgValue(args[argIndex], spec.ArgTypes[0], spec, nil)
+			val, err := parseArgValue(args[argIndex], spec, 0, nil)
 			if err != nil {
 				return argIndex, ParseError{
 					Flag:    spec.Names[0],
@@ -683,45 +1085,104 @@ func (cmd *Command) parseRootGlobalFlags(args []string) (map[string]interface{},
 		}
 
 		// Find if this is a root global flag
-		spec := cmd.findRootGlobalFlag(arg)
+		spec, word := cmd.resolveFlagWord(arg, cmd.findRootGlobalFlag)
 		if spec == nil {
-			// Not a root global flag - stop here
+			bundle := cmd.splitBundle(arg, cmd.findRootGlobalFlag)
+			if bundle == nil {
+				// Not a root global flag - stop here
+				break
+			}
+			for _, b := range bundle[:len(bundle)-1] {
+				if b.IsCounter {
+					countOccurrence(b, flags, flagWord{})
+				} else {
+					flags[b.Names[0]] = true
+				}
+			}
+			letters := bundleLetters(arg)
+			spec, word = bundle[len(bundle)-1], flagWord{name: letters[len(letters)-1]}
+		}
+		if word.plus {
+			// +flag is left to the full parser's prefix handling
 			break
 		}
 
+		// An inline -flag=value supplies the single argument itself
+		values := args[i+1:]
+		width := 1 + spec.ArgCount
+		if word.inline && spec.ArgCount > 0 {
+			if spec.ArgCount > 1 {
+				return nil, nil, ParseError{
+					Flag:    word.name,
+					Message: fmt.Sprintf("requires %d argument(s); only one can follow '='", spec.ArgCount),
+				}
+			}
+			values, width = []string{word.value}, 1
+		}
+
 		// Parse flag value
 		if spec.ArgCount == 0 {
-			// Boolean flag
-			flags[spec.Names[0]] = true
+			// Boolean or counting flag
+			if spec.IsCounter {
+				if err := countOccurrence(spec, flags, word); err != nil {
+					return nil, nil, err
+				}
+			} else {
+				value, err := boolWordValue(word)
+				if err != nil {
+					return nil, nil, err
+				}
+				flags[spec.Names[0]] = value
+			}
 			i++
+		} else if spec.OptionalArg && !word.inline {
+			value, ok := cmd.optionalValue(spec, args, i, flags)
+			if !ok {
+				value, width = spec.BareValue, 1
+			}
+			flags[spec.Names[0]] = value
+			i += width
 		} else {
 			// Flag with arguments
-			if i+spec.ArgCount >= len(args) {
-				return nil, nil, fmt.Errorf("flag %s requires %d argument(s)", spec.Names[0], spec.ArgCount)
+			if spec.ArgCount > len(values) {
+				return nil, nil, ParseError{
+					Flag:    arg,
+					Message: fmt.Sprintf("requires %d argument(s)", spec.ArgCount),
+				}
 			}
 
 			// Parse the arguments
-			if spec.ArgCount == 1 {
+			if spec.isDependent() {
+				// Left raw for the subcommand's parse, which sees every
+				// flag it depends on
+				flags[spec.Names[0]] = values[0]
+			} else if spec.ArgCount == 1 {
 				// Single argument
-				value, err := parseArgValue(args[i+1], spec.ArgTypes[0], spec, flags)
+				value, err := parseArgValue(values[0], spec, 0, flags)
 				if err != nil {
-					return nil, nil, fmt.Errorf("flag %s: %v", spec.Names[0], err)
+					return nil, nil, ParseError{
+						Flag:    arg,
+						Message: fmt.Sprintf("invalid argument: %v", err),
+					}
 				}
 				flags[spec.Names[0]] = value
 			} else {
 				// Multi-argument flag
 				argMap := make(map[string]interface{})
 				for j := 0; j < spec.ArgCount; j++ {
-					value, err := parseArgValue(args[i+1+j], spec.ArgTypes[j], spec, flags)
+					value, err := parseArgValue(values[j], spec, j, flags)
 					if err != nil {
-						return nil, nil, fmt.Errorf("flag %s argument %d: %v", spec.Names[0], j, err)
+						return nil, nil, ParseError{
+							Flag:    arg,
+							Message: fmt.Sprintf("invalid argument %d: %v", j, err),
+						}
 					}
 					argMap[spec.ArgNames[j]] = value
 				}
 				flags[spec.Names[0]] = argMap
 			}
 
-			i += 1 + spec.ArgCount
+			i += width
 		}
 	}
 
@@ -743,30 +1204,57 @@ func (cmd *Command) findRootGlobalFlag(name string) *FlagSpec {
 }
 
 // parseSubcommand parses a subcommand with its flags and clauses
-func (cmd *Command) parseSubcommand(subcmd *Subcommand, rootGlobals map[string]interface{}, args []string) (*Context, error) {
+func (cmd *Command) parseSubcommand(subcmd *Subcommand, path []string, rootGlobals map[string]interface{}, args []string, prompter Prompter, out io.Writer) (*Context, error) {
 	// Create a temporary command with both root global flags and subcommand's flags
 	// This allows root globals to be specified after the subcommand name
+	rootFlags := cmd.rootGlobalFlags()
 	tempCmd := &Command{
-		name:          subcmd.Name,
-		flags:         append(cmd.rootGlobalFlags(), subcmd.Flags...),
-		separators:    subcmd.Separators,
-		prefixHandler: cmd.prefixHandler,
+		name:            subcmd.Name,
+		flags:           append(rootFlags, subcmd.Flags...),
+		separators:      subcmd.Separators,
+		prefixHandler:   cmd.prefixHandler,
+		syntax:          cmd.syntax,
+		expr:            cmd.expr,
+		configFiles:     cmd.configFiles,
+		configPath:      path,
+		configTree:      cmd.subcommands,
+		configRootFlags: rootFlags,
+		parentGlobals:   rootGlobals,
+	}
+	if override, ok := rootGlobals[configFlagName].(string); ok {
+		tempCmd.configOverride = override
 	}
 
-	// Parse using standard parser
-	ctx, err := tempCmd.Parse(args)
+	// Parse using standard parser; dispatch validates
+	ctx, err := tempCmd.parse(args)
 	if err != nil {
 		return nil, err
 	}
 
-	// Merge root globals into context
+	// Merge root globals into context. Occurrences of a Count flag on both
+	// sides of the subcommand name add up.
 	for k, v := range rootGlobals {
+		spec := cmd.findRootGlobalFlag(k)
+		if spec != nil && spec.isDependent() {
+			continue // Parsed by tempCmd.parse
+		}
+		if spec != nil && spec.IsCounter && ctx.sources[k] == SourceArgs {
+			v = v.(int) + ctx.GlobalFlags[k].(int)
+		}
 		ctx.GlobalFlags[k] = v
+		ctx.sources[k] = SourceArgs
+	}
+	if err := cmd.readSecretFiles(ctx, cmd.rootGlobalFlags()); err != nil {
+		return nil, err
 	}
 
 	// Set the actual command reference
 	ctx.Command = cmd
 
+	if err := cmd.promptForMissing(subcmd.Flags, ctx, prompter, out); err != nil {
+		return nil, err
+	}
+
 	return ctx, nil
 }
 
@@ -774,7 +1262,9 @@ func (cmd *Command) parseSubcommand(subcmd *Subcommand, rootGlobals map[string]i
 func (cmd *Command) validateSubcommand(subcmd *Subcommand, ctx *Context) error {
 	// Create temporary command for validation
 	tempCmd := &Command{
-		flags: subcmd.Flags,
+		flags:       subcmd.Flags,
+		groups:      append(cmd.globalGroups(), subcmd.Groups...),
+		clauseRules: subcmd.ClauseRules,
 	}
 
 	return tempCmd.validate(ctx)
@@ -0,0 +1,264 @@
+package completionflags
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Prompter reads answers from the user of an interactive session. Execute
+// uses one reading the terminal on os.Stdin; embedded shells supply their
+// line editor with Context.SetPrompter.
+type Prompter interface {
+	// ReadLine shows prompt and returns the line typed, without the newline
+	ReadLine(prompt string) (string, error)
+
+	// ReadPassword is ReadLine with the typed characters hidden
+	ReadPassword(prompt string) (string, error)
+}
+
+// PromptForMissing makes dispatch ask for required flags that weren't
+// given, instead of failing with "required flag not provided". Each flag
+// is asked for on the session's Stdin and Stdout using its description and
+// type. Fixed options are offered as a numbered menu and Secret flags
+// aren't echoed. Prompting is off when stdin isn't a terminal, so scripts
+// still get the usual error.
+func (cb *CommandBuilder) PromptForMissing() *CommandBuilder {
+	cb.cmd.promptMissing = true
+	return cb
+}
+
+// promptForMissing asks for the missing required flags among flags when
+// PromptForMissing is on, the session is interactive and -yes wasn't given
+func (cmd *Command) promptForMissing(flags []*FlagSpec, ctx *Context, p Prompter, out io.Writer) error {
+	if !cmd.promptMissing || p == nil || cmd.assumeYes(ctx) {
+		return nil
+	}
+	return promptMissing(flags, ctx, p, out)
+}
+
+// interactivePrompter returns the Prompter set with SetPrompter, or one
+// reading Stdin when it is a terminal, or nil when the session can't be
+// prompted
+func (c *Context) interactivePrompter() Prompter {
+	if c.prompter != nil {
+		return c.prompter
+	}
+	if f, ok := c.Stdin().(*os.File); ok && isTerminal(f.Fd()) {
+		return &terminalPrompter{in: f, reader: bufio.NewReader(f), out: c.Stdout()}
+	}
+	return nil
+}
+
+// terminalPrompter prompts on a local terminal
+type terminalPrompter struct {
+	in     *os.File
+	reader *bufio.Reader
+	out    io.Writer
+}
+
+func (p *terminalPrompter) ReadLine(prompt string) (string, error) {
+	fmt.Fprint(p.out, prompt)
+	line, err := p.reader.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func (p *terminalPrompter) ReadPassword(prompt string) (string, error) {
+	var line string
+	err := withoutEcho(p.in.Fd(), func() error {
+		var err error
+		line, err = p.ReadLine(prompt)
+		return err
+	})
+	fmt.Fprintln(p.out)
+	return line, err
+}
+
+// promptMissing asks for each required flag in flags that has no value,
+// storing the answers in ctx. Global flags go to GlobalFlags and local ones
+// to the first clause. Invalid answers are reported and asked again; end
+// of input leaves the flag unset for validation to report.
+func promptMissing(flags []*FlagSpec, ctx *Context, p Prompter, out io.Writer) error {
+	for _, spec := range flags {
+		if !spec.Required || spec.IsCounter || flagPresent(ctx, spec) {
+			continue
+		}
+		target := ctx.GlobalFlags
+		if spec.Scope == ScopeLocal && len(ctx.Clauses) > 0 {
+			target = ctx.Clauses[0].Flags
+		}
+
+		value, err := promptFlagValue(spec, ctx, p, out)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		storeFlagValue(spec, target, value)
+	}
+	ctx.markSources(SourcePrompt)
+	return nil
+}
+
+// flagPresent reports whether spec has a value in ctx, in any clause for a
+// local flag
+func flagPresent(ctx *Context, spec *FlagSpec) bool {
+	name := spec.Names[0]
+	if spec.Scope == ScopeGlobal {
+		_, ok := ctx.GlobalFlags[name]
+		return ok
+	}
+	for _, clause := range ctx.Clauses {
+		if _, ok := clause.Flags[name]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// promptFlagValue asks for every argument of spec until the answers parse
+// and pass the flag's validator
+func promptFlagValue(spec *FlagSpec, ctx *Context, p Prompter, out io.Writer) (interface{}, error) {
+	for {
+		value, err := promptFlagArgs(spec, ctx, p, out)
+		if err != nil {
+			return nil, err
+		}
+		if spec.Validator != nil {
+			if err := spec.Validator(value); err != nil {
+				fmt.Fprintf(out, "Invalid value for %s: %v\n", spec.Names[0], err)
+				continue
+			}
+		}
+		return value, nil
+	}
+}
+
+// promptFlagArgs asks for spec's arguments once each: a yes/no answer for
+// a Bool flag, one value, or a map of ArgNames to values
+func promptFlagArgs(spec *FlagSpec, ctx *Context, p Prompter, out io.Writer) (interface{}, error) {
+	if spec.ArgCount == 0 {
+		for {
+			answer, err := p.ReadLine(promptLabel(spec, -1) + " [y/n]: ")
+			if err != nil {
+				return nil, err
+			}
+			if b, ok := parseYesNo(answer); ok {
+				return b, nil
+			}
+			fmt.Fprintln(out, "Please answer y or n")
+		}
+	}
+
+	values := make(map[string]interface{}, spec.ArgCount)
+	for i := 0; i < spec.ArgCount; i++ {
+		value, err := promptArg(spec, i, ctx, p, out)
+		if err != nil {
+			return nil, err
+		}
+		if spec.ArgCount == 1 {
+			return value, nil
+		}
+		values[spec.ArgNames[i]] = value
+	}
+	return values, nil
+}
+
+// promptArg asks for argument index of spec until the answer parses
+func promptArg(spec *FlagSpec, index int, ctx *Context, p Prompter, out io.Writer) (interface{}, error) {
+	label := promptLabel(spec, index)
+	options := staticOptions(spec, index)
+	for {
+		var answer string
+		var err error
+		switch {
+		case spec.Secret:
+			answer, err = p.ReadPassword(label + ": ")
+		case len(options) > 0:
+			answer, err = promptMenu(label, options, p, out)
+		default:
+			answer, err = p.ReadLine(label + ": ")
+		}
+		if err != nil {
+			return nil, err
+		}
+		if strings.TrimSpace(answer) == "" {
+			continue
+		}
+
+		value, err := parseArgValue(answer, spec, index, ctx.GlobalFlags)
+		if err != nil {
+			fmt.Fprintf(out, "Invalid value for %s: %v\n", spec.Names[0], err)
+			continue
+		}
+		return value, nil
+	}
+}
+
+// promptMenu offers options as a numbered list, accepting a number or the
+// option itself
+func promptMenu(label string, options []string, p Prompter, out io.Writer) (string, error) {
+	fmt.Fprintln(out, label+":")
+	for i, option := range options {
+		fmt.Fprintf(out, "  %d) %s\n", i+1, option)
+	}
+	answer, err := p.ReadLine(fmt.Sprintf("Choose 1-%d: ", len(options)))
+	if err != nil {
+		return "", err
+	}
+	answer = strings.TrimSpace(answer)
+	if n, err := strconv.Atoi(answer); err == nil && n >= 1 && n <= len(options) {
+		return options[n-1], nil
+	}
+	return answer, nil
+}
+
+// promptLabel describes argument index of spec (-1 for a Bool flag) as
+// "Description (-flag NAME, type)"
+func promptLabel(spec *FlagSpec, index int) string {
+	what := spec.Names[0]
+	if index >= 0 {
+		if spec.ArgCount > 1 && index < len(spec.ArgNames) {
+			what += " " + spec.ArgNames[index]
+		}
+		if len(staticOptions(spec, index)) == 0 {
+			what += ", " + argLabel(spec, index)
+		}
+	}
+	if spec.Description == "" {
+		return what
+	}
+	return fmt.Sprintf("%s (%s)", spec.Description, what)
+}
+
+// staticOptions returns the fixed values argument index of spec completes
+// to, if it has a StaticCompleter
+func staticOptions(spec *FlagSpec, index int) []string {
+	if index < 0 || index >= len(spec.ArgCompleters) {
+		return nil
+	}
+	if sc, ok := spec.ArgCompleters[index].(*StaticCompleter); ok {
+		return sc.Options
+	}
+	return nil
+}
+
+// parseYesNo accepts y, yes, n, no and the strconv.ParseBool spellings
+func parseYesNo(answer string) (bool, bool) {
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true, true
+	case "n", "no":
+		return false, true
+	}
+	b, err := strconv.ParseBool(strings.TrimSpace(answer))
+	return b, err == nil
+}
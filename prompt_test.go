@@ -0,0 +1,153 @@
+package completionflags
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// scriptedPrompter answers prompts from a list, recording what was asked
+type scriptedPrompter struct {
+	answers []string
+	asked   []string
+	secret  []bool
+}
+
+func (p *scriptedPrompter) next(prompt string, secret bool) (string, error) {
+	p.asked = append(p.asked, prompt)
+	p.secret = append(p.secret, secret)
+	if len(p.answers) == 0 {
+		return "", io.EOF
+	}
+	answer := p.answers[0]
+	p.answers = p.answers[1:]
+	return answer, nil
+}
+
+func (p *scriptedPrompter) ReadLine(prompt string) (string, error) { return p.next(prompt, false) }
+
+func (p *scriptedPrompter) ReadPassword(prompt string) (string, error) { return p.next(prompt, true) }
+
+func TestPromptForMissing(t *testing.T) {
+	var ctx *Context
+	cmd := NewCommand("tool").
+		PromptForMissing().
+		Subcommand("deploy").
+		Flag("-env").String().Options("dev", "staging", "prod").Required().Help("Target environment").Done().
+		Flag("-replicas").Int().Required().Done().
+		Flag("-token").String().Secret().Required().Global().Help("API token").Done().
+		Flag("-note").String().Done().
+		Handler(func(c *Context) error {
+			ctx = c
+			return nil
+		}).
+		Done().
+		Build()
+
+	var out bytes.Buffer
+	p := &scriptedPrompter{answers: []string{"3", "many", "4", "s3cret"}}
+	base := (&Context{}).SetStdout(&out).SetPrompter(p)
+	if err := cmd.ExecuteWith([]string{"deploy"}, base); err != nil {
+		t.Fatalf("ExecuteWith: %v", err)
+	}
+
+	clause := ctx.Clauses[0]
+	if clause.Flags["-env"] != "prod" || clause.Flags["-replicas"] != 4 || ctx.GlobalFlags["-token"] != "s3cret" {
+		t.Errorf("values: %v %v", clause.Flags, ctx.GlobalFlags)
+	}
+	if clause.Source("-env") != SourcePrompt || ctx.Source("-token") != SourcePrompt {
+		t.Errorf("sources: %v %v", clause.Source("-env"), ctx.Source("-token"))
+	}
+	if _, ok := clause.Flags["-note"]; ok {
+		t.Error("optional flag was prompted for")
+	}
+
+	wantAsked := []string{
+		"Choose 1-3: ",
+		"-replicas, integer: ",
+		"-replicas, integer: ",
+		"API token (-token, string): ",
+	}
+	if !reflect.DeepEqual(p.asked, wantAsked) {
+		t.Errorf("asked:\n got %q\nwant %q", p.asked, wantAsked)
+	}
+	if !reflect.DeepEqual(p.secret, []bool{false, false, false, true}) {
+		t.Errorf("secret = %v", p.secret)
+	}
+	for _, want := range []string{"Target environment (-env):\n  1) dev\n  2) staging\n  3) prod\n", "Invalid value for -replicas"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output missing %q:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestPromptForMissing_GivenFlagsNotAsked(t *testing.T) {
+	var ctx *Context
+	cmd := NewCommand("tool").
+		PromptForMissing().
+		Subcommand("deploy").
+		Flag("-env").String().Required().Done().
+		Flag("-replicas").Int().Required().Done().
+		Flag("-token").String().Required().Global().Done().
+		Handler(func(c *Context) error {
+			ctx = c
+			return nil
+		}).
+		Done().
+		Build()
+
+	p := &scriptedPrompter{answers: []string{"abc"}}
+	base := (&Context{}).SetStdout(io.Discard).SetPrompter(p)
+	if err := cmd.ExecuteWith([]string{"deploy", "-env", "dev", "-replicas", "1"}, base); err != nil {
+		t.Fatal(err)
+	}
+	if len(p.asked) != 1 || ctx.Clauses[0].Source("-env") != SourceArgs {
+		t.Errorf("asked %q", p.asked)
+	}
+}
+
+func TestPromptForMissing_EndOfInput(t *testing.T) {
+	cmd := NewCommand("tool").
+		PromptForMissing().
+		Subcommand("deploy").
+		Flag("-env").String().Required().Done().
+		Handler(func(ctx *Context) error { return nil }).
+		Done().
+		Build()
+
+	base := (&Context{}).SetStdout(io.Discard).SetPrompter(&scriptedPrompter{})
+	err := cmd.ExecuteWith([]string{"deploy"}, base)
+	if err == nil || !strings.Contains(err.Error(), "required flag not provided") {
+		t.Errorf("error = %v", err)
+	}
+}
+
+func TestPromptForMissing_NotInteractive(t *testing.T) {
+	cmd := NewCommand("tool").
+		PromptForMissing().
+		Flag("-name").String().Required().Global().Done().
+		Handler(func(ctx *Context) error { return nil }).
+		Build()
+
+	// No prompter and a Stdin that isn't a terminal: the usual error
+	base := (&Context{}).SetStdin(strings.NewReader("x\n")).SetStdout(io.Discard)
+	err := cmd.ExecuteWith(nil, base)
+	if err == nil || !strings.Contains(err.Error(), "required flag not provided") {
+		t.Errorf("error = %v", err)
+	}
+}
+
+func TestPromptForMissing_Off(t *testing.T) {
+	cmd := NewCommand("tool").
+		Flag("-name").String().Required().Global().Done().
+		Handler(func(ctx *Context) error { return nil }).
+		Build()
+
+	p := &scriptedPrompter{answers: []string{"x"}}
+	err := cmd.ExecuteWith(nil, (&Context{}).SetPrompter(p))
+	if err == nil || len(p.asked) != 0 {
+		t.Errorf("error = %v, asked %q", err, p.asked)
+	}
+}
@@ -0,0 +1,221 @@
+package completionflags
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// maxResponseFileDepth bounds @file nesting as a backstop to cycle detection
+const maxResponseFileDepth = 32
+
+// expandResponseFiles replaces every @path argument with the words in the
+// file when ResponseFiles is on. Each line is split using the quoting rules
+// of shell.Tokenize; blank lines and lines starting with "#" are skipped.
+// An @path inside a file is expanded in turn, relative to that file's
+// directory. "@@word" passes "@word" through, and nothing after "--" is
+// expanded, whether it is given on the command line or read from a file.
+// widths holds the number of words each argument became.
+func (cmd *Command) expandResponseFiles(args []string) (expanded []string, widths []int, err error) {
+	widths = make([]int, len(args))
+	for i := range widths {
+		widths[i] = 1
+	}
+	if !cmd.responseFiles {
+		return args, widths, nil
+	}
+	for i, arg := range args {
+		if arg == "--" {
+			return append(expanded, args[i:]...), widths, nil
+		}
+		words, stopped, err := expandResponseArgs([]string{arg}, "", nil)
+		if err != nil {
+			return nil, nil, err
+		}
+		expanded = append(expanded, words...)
+		widths[i] = len(words)
+		if stopped {
+			return append(expanded, args[i+1:]...), widths, nil
+		}
+	}
+	return expanded, widths, nil
+}
+
+// unexpandedTail returns the arguments of raw that expandResponseFiles
+// turned into the last n words of expanded, with their @path words as given.
+// When a file's words straddle the start of the tail, just the words of it
+// inside the tail are kept.
+func unexpandedTail(raw, expanded []string, widths []int, n int) []string {
+	skip := len(expanded) - n
+	for i, width := range widths {
+		if skip == 0 {
+			return raw[i:]
+		}
+		if skip < width {
+			tail := append([]string{}, expanded[len(expanded)-n:][:width-skip]...)
+			return append(tail, raw[i+1:]...)
+		}
+		skip -= width
+	}
+	return []string{}
+}
+
+// expandResponseArgs expands args read from the file at the top of stack
+// (or from the command line when stack is empty); dir resolves relative
+// paths. stopped reports that a "--" was met, here or in a nested file, so
+// nothing after it is to be expanded either.
+func expandResponseArgs(args []string, dir string, stack []string) (out []string, stopped bool, err error) {
+	out = make([]string, 0, len(args))
+	for i, arg := range args {
+		switch {
+		case arg == "--":
+			return append(out, args[i:]...), true, nil
+		case strings.HasPrefix(arg, "@@"):
+			out = append(out, arg[1:])
+		case len(arg) > 1 && arg[0] == '@':
+			words, stopped, err := readResponseFile(arg[1:], dir, stack)
+			if err != nil {
+				return nil, false, err
+			}
+			out = append(out, words...)
+			if stopped {
+				return append(out, args[i+1:]...), true, nil
+			}
+		default:
+			out = append(out, arg)
+		}
+	}
+	return out, false, nil
+}
+
+// readResponseFile returns the expanded words of one response file, and
+// whether they hold a "--"
+func readResponseFile(name, dir string, stack []string) ([]string, bool, error) {
+	path := name
+	if dir != "" && !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, false, ParseError{Message: fmt.Sprintf("response file @%s: %v", name, err)}
+	}
+	for i, seen := range stack {
+		if seen == abs {
+			cycle := append(append([]string{}, stack[i:]...), abs)
+			return nil, false, ParseError{Message: "response file cycle: " + strings.Join(cycle, " -> ")}
+		}
+	}
+	if len(stack) >= maxResponseFileDepth {
+		return nil, false, ParseError{Message: fmt.Sprintf("response file @%s: nested too deeply", name)}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false, ParseError{Message: fmt.Sprintf("response file @%s: %v", name, err)}
+	}
+	var words []string
+	for n, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(strings.TrimSuffix(line, "\r"))
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		tokens, err := tokenizeLine(line)
+		if err != nil {
+			return nil, false, ParseError{Message: fmt.Sprintf("response file %s:%d: %v", path, n+1, err)}
+		}
+		words = append(words, tokens...)
+	}
+	return expandResponseArgs(words, filepath.Dir(abs), append(stack, abs))
+}
+
+// tokenizeLine splits a line into words the way shell.Tokenize does:
+// whitespace separates words, single quotes are literal, double quotes
+// honour \" \\ \$ and \`, and a backslash outside quotes escapes the next
+// character.
+func tokenizeLine(line string) ([]string, error) {
+	var tokens []string
+	var cur strings.Builder
+	inToken := false
+
+	flush := func() {
+		if inToken {
+			tokens = append(tokens, cur.String())
+			cur.Reset()
+			inToken = false
+		}
+	}
+
+	i := 0
+	for i < len(line) {
+		c := line[i]
+		switch {
+		case c == ' ' || c == '\t':
+			flush()
+			i++
+		case c == '\'':
+			end := strings.IndexByte(line[i+1:], '\'')
+			if end == -1 {
+				return nil, fmt.Errorf("unterminated single quote")
+			}
+			cur.WriteString(line[i+1 : i+1+end])
+			inToken = true
+			i += 1 + end + 1
+		case c == '"':
+			j := i + 1
+			for j < len(line) && line[j] != '"' {
+				if line[j] == '\\' && j+1 < len(line) {
+					next := line[j+1]
+					if next == '"' || next == '\\' || next == '$' || next == '`' {
+						cur.WriteByte(next)
+						j += 2
+						continue
+					}
+				}
+				cur.WriteByte(line[j])
+				j++
+			}
+			if j >= len(line) {
+				return nil, fmt.Errorf("unterminated double quote")
+			}
+			inToken = true
+			i = j + 1
+		case c == '\\':
+			if i+1 < len(line) {
+				cur.WriteByte(line[i+1])
+				i += 2
+			} else {
+				cur.WriteByte('\\')
+				i++
+			}
+			inToken = true
+		default:
+			cur.WriteByte(c)
+			inToken = true
+			i++
+		}
+	}
+	flush()
+	return tokens, nil
+}
+
+// completeResponseFile completes the file name in an "@path" word
+func (cmd *Command) completeResponseFile(args []string, pos int) ([]string, bool) {
+	if !cmd.responseFiles || pos < 1 || pos > len(args) {
+		return nil, false
+	}
+	partial := args[pos-1]
+	if !strings.HasPrefix(partial, "@") || strings.HasPrefix(partial, "@@") {
+		return nil, false
+	}
+	for _, arg := range args[:pos-1] {
+		if arg == "--" {
+			return nil, false
+		}
+	}
+	matches, _ := (&FileCompleter{}).Complete(CompletionContext{Partial: partial[1:]})
+	for i := range matches {
+		matches[i] = "@" + matches[i]
+	}
+	return matches, true
+}
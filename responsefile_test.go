@@ -0,0 +1,210 @@
+package completionflags
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func writeResponseFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestResponseFiles_Expand(t *testing.T) {
+	dir := t.TempDir()
+	writeResponseFile(t, dir, "more.txt", "-filter name eq 'Ada Lovelace'\n")
+	filters := writeResponseFile(t, dir, "filters.txt", `# active admins
+-filter status eq active
+-filter role eq admin
+
++
+@more.txt
+`)
+
+	var ctx *Context
+	cmd := NewCommand("tool").
+		ResponseFiles().
+		Flag("-limit").Int().Global().Done().
+		Flag("-filter").
+		Arg("FIELD").Done().
+		Arg("OP").Done().
+		Arg("VALUE").Done().
+		Accumulate().
+		Local().
+		Done().
+		Handler(func(c *Context) error {
+			ctx = c
+			return nil
+		}).
+		Build()
+
+	args := []string{"-limit", "5", "@" + filters}
+	if err := cmd.Execute(args); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if len(ctx.Clauses) != 2 {
+		t.Fatalf("got %d clauses, want 2", len(ctx.Clauses))
+	}
+	if got := ctx.Clauses[0].Flags["-filter"]; len(got.([]interface{})) != 2 {
+		t.Errorf("first clause filters = %v", got)
+	}
+	want := []interface{}{map[string]interface{}{"FIELD": "name", "OP": "eq", "VALUE": "Ada Lovelace"}}
+	if got := ctx.Clauses[1].Flags["-filter"]; !reflect.DeepEqual(got, want) {
+		t.Errorf("nested file filters = %v, want %v", got, want)
+	}
+	if !reflect.DeepEqual(ctx.RawArgs, args) {
+		t.Errorf("RawArgs = %v, want %v", ctx.RawArgs, args)
+	}
+}
+
+func TestResponseFiles_SubcommandRawArgs(t *testing.T) {
+	dir := t.TempDir()
+	flags := writeResponseFile(t, dir, "flags.txt", "-x 1\n")
+	straddle := writeResponseFile(t, dir, "straddle.txt", "run -x 2\n")
+
+	var raw []string
+	record := func(c *Context) error {
+		raw = c.RawArgs
+		return nil
+	}
+	for _, responseFiles := range []bool{false, true} {
+		cb := NewCommand("tool")
+		if responseFiles {
+			cb.ResponseFiles()
+		}
+		cmd := cb.
+			Flag("-v").Bool().Global().Done().
+			Subcommand("run").
+			Flag("-x").Int().Done().
+			Flag("-y").Bool().Done().
+			Handler(record).
+			Done().
+			Build()
+
+		// The words after the subcommand path, as given
+		if err := cmd.Execute([]string{"-v", "run", "-x", "1"}); err != nil {
+			t.Fatalf("Execute failed: %v", err)
+		}
+		if want := []string{"-x", "1"}; !reflect.DeepEqual(raw, want) {
+			t.Errorf("RawArgs = %v, want %v", raw, want)
+		}
+		if !responseFiles {
+			continue
+		}
+
+		if err := cmd.Execute([]string{"-v", "run", "@" + flags, "-y"}); err != nil {
+			t.Fatalf("Execute failed: %v", err)
+		}
+		if want := []string{"@" + flags, "-y"}; !reflect.DeepEqual(raw, want) {
+			t.Errorf("RawArgs = %v, want %v", raw, want)
+		}
+
+		// A file holding the subcommand name contributes the words after it
+		if err := cmd.Execute([]string{"@" + straddle, "-y"}); err != nil {
+			t.Fatalf("Execute failed: %v", err)
+		}
+		if want := []string{"-x", "2", "-y"}; !reflect.DeepEqual(raw, want) {
+			t.Errorf("RawArgs = %v, want %v", raw, want)
+		}
+	}
+}
+
+func TestResponseFiles_DashDashInFile(t *testing.T) {
+	dir := t.TempDir()
+	stop := writeResponseFile(t, dir, "stop.txt", "-limit 5\n--\n@inside\n")
+	outer := writeResponseFile(t, dir, "outer.txt", "@stop.txt\n@after\n")
+
+	var ctx *Context
+	cmd := NewCommand("tool").
+		ResponseFiles().
+		Flag("-limit").Int().Global().Done().
+		Handler(func(c *Context) error {
+			ctx = c
+			return nil
+		}).
+		Build()
+
+	// A "--" read from a file ends expansion for the rest of the file, the
+	// files around it and the command line
+	tests := []struct {
+		args []string
+		want []string
+	}{
+		{[]string{"@" + stop, "@x"}, []string{"@inside", "@x"}},
+		{[]string{"@" + outer, "@x"}, []string{"@inside", "@after", "@x"}},
+	}
+	for _, tt := range tests {
+		if err := cmd.Execute(tt.args); err != nil {
+			t.Fatalf("%v: Execute failed: %v", tt.args, err)
+		}
+		if got := ctx.GetInt("-limit", 0); got != 5 {
+			t.Errorf("%v: -limit = %d, want 5", tt.args, got)
+		}
+		if !reflect.DeepEqual(ctx.RemainingArgs, tt.want) {
+			t.Errorf("%v: RemainingArgs = %v, want %v", tt.args, ctx.RemainingArgs, tt.want)
+		}
+		if !reflect.DeepEqual(ctx.RawArgs, tt.args) {
+			t.Errorf("%v: RawArgs = %v, want %v", tt.args, ctx.RawArgs, tt.args)
+		}
+	}
+}
+
+func TestResponseFiles_Errors(t *testing.T) {
+	dir := t.TempDir()
+	a := writeResponseFile(t, dir, "a.txt", "@b.txt\n")
+	writeResponseFile(t, dir, "b.txt", "@a.txt\n")
+	quote := writeResponseFile(t, dir, "quote.txt", "-limit '5\n")
+
+	cmd := NewCommand("tool").
+		ResponseFiles().
+		Flag("-limit").Int().Global().Done().
+		Handler(func(ctx *Context) error { return nil }).
+		Build()
+
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"@" + a}, "response file cycle"},
+		{[]string{"@" + quote}, "quote.txt:1: unterminated single quote"},
+		{[]string{"@" + filepath.Join(dir, "missing.txt")}, "response file @"},
+	}
+	for _, tt := range tests {
+		err := cmd.Execute(tt.args)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%v: got %v, want error containing %q", tt.args, err, tt.want)
+		}
+	}
+
+	// "@@" escapes, and nothing is expanded after "--" or unless enabled
+	args, _, err := cmd.expandResponseFiles([]string{"@@literal", "--", "@" + a})
+	if err != nil || !reflect.DeepEqual(args, []string{"@literal", "--", "@" + a}) {
+		t.Errorf("escapes: got %v, %v", args, err)
+	}
+	plain := NewCommand("tool").Handler(func(*Context) error { return nil }).Build()
+	if args, _, _ := plain.expandResponseFiles([]string{"@" + a}); args[0] != "@"+a {
+		t.Errorf("expanded without ResponseFiles: %v", args)
+	}
+}
+
+func TestResponseFiles_Completion(t *testing.T) {
+	dir := t.TempDir()
+	writeResponseFile(t, dir, "filters.txt", "")
+
+	cmd := NewCommand("tool").
+		ResponseFiles().
+		Handler(func(ctx *Context) error { return nil }).
+		Build()
+
+	matches, _ := cmd.Complete([]string{"@" + dir + "/fil"}, 1)
+	if !reflect.DeepEqual(matches, []string{"@" + dir + "/filters.txt"}) {
+		t.Errorf("got %v", matches)
+	}
+}
@@ -0,0 +1,114 @@
+package completionflags
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// byteUnits maps lower-cased size suffixes to multipliers. Bare letters and
+// the "iB" forms are powers of 1024; the "B" forms are powers of 1000.
+var byteUnits = map[string]float64{
+	"":    1,
+	"b":   1,
+	"k":   1 << 10,
+	"kib": 1 << 10,
+	"kb":  1e3,
+	"m":   1 << 20,
+	"mib": 1 << 20,
+	"mb":  1e6,
+	"g":   1 << 30,
+	"gib": 1 << 30,
+	"gb":  1e9,
+	"t":   1 << 40,
+	"tib": 1 << 40,
+	"tb":  1e12,
+	"p":   1 << 50,
+	"pib": 1 << 50,
+	"pb":  1e15,
+}
+
+// parseBytes parses a byte count such as "512MiB", "1.5G" or "4096"
+func parseBytes(value string) (int64, error) {
+	s := strings.TrimSpace(value)
+	i := 0
+	for i < len(s) && (s[i] >= '0' && s[i] <= '9' || s[i] == '.') {
+		i++
+	}
+	if i == 0 {
+		return 0, fmt.Errorf("invalid size %q", value)
+	}
+
+	n, err := strconv.ParseFloat(s[:i], 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size %q", value)
+	}
+	unit, ok := byteUnits[strings.ToLower(strings.TrimSpace(s[i:]))]
+	if !ok {
+		return 0, fmt.Errorf("invalid size %q: unknown unit %q", value, strings.TrimSpace(s[i:]))
+	}
+	size := n * unit
+	if size >= math.MaxInt64 {
+		return 0, fmt.Errorf("size %q is too large", value)
+	}
+	return int64(size), nil
+}
+
+// parseIP parses an IPv4 or IPv6 address
+func parseIP(value string) (net.IP, error) {
+	ip := net.ParseIP(value)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid IP address %q", value)
+	}
+	return ip, nil
+}
+
+// parseURL parses an absolute URL
+func parseURL(value string) (*url.URL, error) {
+	u, err := url.Parse(value)
+	if err != nil {
+		return nil, err
+	}
+	if !u.IsAbs() {
+		return nil, fmt.Errorf("URL %q has no scheme", value)
+	}
+	return u, nil
+}
+
+// enumValues returns the allowed values of argument index
+func (spec *FlagSpec) enumValues(index int) []string {
+	if index < 0 || index >= len(spec.EnumValues) {
+		return nil
+	}
+	return spec.EnumValues[index]
+}
+
+// parseEnum accepts value only if it is one of allowed
+func parseEnum(value string, allowed []string) (string, error) {
+	for _, a := range allowed {
+		if value == a {
+			return value, nil
+		}
+	}
+	hint := didYouMean(suggest(value, allowed), strconv.Quote)
+	return "", fmt.Errorf("%q is not one of %s%s", value, strings.Join(allowed, ", "), hint)
+}
+
+// setEnum restricts argument index of spec to values and, unless the
+// argument already has a completer, offers them for completion
+func setEnum(spec *FlagSpec, index int, values []string) {
+	if index < 0 || index >= len(spec.ArgCompleters) {
+		return
+	}
+	for len(spec.EnumValues) <= index {
+		spec.EnumValues = append(spec.EnumValues, nil)
+	}
+	spec.EnumValues[index] = values
+	setArgType(spec, index, ArgEnum)
+	if nc, ok := spec.ArgCompleters[index].(NoCompleter); ok && nc.Hint == "" {
+		spec.ArgCompleters[index] = &StaticCompleter{Options: values}
+	}
+}
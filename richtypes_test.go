@@ -0,0 +1,155 @@
+package completionflags
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseBytes(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int64
+	}{
+		{"4096", 4096},
+		{"512MiB", 512 << 20},
+		{"1.5G", 3 << 29},
+		{"2kb", 2000},
+		{"10 B", 10},
+	}
+	for _, tt := range tests {
+		got, err := parseBytes(tt.in)
+		if err != nil || got != tt.want {
+			t.Errorf("parseBytes(%q) = %d, %v; want %d", tt.in, got, err, tt.want)
+		}
+	}
+	for _, bad := range []string{"", "MiB", "12 parsecs", "1.2.3G", "99999P"} {
+		if _, err := parseBytes(bad); err == nil {
+			t.Errorf("parseBytes(%q): expected error", bad)
+		}
+	}
+}
+
+func TestRichTypes_Accessors(t *testing.T) {
+	var ctx *Context
+	cmd := NewCommand("tool").
+		Flag("-mem").Bytes().Global().Done().
+		Flag("-bind").IP().Global().Done().
+		Flag("-allow").CIDR().Global().Done().
+		Flag("-upstream").URL().Global().Done().
+		Flag("-match").Regexp().Global().Done().
+		Flag("-format").Enum("json", "csv", "table").Global().Done().
+		Handler(func(c *Context) error {
+			ctx = c
+			return nil
+		}).
+		Build()
+
+	err := cmd.Execute([]string{
+		"-mem", "1.5G", "-bind", "::1", "-allow", "10.1.2.3/8",
+		"-upstream", "https://example.com/api", "-match", "^a+$", "-format", "csv",
+	})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if got := ctx.GetBytes("-mem", 0); got != 3<<29 {
+		t.Errorf("GetBytes = %d", got)
+	}
+	if got := ctx.GetIP("-bind", nil); got.String() != "::1" {
+		t.Errorf("GetIP = %v", got)
+	}
+	if got := ctx.GetCIDR("-allow", nil); got.String() != "10.0.0.0/8" {
+		t.Errorf("GetCIDR = %v", got)
+	}
+	if got := ctx.GetURL("-upstream", nil); got.Host != "example.com" {
+		t.Errorf("GetURL = %v", got)
+	}
+	if got := ctx.GetRegexp("-match", nil); !got.MatchString("aaa") {
+		t.Errorf("GetRegexp = %v", got)
+	}
+	if got := ctx.GetString("-format", ""); got != "csv" {
+		t.Errorf("GetString(-format) = %q", got)
+	}
+}
+
+func TestRichTypes_Errors(t *testing.T) {
+	cmd := NewCommand("tool").
+		Flag("-bind").IP().Done().
+		Flag("-upstream").URL().Done().
+		Flag("-match").Regexp().Done().
+		Flag("-format").Enum("json", "csv", "table").Done().
+		Handler(func(ctx *Context) error { return nil }).
+		Build()
+
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"-bind", "10.0.0.256"}, "flag -bind: invalid argument"},
+		{[]string{"-upstream", "example.com"}, "has no scheme"},
+		{[]string{"-match", "a("}, "flag -match"},
+		{[]string{"-format", "jsno"}, `is not one of json, csv, table (did you mean "json"?)`},
+	}
+	for _, tt := range tests {
+		err := cmd.Execute(tt.args)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%v: got %v, want error containing %q", tt.args, err, tt.want)
+		}
+	}
+}
+
+func TestRichTypes_EnumCompletionAndHelp(t *testing.T) {
+	cmd := NewCommand("tool").
+		Flag("-format").Enum("json", "csv", "table").Done().
+		Flag("-mem").Bytes().Done().
+		Handler(func(ctx *Context) error { return nil }).
+		Build()
+
+	matches, _ := cmd.Complete([]string{"-format", "c"}, 2)
+	if !reflect.DeepEqual(matches, []string{"csv"}) {
+		t.Errorf("enum completion: got %v", matches)
+	}
+	matches, _ = cmd.Complete([]string{"-mem", ""}, 2)
+	if !reflect.DeepEqual(matches, []string{"<SIZE>"}) {
+		t.Errorf("bytes hint: got %v", matches)
+	}
+
+	text, err := cmd.HelpAt([]string{"-format", ""}, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(text, "one of json, csv, table") {
+		t.Errorf("help-at missing enum values:\n%s", text)
+	}
+}
+
+func TestRichTypes_EnumPerArgument(t *testing.T) {
+	var mode, level string
+	cmd := NewCommand("tool").
+		Flag("-set").
+		Arg("MODE").Enum("fast", "safe").Done().
+		Arg("LEVEL").Enum("low", "high").Done().
+		Global().
+		Done().
+		Handler(func(ctx *Context) error {
+			args := ctx.GlobalFlags["-set"].(map[string]interface{})
+			mode, level = args["MODE"].(string), args["LEVEL"].(string)
+			return nil
+		}).
+		Build()
+
+	if err := cmd.Execute([]string{"-set", "safe", "high"}); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if mode != "safe" || level != "high" {
+		t.Errorf("got mode=%q level=%q", mode, level)
+	}
+	if err := cmd.Execute([]string{"-set", "high", "safe"}); err == nil || !strings.Contains(err.Error(), "not one of fast, safe") {
+		t.Errorf("expected MODE to reject a LEVEL value, got %v", err)
+	}
+
+	args := cmd.Schema().Flags[0].Args
+	if !reflect.DeepEqual(args[0].Values, []string{"fast", "safe"}) || !reflect.DeepEqual(args[1].Values, []string{"low", "high"}) {
+		t.Errorf("schema values: %+v", args)
+	}
+}
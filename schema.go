@@ -0,0 +1,270 @@
+package completionflags
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+)
+
+// SchemaVersion is the version of the layout Schema produces. It changes
+// when a field is renamed, removed or changes meaning; new fields may be
+// added without a change.
+const SchemaVersion = 1
+
+// CommandSchema describes a command tree for tools: IDE plugins, docs
+// sites, compatibility checks. Marshalled to JSON it is the output of the
+// built-in -schema flag.
+type CommandSchema struct {
+	SchemaVersion     int                `json:"schema_version"`
+	Name              string             `json:"name"`
+	Version           string             `json:"version,omitempty"`
+	Description       string             `json:"description,omitempty"`
+	Author            string             `json:"author,omitempty"`
+	Separators        []string           `json:"separators"`
+	ExpressionClauses bool               `json:"expression_clauses,omitempty"`
+	MinClauses        int                `json:"min_clauses,omitempty"`
+	MaxClauses        int                `json:"max_clauses,omitempty"`
+	Examples          []ExampleSchema    `json:"examples,omitempty"`
+	Flags             []FlagSchema       `json:"flags"`
+	Groups            []GroupSchema      `json:"groups,omitempty"`
+	Subcommands       []SubcommandSchema `json:"subcommands,omitempty"`
+}
+
+// SubcommandSchema describes a subcommand and those nested below it
+type SubcommandSchema struct {
+	Name              string             `json:"name"`
+	Aliases           []string           `json:"aliases,omitempty"`
+	Description       string             `json:"description,omitempty"`
+	Author            string             `json:"author,omitempty"`
+	Separators        []string           `json:"separators"`
+	ClauseDescription string             `json:"clause_description,omitempty"`
+	MinClauses        int                `json:"min_clauses,omitempty"`
+	MaxClauses        int                `json:"max_clauses,omitempty"`
+	Timeout           string             `json:"timeout,omitempty"`
+	Runnable          bool               `json:"runnable"` // Has a handler
+	Examples          []ExampleSchema    `json:"examples,omitempty"`
+	Flags             []FlagSchema       `json:"flags"`
+	Groups            []GroupSchema      `json:"groups,omitempty"`
+	Subcommands       []SubcommandSchema `json:"subcommands,omitempty"`
+}
+
+// FlagSchema describes a flag or positional argument
+type FlagSchema struct {
+	Names                []string          `json:"names"`
+	Description          string            `json:"description,omitempty"`
+	Scope                string            `json:"scope"` // "global" or "local"
+	Positional           bool              `json:"positional,omitempty"`
+	Args                 []ArgSchema       `json:"args"`
+	Default              interface{}       `json:"default,omitempty"` // Omitted for Secret flags
+	Required             bool              `json:"required,omitempty"`
+	RequiredIf           []ConditionSchema `json:"required_if,omitempty"`
+	RequiredInEachClause bool              `json:"required_in_each_clause,omitempty"`
+	MaxPerClause         int               `json:"max_per_clause,omitempty"`
+	Hidden               bool              `json:"hidden,omitempty"`
+	Secret               bool              `json:"secret,omitempty"`
+	SecretFileFlag       string            `json:"secret_file_flag,omitempty"` // The -NAME-file flag (SecretFile)
+	Variadic             bool              `json:"variadic,omitempty"`
+	Accumulate           bool              `json:"accumulate,omitempty"`
+	Counter              bool              `json:"counter,omitempty"`
+	OptionalValue        bool              `json:"optional_value,omitempty"`
+	DependsOn            []string          `json:"depends_on,omitempty"`
+	Env                  string            `json:"env,omitempty"`
+	FieldsFromFlag       string            `json:"fields_from_flag,omitempty"`
+	TimeFormats          []string          `json:"time_formats,omitempty"`
+	TimeZone             string            `json:"time_zone,omitempty"`
+	TimeZoneFromFlag     string            `json:"time_zone_from_flag,omitempty"`
+}
+
+// ArgSchema describes one argument of a flag
+type ArgSchema struct {
+	Name            string             `json:"name"`
+	Type            string             `json:"type"`             // ArgType name: "string", "int", "duration", ...
+	Values          []string           `json:"values,omitempty"` // Allowed values (Enum) or fixed completions (Options)
+	FilePattern     string             `json:"file_pattern,omitempty"`
+	FieldValuesFrom *FieldValuesSchema `json:"field_values_from,omitempty"`
+}
+
+// FieldValuesSchema is the link set by ArgBuilder.FieldValuesFrom
+type FieldValuesSchema struct {
+	SourceFlag string `json:"source_flag"`
+	FieldArg   string `json:"field_arg"`
+}
+
+// ConditionSchema is one RequiredIf condition
+type ConditionSchema struct {
+	Flag  string      `json:"flag"`
+	Value interface{} `json:"value"`
+}
+
+// GroupSchema is a constraint across flags
+type GroupSchema struct {
+	Kind  string   `json:"kind"` // "mutually_exclusive", "required_together" or "one_required"
+	Flags []string `json:"flags"`
+}
+
+// ExampleSchema is a usage example
+type ExampleSchema struct {
+	Command     string `json:"command"`
+	Description string `json:"description,omitempty"`
+}
+
+// Schema describes the whole command tree: subcommands, flags with their
+// arguments, defaults and constraints, separators and examples. Handlers,
+// validators and hooks aren't included.
+func (cmd *Command) Schema() CommandSchema {
+	return CommandSchema{
+		SchemaVersion:     SchemaVersion,
+		Name:              cmd.name,
+		Version:           cmd.version,
+		Description:       cmd.description,
+		Author:            cmd.author,
+		Separators:        nonNil(cmd.separators),
+		ExpressionClauses: cmd.expr != nil,
+		MinClauses:        cmd.clauseRules.Min,
+		MaxClauses:        cmd.clauseRules.Max,
+		Examples:          examplesSchema(cmd.examples),
+		Flags:             flagsSchema(cmd.flags),
+		Groups:            groupsSchema(cmd.groups),
+		Subcommands:       subcommandsSchema(cmd.subcommands),
+	}
+}
+
+// writeSchema writes cmd.Schema() as indented JSON
+func (cmd *Command) writeSchema(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(cmd.Schema())
+}
+
+// subcommandsSchema describes subcommands in name order
+func subcommandsSchema(subcommands map[string]*Subcommand) []SubcommandSchema {
+	names := make([]string, 0, len(subcommands))
+	for name := range subcommands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var out []SubcommandSchema
+	for _, name := range names {
+		sub := subcommands[name]
+		s := SubcommandSchema{
+			Name:              sub.Name,
+			Aliases:           sub.Aliases,
+			Description:       sub.Description,
+			Author:            sub.Author,
+			Separators:        nonNil(sub.Separators),
+			ClauseDescription: sub.ClauseDescription,
+			MinClauses:        sub.ClauseRules.Min,
+			MaxClauses:        sub.ClauseRules.Max,
+			Runnable:          sub.Handler != nil,
+			Examples:          examplesSchema(sub.Examples),
+			Flags:             flagsSchema(sub.Flags),
+			Groups:            groupsSchema(sub.Groups),
+			Subcommands:       subcommandsSchema(sub.Subcommands),
+		}
+		if sub.Timeout > 0 {
+			s.Timeout = sub.Timeout.String()
+		}
+		out = append(out, s)
+	}
+	return out
+}
+
+// flagsSchema describes flags in declaration order
+func flagsSchema(flags []*FlagSpec) []FlagSchema {
+	out := []FlagSchema{}
+	for _, spec := range flags {
+		f := FlagSchema{
+			Names:                spec.Names,
+			Description:          spec.Description,
+			Scope:                "local",
+			Positional:           spec.isPositional(),
+			Args:                 argsSchema(spec),
+			Required:             spec.Required,
+			RequiredInEachClause: spec.RequiredInEachClause,
+			MaxPerClause:         spec.MaxPerClause,
+			Hidden:               spec.Hidden,
+			Secret:               spec.Secret,
+			Variadic:             spec.IsVariadic,
+			Accumulate:           spec.IsSlice,
+			Counter:              spec.IsCounter,
+			OptionalValue:        spec.OptionalArg,
+			DependsOn:            spec.DependsOn,
+			Env:                  spec.EnvVar,
+			FieldsFromFlag:       spec.FieldsFromFlag,
+			TimeFormats:          spec.TimeFormats,
+			TimeZone:             spec.TimeZone,
+			TimeZoneFromFlag:     spec.TimeZoneFromFlag,
+		}
+		if spec.Scope == ScopeGlobal {
+			f.Scope = "global"
+		}
+		if !spec.Secret {
+			f.Default = explainValue(spec.Default)
+		}
+		if spec.SecretFile {
+			f.SecretFileFlag = spec.Names[0] + "-file"
+		}
+		for _, cond := range spec.RequiredIf {
+			f.RequiredIf = append(f.RequiredIf, ConditionSchema{Flag: cond.Flag, Value: explainValue(cond.Value)})
+		}
+		out = append(out, f)
+	}
+	return out
+}
+
+// argsSchema describes the arguments of spec
+func argsSchema(spec *FlagSpec) []ArgSchema {
+	out := []ArgSchema{}
+	for i := 0; i < spec.ArgCount; i++ {
+		arg := ArgSchema{Type: ArgString.String()}
+		if i < len(spec.ArgNames) {
+			arg.Name = spec.ArgNames[i]
+		}
+		if i < len(spec.ArgTypes) {
+			arg.Type = spec.ArgTypes[i].String()
+			if spec.ArgTypes[i] == ArgEnum {
+				arg.Values = spec.enumValues(i)
+			}
+		}
+		if i < len(spec.ArgCompleters) {
+			switch c := spec.ArgCompleters[i].(type) {
+			case *StaticCompleter:
+				if arg.Values == nil {
+					arg.Values = c.Options
+				}
+			case *FileCompleter:
+				arg.FilePattern = c.Pattern
+			case *FieldValueCompleter:
+				arg.FieldValuesFrom = &FieldValuesSchema{SourceFlag: c.SourceFlag, FieldArg: c.FieldArg}
+			}
+		}
+		out = append(out, arg)
+	}
+	return out
+}
+
+// groupsSchema describes flag groups
+func groupsSchema(groups []FlagGroup) []GroupSchema {
+	var out []GroupSchema
+	for _, g := range groups {
+		kind := "one_required"
+		switch g.Kind {
+		case GroupMutuallyExclusive:
+			kind = "mutually_exclusive"
+		case GroupRequiredTogether:
+			kind = "required_together"
+		}
+		out = append(out, GroupSchema{Kind: kind, Flags: g.Flags})
+	}
+	return out
+}
+
+// examplesSchema converts usage examples
+func examplesSchema(examples []Example) []ExampleSchema {
+	var out []ExampleSchema
+	for _, ex := range examples {
+		out = append(out, ExampleSchema{Command: ex.Command, Description: ex.Description})
+	}
+	return out
+}
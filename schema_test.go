@@ -0,0 +1,120 @@
+package completionflags
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSchema(t *testing.T) {
+	cmd := NewCommand("tool").
+		Version("1.2.0").
+		Example("tool query users", "List users").
+		Flag("-verbose", "-v").Bool().Global().Help("Verbose output").Done().
+		Flag("-input").String().FilePattern("*.csv").Global().Done().
+		Flag("-token").String().SecretFile().Global().Default("changeme").Done().
+		MutuallyExclusive("-verbose", "-token").
+		Subcommand("query").
+		Description("Query a table").
+		Aliases("q").
+		Timeout(30*time.Second).
+		ClauseDescription("Clauses are ORed").
+		Flag("-match").
+		Arg("FIELD").FieldsFromFlag("-input").Done().
+		Arg("VALUE").FieldValuesFrom("-input", "FIELD").Done().
+		Accumulate().Local().Done().
+		Flag("-format").String().Options("json", "csv").Default("json").Global().Done().
+		Flag("-since").Time().TimeFormats("2006-01-02").Global().Done().
+		Flag("FILES").String().Variadic().Global().Done().
+		Handler(func(ctx *Context) error { return nil }).
+		Subcommand("deep").
+		Handler(func(ctx *Context) error { return nil }).
+		Done().
+		Done().
+		Build()
+
+	s := cmd.Schema()
+
+	if s.SchemaVersion != SchemaVersion || s.Name != "tool" || s.Version != "1.2.0" {
+		t.Errorf("header = %+v", s)
+	}
+	if !reflect.DeepEqual(s.Separators, []string{"+", "-"}) || len(s.Examples) != 1 {
+		t.Errorf("separators %v, examples %v", s.Separators, s.Examples)
+	}
+	if len(s.Groups) != 1 || s.Groups[0].Kind != "mutually_exclusive" {
+		t.Errorf("groups = %+v", s.Groups)
+	}
+
+	flags := map[string]FlagSchema{}
+	for _, f := range s.Flags {
+		flags[f.Names[0]] = f
+	}
+	if v := flags["-verbose"]; !reflect.DeepEqual(v.Names, []string{"-verbose", "-v"}) || v.Scope != "global" || len(v.Args) != 0 {
+		t.Errorf("-verbose = %+v", v)
+	}
+	if in := flags["-input"]; in.Args[0].FilePattern != "*.csv" {
+		t.Errorf("-input = %+v", in)
+	}
+	if tok := flags["-token"]; tok.Default != nil || !tok.Secret || tok.SecretFileFlag != "-token-file" {
+		t.Errorf("-token = %+v", tok)
+	}
+	if _, ok := flags["-token-file"]; !ok {
+		t.Error("-token-file missing")
+	}
+
+	if len(s.Subcommands) != 1 {
+		t.Fatalf("subcommands = %+v", s.Subcommands)
+	}
+	q := s.Subcommands[0]
+	if q.Name != "query" || q.Timeout != "30s" || q.ClauseDescription != "Clauses are ORed" || !q.Runnable ||
+		!reflect.DeepEqual(q.Aliases, []string{"q"}) {
+		t.Errorf("query = %+v", q)
+	}
+	if len(q.Subcommands) != 1 || q.Subcommands[0].Name != "deep" {
+		t.Errorf("nested = %+v", q.Subcommands)
+	}
+
+	sub := map[string]FlagSchema{}
+	for _, f := range q.Flags {
+		sub[f.Names[0]] = f
+	}
+	match := sub["-match"]
+	if match.Scope != "local" || !match.Accumulate || match.FieldsFromFlag != "-input" || len(match.Args) != 2 ||
+		match.Args[0].Name != "FIELD" ||
+		!reflect.DeepEqual(match.Args[1].FieldValuesFrom, &FieldValuesSchema{SourceFlag: "-input", FieldArg: "FIELD"}) {
+		t.Errorf("-match = %+v", match)
+	}
+	if f := sub["-format"]; f.Default != "json" || !reflect.DeepEqual(f.Args[0].Values, []string{"json", "csv"}) {
+		t.Errorf("-format = %+v", f)
+	}
+	if f := sub["-since"]; f.Args[0].Type != "time" || !reflect.DeepEqual(f.TimeFormats, []string{"2006-01-02"}) {
+		t.Errorf("-since = %+v", f)
+	}
+	if f := sub["FILES"]; !f.Positional || !f.Variadic {
+		t.Errorf("FILES = %+v", f)
+	}
+}
+
+func TestSchemaFlag(t *testing.T) {
+	cmd := NewCommand("tool").
+		Flag("-token").String().Secret().Global().Default("changeme").Done().
+		Handler(func(ctx *Context) error { return nil }).
+		Build()
+
+	var out bytes.Buffer
+	if err := cmd.ExecuteWith([]string{"-schema"}, (&Context{}).SetStdout(&out)); err != nil {
+		t.Fatal(err)
+	}
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(out.Bytes(), &decoded); err != nil {
+		t.Fatalf("%v:\n%s", err, out.String())
+	}
+	if decoded["schema_version"] != float64(SchemaVersion) || decoded["name"] != "tool" {
+		t.Errorf("decoded = %v", decoded)
+	}
+	if bytes.Contains(out.Bytes(), []byte("changeme")) {
+		t.Error("schema shows the default of a Secret flag")
+	}
+}
@@ -0,0 +1,173 @@
+package completionflags
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// redacted replaces the value of a Secret flag wherever it would be shown
+const redacted = "********"
+
+// errSecretInvalid stands in for parse and validation errors about a Secret
+// flag, whose messages often quote the value
+var errSecretInvalid = errors.New("invalid value (not shown: secret)")
+
+// secretError hides err's message when spec is Secret
+func secretError(spec *FlagSpec, err error) error {
+	if err == nil || spec == nil || !spec.Secret {
+		return err
+	}
+	return errSecretInvalid
+}
+
+// secretFileFlag returns the -NAME-file companion of a SecretFile flag, or
+// nil when spec doesn't have one. Builders add it next to the flag in Done.
+func secretFileFlag(spec *FlagSpec) *FlagSpec {
+	if !spec.SecretFile {
+		return nil
+	}
+	if spec.ArgCount != 1 {
+		panic(fmt.Sprintf("flag %s: SecretFile needs a flag taking one argument", spec.Names[0]))
+	}
+	return &FlagSpec{
+		Names:         []string{spec.Names[0] + "-file"},
+		Description:   fmt.Sprintf("Read %s from FILE", spec.Names[0]),
+		Scope:         spec.Scope,
+		ArgCount:      1,
+		ArgNames:      []string{"FILE"},
+		ArgTypes:      []ArgType{ArgString},
+		ArgCompleters: []Completer{&FileCompleter{Hint: "<FILE>"}},
+		Hidden:        spec.Hidden,
+		secretFor:     spec,
+	}
+}
+
+// readSecretFiles replaces each -NAME-file given in ctx with the value of
+// -NAME read from that file. Giving both on the command line, on either
+// side of a subcommand name, is an error; a value from the environment, a
+// config file or a default is overridden.
+func (cmd *Command) readSecretFiles(ctx *Context, flags []*FlagSpec) error {
+	for _, spec := range flags {
+		if spec.secretFor == nil {
+			continue
+		}
+		if spec.Scope == ScopeGlobal {
+			if err := cmd.readSecretFile(ctx, spec, ctx.GlobalFlags, ctx.sources); err != nil {
+				return err
+			}
+			continue
+		}
+		for i := range ctx.Clauses {
+			clause := &ctx.Clauses[i]
+			if err := cmd.readSecretFile(ctx, spec, clause.Flags, clause.sources); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// readSecretFile handles one -NAME-file occurrence in target
+func (cmd *Command) readSecretFile(ctx *Context, fileSpec *FlagSpec, target map[string]interface{}, sources map[string]ValueSource) error {
+	path, ok := target[fileSpec.Names[0]].(string)
+	if !ok {
+		return nil
+	}
+	spec := fileSpec.secretFor
+	name := spec.Names[0]
+	_, given := target[name]
+	given = given && (sources[name] == SourceUnset || sources[name] == SourceArgs)
+	if _, before := cmd.parentGlobals[name]; given || before {
+		return ParseError{
+			Flag:    fileSpec.Names[0],
+			Message: fmt.Sprintf("can't be used together with %s", name),
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ParseError{Flag: fileSpec.Names[0], Message: err.Error()}
+	}
+	value, err := parseArgValue(strings.TrimRight(string(data), "\r\n"), spec, 0, ctx.GlobalFlags)
+	if err != nil {
+		return ParseError{Flag: name, Message: fmt.Sprintf("invalid argument: %v", err)}
+	}
+
+	target[name] = value
+	delete(target, fileSpec.Names[0])
+	if sources != nil {
+		sources[name] = SourceArgs
+		delete(sources, fileSpec.Names[0])
+	}
+	return nil
+}
+
+// RedactArgs returns a copy of args with the values of Secret flags
+// replaced by "********", for logging or keeping a command line. Flags of
+// the subcommands named in args are recognised, as in dispatch.
+func (cmd *Command) RedactArgs(args []string) []string {
+	out := append([]string(nil), args...)
+	specs := cmd.flags
+	subcommands := cmd.subcommands
+	find := func(name string) *FlagSpec {
+		for _, spec := range specs {
+			for _, n := range spec.Names {
+				if n == name {
+					return spec
+				}
+			}
+		}
+		return nil
+	}
+
+	for i := 0; i < len(out); i++ {
+		word := out[i]
+		if word == "--" {
+			break
+		}
+		if len(word) > 1 && (word[0] == '-' || word[0] == '+') {
+			spec, fw := cmd.resolveFlagWord(word, find)
+			if spec == nil {
+				if bundle := cmd.splitBundle(word, find); bundle != nil {
+					spec = bundle[len(bundle)-1]
+				}
+			}
+			if spec == nil {
+				continue
+			}
+			if fw.inline {
+				if spec.Secret {
+					name, _, _ := strings.Cut(word, "=")
+					out[i] = name + "=" + redacted
+				}
+				continue
+			}
+			n := spec.ArgCount
+			if spec.OptionalArg {
+				if _, ok := cmd.optionalValue(spec, out, i, nil); !ok {
+					n = 0
+				}
+			}
+			for j := i + 1; j <= i+n && j < len(out); j++ {
+				if spec.Secret {
+					out[j] = redacted
+				}
+			}
+			i += n
+			continue
+		}
+
+		// Subcommand names extend the flags in scope; the first other
+		// word ends the subcommand path
+		sub, _ := cmd.resolveSubcommand(subcommands, word)
+		if sub == nil {
+			subcommands = nil
+			continue
+		}
+		specs = append(append([]*FlagSpec(nil), specs...), sub.Flags...)
+		subcommands = sub.Subcommands
+	}
+	return out
+}
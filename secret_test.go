@@ -0,0 +1,131 @@
+package completionflags
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRedactArgs(t *testing.T) {
+	cmd := NewCommand("tool").
+		AllowEquals().
+		Flag("-password").String().Secret().Global().Done().
+		Subcommand("login").
+		Flag("-user").String().Done().
+		Flag("-pin").Int().Secret().Done().
+		Handler(func(ctx *Context) error { return nil }).
+		Done().
+		Build()
+
+	tests := []struct {
+		args []string
+		want []string
+	}{
+		{
+			[]string{"-password", "pw", "login", "-user", "bob", "-pin", "1234"},
+			[]string{"-password", redacted, "login", "-user", "bob", "-pin", redacted},
+		},
+		{
+			[]string{"login", "--password=pw", "-user", "-pin"},
+			[]string{"login", "--password=" + redacted, "-user", "-pin"},
+		},
+		{
+			[]string{"login", "--", "-pin", "1234"},
+			[]string{"login", "--", "-pin", "1234"},
+		},
+	}
+	for _, tt := range tests {
+		if got := cmd.RedactArgs(tt.args); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("RedactArgs(%q) = %q, want %q", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestSecret_RawArgsAndErrors(t *testing.T) {
+	var ctx *Context
+	rejectPin := func(v interface{}) error { return fmt.Errorf("bad pin %v", v) }
+	cmd := NewCommand("tool").
+		Flag("-password").String().SecretFile().Global().Default("changeme").Done().
+		Subcommand("login").
+		Flag("-user").String().Done().
+		Flag("-pin").Int().Secret().Validate(rejectPin).Done().
+		Handler(func(c *Context) error {
+			ctx = c
+			return nil
+		}).
+		Done().
+		Build()
+
+	if err := cmd.ExecuteWith([]string{"-password", "hunter2", "login", "-user", "bob"}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(strings.Join(ctx.RawArgs, " "), "hunter2") {
+		t.Errorf("RawArgs = %q", ctx.RawArgs)
+	}
+	if ctx.GetString("-password", "") != "hunter2" {
+		t.Errorf("-password = %v", ctx.GlobalFlags["-password"])
+	}
+
+	for _, args := range [][]string{
+		{"login", "-pin", "12x4"}, // Parse error quoting the value
+		{"login", "-pin", "1234"}, // Validator error quoting the value
+	} {
+		err := cmd.ExecuteWith(args, nil)
+		if err == nil || strings.Contains(err.Error(), "12") {
+			t.Errorf("%q: error = %v", args, err)
+		}
+	}
+
+	help := cmd.GenerateHelp() + cmd.GenerateManPage()
+	if strings.Contains(help, "changeme") {
+		t.Error("help shows the default of a Secret flag")
+	}
+	if !strings.Contains(cmd.GenerateHelp(), "-password-file FILE") {
+		t.Errorf("help missing -password-file:\n%s", cmd.GenerateHelp())
+	}
+}
+
+func TestSecretFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pw")
+	if err := os.WriteFile(path, []byte("s3cret\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var ctx *Context
+	cmd := NewCommand("tool").
+		Flag("-password").String().SecretFile().Global().Done().
+		Subcommand("login").
+		Handler(func(c *Context) error {
+			ctx = c
+			return nil
+		}).
+		Done().
+		Build()
+
+	for _, args := range [][]string{
+		{"-password-file", path, "login"},
+		{"login", "-password-file", path},
+	} {
+		if err := cmd.ExecuteWith(args, nil); err != nil {
+			t.Fatalf("%q: %v", args, err)
+		}
+		if ctx.GetString("-password", "") != "s3cret" || ctx.Source("-password") != SourceArgs {
+			t.Errorf("%q: -password = %v (%v)", args, ctx.GlobalFlags["-password"], ctx.Source("-password"))
+		}
+		if _, ok := ctx.GlobalFlags["-password-file"]; ok {
+			t.Errorf("%q: -password-file left in GlobalFlags", args)
+		}
+	}
+
+	err := cmd.ExecuteWith([]string{"-password", "x", "login", "-password-file", path}, nil)
+	if err == nil || !strings.Contains(err.Error(), "can't be used together with -password") {
+		t.Errorf("error = %v", err)
+	}
+	err = cmd.ExecuteWith([]string{"login", "-password-file", path + ".missing"}, nil)
+	if err == nil || !strings.Contains(err.Error(), "-password-file") {
+		t.Errorf("error = %v", err)
+	}
+}
@@ -4,7 +4,11 @@ import (
 	"bufio"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"sync"
+
+	cf "github.com/rosscartlidge/autocli/v4"
 )
 
 // fileHistory is a bounded, file-backed implementation of
@@ -19,6 +23,10 @@ type fileHistory struct {
 	mu      sync.Mutex
 	path    string
 	entries []string // newest at index 0 to match term.History.At() semantics
+
+	// secret, if set, reports lines that must not be kept (they pass a
+	// value to a Secret flag)
+	secret func(line string) bool
 }
 
 const maxHistory = 1000
@@ -56,6 +64,10 @@ func newFileHistory(path string) *fileHistory {
 }
 
 func (h *fileHistory) Add(entry string) {
+	if h.secret != nil && h.secret(entry) {
+		return
+	}
+
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
@@ -116,3 +128,18 @@ func (h *fileHistory) flushLocked() error {
 	}
 	return os.Rename(tmp, h.path)
 }
+
+// hasSecret reports whether line gives a value to a Secret flag of cli in
+// any of its pipeline stages
+func hasSecret(cli *cf.Command, line string) bool {
+	args, err := Tokenize(line)
+	if err != nil {
+		args = strings.Fields(line) // Unterminated quote: best effort
+	}
+	for _, stage := range splitStagesForCompletion(args) {
+		if !slices.Equal(cli.RedactArgs(stage), stage) {
+			return true
+		}
+	}
+	return false
+}
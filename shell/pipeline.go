@@ -154,9 +154,8 @@ func runPipeline(cli *cf.Command, stages [][]string, base *cf.Context) error {
 		if errors.Is(err, io.ErrClosedPipe) {
 			continue
 		}
-		var unknown cf.ErrUnknownCommand
-		if errors.As(err, &unknown) {
-			return fmt.Errorf("stage %d: unknown command %q (try -help or :help)", i+1, string(unknown))
+		if msg, ok := unknownCommandMessage(err); ok {
+			return fmt.Errorf("stage %d: %s", i+1, msg)
 		}
 		return fmt.Errorf("stage %d (%s): %w", i+1, strings.Join(stages[i], " "), err)
 	}
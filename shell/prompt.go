@@ -0,0 +1,70 @@
+package shell
+
+import (
+	"context"
+	"os"
+
+	cf "github.com/rosscartlidge/autocli/v4"
+	"golang.org/x/term"
+)
+
+// linePrompter answers cf.Prompter questions from the session's line
+// editor, so a command can ask for input (PromptForMissing) mid-session.
+// Answers stay out of the command history and TAB doesn't complete them.
+type linePrompter struct {
+	t      *term.Terminal
+	cr     *ctrlCReader
+	prompt string // The shell prompt to restore
+}
+
+func (p *linePrompter) ReadLine(prompt string) (string, error) {
+	return p.read(func() (string, error) {
+		p.t.SetPrompt(prompt)
+		defer p.t.SetPrompt(p.prompt)
+		return p.t.ReadLine()
+	})
+}
+
+func (p *linePrompter) ReadPassword(prompt string) (string, error) {
+	return p.read(func() (string, error) {
+		return p.t.ReadPassword(prompt)
+	})
+}
+
+// read runs one ReadLine/ReadPassword with completion and history switched
+// off. Ctrl-C abandons the question with context.Canceled; Ctrl-D gives
+// io.EOF.
+func (p *linePrompter) read(readLine func() (string, error)) (string, error) {
+	complete, history := p.t.AutoCompleteCallback, p.t.History
+	p.t.AutoCompleteCallback, p.t.History = nil, noHistory{}
+	defer func() { p.t.AutoCompleteCallback, p.t.History = complete, history }()
+
+	line, err := readLine()
+	if p.cr.consumeCtrlC() {
+		return "", context.Canceled
+	}
+	return line, err
+}
+
+// noHistory discards lines read while answering a prompt
+type noHistory struct{}
+
+func (noHistory) Add(string)    {}
+func (noHistory) Len() int      { return 0 }
+func (noHistory) At(int) string { panic("shell: empty history") }
+
+// isInteractive reports whether commands may prompt the user: stdin is a
+// local terminal, or the caller says the streams are one (Options.Terminal)
+func isInteractive(opts *Options) bool {
+	if opts.Terminal {
+		return true
+	}
+	f, ok := opts.Stdin.(*os.File)
+	return ok && term.IsTerminal(int(f.Fd()))
+}
+
+// Compile-time checks against the interfaces these implement
+var (
+	_ cf.Prompter  = (*linePrompter)(nil)
+	_ term.History = noHistory{}
+)
@@ -54,7 +54,8 @@ type Options struct {
 
 	// HistoryFile, if non-empty, persists the session's command history.
 	// Empty means in-memory only. File-format is one line per entry,
-	// oldest-first; capped at 1000 entries.
+	// oldest-first; capped at 1000 entries. Lines that give a value to a
+	// Secret flag (cf.FlagBuilder.Secret) are not kept.
 	HistoryFile string
 
 	// EditingMode used to switch between emacs and vi keybindings.
@@ -131,6 +132,12 @@ type Options struct {
 	// payloads; local shell.Serve callers can ignore it (x/term
 	// inspects the local terminal directly when MakeRaw is used).
 	ResizeChan <-chan TerminalSize
+
+	// Terminal marks Stdin/Stdout as an interactive terminal even though
+	// Stdin isn't a local TTY, so commands may ask the operator questions
+	// (cf.PromptForMissing). autocli/ssh sets it when the client requested
+	// a pty. A local terminal on os.Stdin is detected without it.
+	Terminal bool
 }
 
 // TerminalSize is a width+height pair pushed through Options.ResizeChan
@@ -202,7 +209,9 @@ func Serve(cli *cf.Command, opts Options) error {
 	t := term.NewTerminal(rw, opts.Prompt)
 
 	if opts.HistoryFile != "" {
-		t.History = newFileHistory(opts.HistoryFile)
+		h := newFileHistory(opts.HistoryFile)
+		h.secret = func(line string) bool { return hasSecret(cli, line) }
+		t.History = h
 	}
 
 	// TAB completion. AutoCompleteCallback fires on EVERY keypress;
@@ -300,7 +309,8 @@ func Serve(cli *cf.Command, opts Options) error {
 		// command's stdin would let a transform like `where` swallow
 		// the operator's next keystrokes and appear to hang the
 		// session. Commands that need data are expected to take a
-		// pipeline upstream (e.g. `from-loaded | where ...`).
+		// pipeline upstream (e.g. `from-loaded | where ...`); ones that
+		// need an answer ask through the Prompter set below.
 		base := (&cf.Context{State: opts.State}).
 			SetStdin(bytes.NewReader(nil)).
 			SetStdout(t).
@@ -328,12 +338,17 @@ func Serve(cli *cf.Command, opts Options) error {
 			continue
 		}
 
+		// A single command may ask the operator questions through the
+		// line editor; pipeline stages run concurrently and don't
+		if isInteractive(&opts) {
+			base.SetPrompter(&linePrompter{t: t, cr: cr, prompt: opts.Prompt})
+		}
+
 		if err := cli.ExecuteWith(args, base); err != nil {
 			// Friendly message for unknown commands instead of dumping
 			// the full help screen on every typo.
-			var unknown cf.ErrUnknownCommand
-			if errors.As(err, &unknown) {
-				fmt.Fprintf(opts.Stderr, "unknown command: %q (try -help or :help)\n", string(unknown))
+			if msg, ok := unknownCommandMessage(err); ok {
+				fmt.Fprintln(opts.Stderr, msg)
 			} else {
 				fmt.Fprintf(opts.Stderr, "%v\n", err)
 			}
@@ -404,6 +419,31 @@ func isHelpToken(s string) bool {
 	return false
 }
 
+// unknownCommandMessage renders an unknown-command error for the prompt:
+// "unknown command 'stauts'; did you mean 'status'?" when autocli found
+// similar commands, otherwise a pointer at -help. ok is false for any
+// other error.
+func unknownCommandMessage(err error) (msg string, ok bool) {
+	var unknown cf.ErrUnknownCommand
+	if !errors.As(err, &unknown) {
+		return "", false
+	}
+
+	var detailed cf.UnknownCommandError
+	if errors.As(err, &detailed) && len(detailed.Suggestions) > 0 {
+		quoted := make([]string, len(detailed.Suggestions))
+		for i, s := range detailed.Suggestions {
+			quoted[i] = "'" + s + "'"
+		}
+		list := quoted[0]
+		if n := len(quoted); n > 1 {
+			list = strings.Join(quoted[:n-1], ", ") + " or " + quoted[n-1]
+		}
+		return fmt.Sprintf("unknown command '%s'; did you mean %s?", detailed.Name, list), true
+	}
+	return fmt.Sprintf("unknown command: %q (try -help or :help)", string(unknown)), true
+}
+
 // tabComplete is the per-TAB-press completer.
 //
 //   - Single match → replace the current word with the match + space.
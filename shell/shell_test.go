@@ -5,6 +5,8 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
@@ -256,6 +258,18 @@ func TestServe_UnknownCommandShowsFriendlyError(t *testing.T) {
 	}
 }
 
+// TestServe_UnknownCommandSuggests asserts a near-miss typo is
+// answered with autocli's "did you mean" candidates.
+func TestServe_UnknownCommandSuggests(t *testing.T) {
+	cli := buildTestCLI(&testState{})
+
+	out := runShellWithInput(t, cli, Options{}, "raed\n:exit\n")
+
+	if !strings.Contains(out, "unknown command 'raed'; did you mean 'read'?") {
+		t.Errorf("expected suggestion for typo; got: %q", out)
+	}
+}
+
 // TestServe_DashHelpUsesEmbeddedForm asserts typing `-help` at the
 // prompt emits the embedded help (no SHELL COMPLETION footer / no
 // -man reference) rather than the bash flavoured form.
@@ -318,3 +332,98 @@ func TestServe_EOFExits(t *testing.T) {
 		t.Errorf("missing inc output: %q", out)
 	}
 }
+
+// buildPromptCLI returns a command tree that asks for a missing required
+// flag (cf.PromptForMissing)
+func buildPromptCLI(got *string) *cf.Command {
+	return cf.NewCommand("svc").
+		PromptForMissing().
+		Subcommand("greet").
+		Flag("-name").String().Required().Global().Help("Who to greet").Done().
+		Handler(func(ctx *cf.Context) error {
+			*got = ctx.GetString("-name", "")
+			fmt.Fprintf(ctx.Stdout(), "hello %s\n", *got)
+			return nil
+		}).
+		Done().
+		Build()
+}
+
+// TestServe_PromptsForMissingFlag asserts a terminal session asks for a
+// missing required flag through the line editor
+func TestServe_PromptsForMissingFlag(t *testing.T) {
+	var got string
+	cli := buildPromptCLI(&got)
+
+	out := runShellWithInput(t, cli, Options{Terminal: true}, "greet\nworld\n")
+
+	if got != "world" {
+		t.Errorf("name = %q, want world; output %q", got, out)
+	}
+	if !strings.Contains(out, "Who to greet (-name, string): ") {
+		t.Errorf("prompt missing in: %q", out)
+	}
+	if !strings.Contains(out, "hello world") {
+		t.Errorf("handler output missing in: %q", out)
+	}
+}
+
+// TestServe_NoPromptWithoutTerminal asserts piped sessions keep the
+// "required flag" error
+func TestServe_NoPromptWithoutTerminal(t *testing.T) {
+	var got string
+	cli := buildPromptCLI(&got)
+
+	out := runShellWithInput(t, cli, Options{}, "greet\nworld\n")
+
+	if got != "" || !strings.Contains(out, "required flag not provided") {
+		t.Errorf("name = %q, output %q", got, out)
+	}
+}
+
+// TestServe_HandlerConfirm asserts a handler's ctx.Confirm is answered
+// through the line editor, and refused in a piped session
+func TestServe_HandlerConfirm(t *testing.T) {
+	var confirmed bool
+	cli := cf.NewCommand("svc").
+		Subcommand("drop").
+		Handler(func(ctx *cf.Context) error {
+			ok, err := ctx.Confirm("Drop table?")
+			confirmed = ok
+			return err
+		}).
+		Done().
+		Build()
+
+	out := runShellWithInput(t, cli, Options{Terminal: true}, "drop\ny\n")
+	if !confirmed || !strings.Contains(out, "Drop table? [y/N]: ") {
+		t.Errorf("confirmed = %v, output %q", confirmed, out)
+	}
+
+	out = runShellWithInput(t, cli, Options{}, "drop\ny\n")
+	if confirmed || !strings.Contains(out, cf.ErrNotInteractive.Error()) {
+		t.Errorf("piped: confirmed = %v, output %q", confirmed, out)
+	}
+}
+
+// TestServe_HistorySkipsSecrets asserts lines passing a Secret flag value
+// never reach the history file
+func TestServe_HistorySkipsSecrets(t *testing.T) {
+	cli := cf.NewCommand("svc").
+		Subcommand("login").
+		Flag("-token").String().Secret().Done().
+		Handler(func(ctx *cf.Context) error { return nil }).
+		Done().
+		Build()
+
+	path := filepath.Join(t.TempDir(), "history")
+	runShellWithInput(t, cli, Options{HistoryFile: path}, "login\nlogin -token hunter2\nlogin\n")
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "hunter2") || !strings.Contains(string(data), "login\n") {
+		t.Errorf("history file:\n%s", data)
+	}
+}
@@ -0,0 +1,96 @@
+package completionflags
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// InterruptedError is the cause of the cancellation of Context.Ctx() when
+// Execute receives SIGINT or SIGTERM (see context.Cause). It unwraps to
+// context.Canceled, and Execute returns it in place of a handler's
+// context.Canceled error.
+type InterruptedError struct {
+	Signal os.Signal
+}
+
+func (e InterruptedError) Error() string {
+	return fmt.Sprintf("interrupted (%v)", e.Signal)
+}
+
+// Unwrap returns context.Canceled
+func (e InterruptedError) Unwrap() error {
+	return context.Canceled
+}
+
+// exitCode follows the shell convention of 128 plus the signal number
+func (e InterruptedError) exitCode() int {
+	if s, ok := e.Signal.(syscall.Signal); ok {
+		return 128 + int(s)
+	}
+	return ExitFailure
+}
+
+// shutdownSignals are the signals Execute turns into cancellation
+var shutdownSignals = []os.Signal{os.Interrupt, syscall.SIGTERM}
+
+// exitProcess is os.Exit, replaced in tests
+var exitProcess = os.Exit
+
+// executeInterruptible runs args with a Context whose Ctx() is cancelled by
+// the first SIGINT or SIGTERM. A second signal, or the handler still
+// running ShutdownTimeout after the first, exits the process.
+func (cmd *Command) executeInterruptible(args []string) error {
+	ctx, cancel := context.WithCancelCause(context.Background())
+	defer cancel(nil)
+
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, shutdownSignals...)
+	defer signal.Stop(signals)
+
+	done := make(chan struct{})
+	defer close(done)
+	go cmd.watchSignals(signals, done, cancel, os.Stderr)
+
+	err := cmd.ExecuteWith(args, (&Context{}).SetCtx(ctx))
+	var interrupted InterruptedError
+	if errors.Is(err, context.Canceled) && errors.As(context.Cause(ctx), &interrupted) {
+		return interrupted
+	}
+	return err
+}
+
+// watchSignals cancels on the first signal and forces an exit on a second
+// one or when the shutdown timeout expires, until done is closed
+func (cmd *Command) watchSignals(signals <-chan os.Signal, done <-chan struct{}, cancel context.CancelCauseFunc, stderr io.Writer) {
+	var first InterruptedError
+	select {
+	case sig := <-signals:
+		first = InterruptedError{Signal: sig}
+		cancel(first)
+	case <-done:
+		return
+	}
+
+	var timeout <-chan time.Time
+	if cmd.shutdownTimeout > 0 {
+		timer := time.NewTimer(cmd.shutdownTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	select {
+	case sig := <-signals:
+		fmt.Fprintf(stderr, "Error: %v again, exiting\n", sig)
+		exitProcess(InterruptedError{Signal: sig}.exitCode())
+	case <-timeout:
+		fmt.Fprintf(stderr, "Error: shutdown timed out after %v\n", cmd.shutdownTimeout)
+		exitProcess(first.exitCode())
+	case <-done:
+	}
+}
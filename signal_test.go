@@ -0,0 +1,117 @@
+//go:build !windows
+
+package completionflags
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestSignal_CancelsCtx(t *testing.T) {
+	cleanedUp := false
+	cmd := NewCommand("tool").
+		Handler(func(ctx *Context) error {
+			if err := syscall.Kill(os.Getpid(), syscall.SIGINT); err != nil {
+				return err
+			}
+			select {
+			case <-ctx.Ctx().Done():
+				cleanedUp = true
+				return ctx.Ctx().Err()
+			case <-time.After(5 * time.Second):
+				return errors.New("context not cancelled")
+			}
+		}).
+		Build()
+
+	err := cmd.Execute(nil)
+	var interrupted InterruptedError
+	if !errors.As(err, &interrupted) || interrupted.Signal != os.Interrupt {
+		t.Fatalf("error = %v, want InterruptedError", err)
+	}
+	if !errors.Is(err, context.Canceled) {
+		t.Error("InterruptedError should unwrap to context.Canceled")
+	}
+	if !cleanedUp {
+		t.Error("handler didn't observe cancellation")
+	}
+	if code := ExitCode(err); code != 130 {
+		t.Errorf("ExitCode = %d, want 130", code)
+	}
+}
+
+func TestSignal_NotInstalledForExecuteWith(t *testing.T) {
+	cmd := NewCommand("tool").
+		Handler(func(ctx *Context) error {
+			if ctx.Ctx() != context.Background() {
+				t.Error("ExecuteWith(nil) should keep context.Background()")
+			}
+			return nil
+		}).
+		Build()
+
+	if err := cmd.ExecuteWith(nil, nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// watchForExit runs watchSignals on a fake channel with exitProcess stubbed,
+// returning the channel and the exit code it was called with
+func watchForExit(t *testing.T, cmd *Command) (chan os.Signal, <-chan int, context.Context) {
+	t.Helper()
+	exited := make(chan int, 1)
+	exitProcess = func(code int) { exited <- code }
+	t.Cleanup(func() { exitProcess = os.Exit })
+
+	ctx, cancel := context.WithCancelCause(context.Background())
+	signals := make(chan os.Signal, 2)
+	done := make(chan struct{})
+	t.Cleanup(func() { close(done); cancel(nil) })
+	go cmd.watchSignals(signals, done, cancel, io.Discard)
+	return signals, exited, ctx
+}
+
+func TestSignal_SecondSignalForcesExit(t *testing.T) {
+	cmd := NewCommand("tool").Handler(func(ctx *Context) error { return nil }).Build()
+	signals, exited, ctx := watchForExit(t, cmd)
+
+	signals <- syscall.SIGTERM
+	<-ctx.Done()
+	signals <- syscall.SIGINT
+	select {
+	case code := <-exited:
+		if code != 130 {
+			t.Errorf("exit code = %d, want 130", code)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("second signal didn't force an exit")
+	}
+}
+
+func TestSignal_ShutdownTimeout(t *testing.T) {
+	cmd := NewCommand("tool").
+		ShutdownTimeout(10 * time.Millisecond).
+		Handler(func(ctx *Context) error { return nil }).
+		Build()
+	signals, exited, ctx := watchForExit(t, cmd)
+
+	signals <- syscall.SIGTERM
+	<-ctx.Done()
+	var interrupted InterruptedError
+	if !errors.As(context.Cause(ctx), &interrupted) || interrupted.Signal != syscall.SIGTERM {
+		t.Errorf("cause = %v", context.Cause(ctx))
+	}
+	select {
+	case code := <-exited:
+		if code != 143 {
+			t.Errorf("exit code = %d, want 143", code)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("shutdown timeout didn't force an exit")
+	}
+}
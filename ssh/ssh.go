@@ -22,6 +22,7 @@ import (
 	"net"
 	"os"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	cf "github.com/rosscartlidge/autocli/v4"
@@ -394,11 +395,14 @@ func handleSession(ctx context.Context, ch gossh.Channel, reqs <-chan *gossh.Req
 	resizeCh := make(chan shell.TerminalSize, 4)
 
 	// Wait for the client to request a shell (after optional pty-req).
+	// pty records whether the client asked for a terminal.
 	ready := make(chan struct{}, 1)
+	var pty atomic.Bool
 	go func() {
 		for req := range reqs {
 			switch req.Type {
 			case "pty-req":
+				pty.Store(true)
 				if cols, rows, ok := parsePtyReq(req.Payload); ok {
 					select {
 					case resizeCh <- shell.TerminalSize{Width: int(cols), Height: int(rows)}:
@@ -454,6 +458,7 @@ func handleSession(ctx context.Context, ch gossh.Channel, reqs <-chan *gossh.Req
 		Settings:    opts.Settings,
 		SchemaWalk:  opts.SchemaWalk,
 		ResizeChan:  resizeCh,
+		Terminal:    pty.Load(), // Commands may prompt the operator
 	}
 	if opts.HistoryDir != "" {
 		// Per-user history + prefs under the supplied directory. User
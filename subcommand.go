@@ -4,11 +4,13 @@ import (
 	"fmt"
 	"sort"
 	"strings"
+	"time"
 )
 
 // Subcommand represents a subcommand with its own flags, positionals, and handler
 type Subcommand struct {
 	Name              string
+	Aliases           []string // Alternative names (e.g. "rm" for "remove")
 	Description       string
 	Author            string
 	Examples          []Example
@@ -17,6 +19,18 @@ type Subcommand struct {
 	Separators        []string
 	ClauseDescription string                 // Custom description for CLAUSES section (optional)
 	Subcommands       map[string]*Subcommand // Nested subcommands (for multi-level commands like "git remote add")
+	Groups            []FlagGroup            // Constraints across flags (MutuallyExclusive, ...)
+	ClauseRules       ClauseRules            // Clause count limits and validators (MinClauses, ...)
+	ContextValidators []ContextValidatorFunc // Cross-flag checks (ValidateContext)
+
+	// Hooks around this subcommand's handler and those nested below it
+	Before     []BeforeFunc
+	After      []AfterFunc
+	Middleware []Middleware
+
+	// Timeout limits how long the handler may run; nested subcommands
+	// inherit it unless they set their own
+	Timeout time.Duration
 }
 
 // Builder is an interface for types that support the fluent subcommand API
@@ -97,18 +111,107 @@ func (sb *SubcommandBuilder) Example(command, description string) *SubcommandBui
 	return sb
 }
 
+// Aliases adds alternative names for the subcommand (e.g. Aliases("rm", "del")).
+// An alias resolves exactly like the canonical name; Context.SubcommandPath
+// always holds the canonical name, and help lists aliases next to it.
+func (sb *SubcommandBuilder) Aliases(names ...string) *SubcommandBuilder {
+	for _, name := range names {
+		if name == "" || strings.HasPrefix(name, "-") || strings.HasPrefix(name, "+") {
+			panic(fmt.Sprintf("subcommand %q: invalid alias %q", sb.name, name))
+		}
+	}
+	sb.subcmd.Aliases = append(sb.subcmd.Aliases, names...)
+	return sb
+}
+
 // ClauseDescription sets a custom description for the CLAUSES section
 func (sb *SubcommandBuilder) ClauseDescription(desc string) *SubcommandBuilder {
 	sb.subcmd.ClauseDescription = desc
 	return sb
 }
 
+// MutuallyExclusive declares that at most one of the flags may be given
+func (sb *SubcommandBuilder) MutuallyExclusive(flags ...string) *SubcommandBuilder {
+	sb.subcmd.Groups = append(sb.subcmd.Groups, FlagGroup{Kind: GroupMutuallyExclusive, Flags: flags})
+	return sb
+}
+
+// RequiredTogether declares that the flags must be given together or not at all
+func (sb *SubcommandBuilder) RequiredTogether(flags ...string) *SubcommandBuilder {
+	sb.subcmd.Groups = append(sb.subcmd.Groups, FlagGroup{Kind: GroupRequiredTogether, Flags: flags})
+	return sb
+}
+
+// OneRequired declares that at least one of the flags must be given
+func (sb *SubcommandBuilder) OneRequired(flags ...string) *SubcommandBuilder {
+	sb.subcmd.Groups = append(sb.subcmd.Groups, FlagGroup{Kind: GroupOneRequired, Flags: flags})
+	return sb
+}
+
 // Separators configures clause separators (overrides parent)
 func (sb *SubcommandBuilder) Separators(seps ...string) *SubcommandBuilder {
 	sb.subcmd.Separators = seps
 	return sb
 }
 
+// ValidateContext adds a check on the subcommand's parsed flags as a whole,
+// root globals included. It also runs for nested subcommands, after the
+// hooks of the root and of parents (see CommandBuilder.ValidateContext).
+func (sb *SubcommandBuilder) ValidateContext(fn ContextValidatorFunc) *SubcommandBuilder {
+	sb.subcmd.ContextValidators = append(sb.subcmd.ContextValidators, fn)
+	return sb
+}
+
+// Before adds a hook run before this subcommand's handler and those of its
+// nested subcommands, inside the parent's hooks (see CommandBuilder.Before)
+func (sb *SubcommandBuilder) Before(fn BeforeFunc) *SubcommandBuilder {
+	sb.subcmd.Before = append(sb.subcmd.Before, fn)
+	return sb
+}
+
+// After adds a hook run after this subcommand's handler and those of its
+// nested subcommands (see CommandBuilder.After)
+func (sb *SubcommandBuilder) After(fn AfterFunc) *SubcommandBuilder {
+	sb.subcmd.After = append(sb.subcmd.After, fn)
+	return sb
+}
+
+// Use wraps this subcommand's handler and those of its nested subcommands
+// in mw (see CommandBuilder.Use)
+func (sb *SubcommandBuilder) Use(mw ...Middleware) *SubcommandBuilder {
+	sb.subcmd.Middleware = append(sb.subcmd.Middleware, mw...)
+	return sb
+}
+
+// Timeout limits how long this subcommand's handler, and those nested
+// below it, may run. Ctx() carries the deadline and dispatch returns a
+// TimeoutError when it passes; a handler still running after that has its
+// output discarded and its questions refused. The root -timeout flag
+// overrides it (see CommandBuilder.TimeoutFlag).
+func (sb *SubcommandBuilder) Timeout(d time.Duration) *SubcommandBuilder {
+	sb.subcmd.Timeout = d
+	return sb
+}
+
+// MinClauses requires at least n clauses
+func (sb *SubcommandBuilder) MinClauses(n int) *SubcommandBuilder {
+	sb.subcmd.ClauseRules.Min = n
+	return sb
+}
+
+// MaxClauses allows at most n clauses
+func (sb *SubcommandBuilder) MaxClauses(n int) *SubcommandBuilder {
+	sb.subcmd.ClauseRules.Max = n
+	return sb
+}
+
+// ClauseValidator adds a check run on every clause after the flags are
+// validated; an error is reported with the clause's index and separator
+func (sb *SubcommandBuilder) ClauseValidator(fn func(Clause) error) *SubcommandBuilder {
+	sb.subcmd.ClauseRules.Validators = append(sb.subcmd.ClauseRules.Validators, fn)
+	return sb
+}
+
 // Flag starts defining a new flag for this subcommand
 func (sb *SubcommandBuilder) Flag(names ...string) *SubcommandFlagBuilder {
 	// Check for conflicts with root global flags
@@ -195,6 +298,21 @@ func (sb *SubcommandBuilder) Done() Builder {
 		panic(fmt.Sprintf("subcommand %q positional validation failed: %v", sb.name, err))
 	}
 
+	// Validate flag groups (root globals may take part)
+	if err := resolveGroupFlags(sb.subcmd.Groups, sb.subcmd.Flags, sb.parent.getRootGlobalFlags()); err != nil {
+		panic(fmt.Sprintf("subcommand %q flag group validation failed: %v", sb.name, err))
+	}
+
+	// Validate flag dependencies (root globals may be depended on)
+	if err := resolveFlagDependencies(sb.subcmd.Flags, sb.parent.getRootGlobalFlags()); err != nil {
+		panic(fmt.Sprintf("subcommand %q flag dependency validation failed: %v", sb.name, err))
+	}
+
+	// Validate per-clause rules
+	if err := resolveClauseRules(sb.subcmd.Flags); err != nil {
+		panic(fmt.Sprintf("subcommand %q clause rule validation failed: %v", sb.name, err))
+	}
+
 	// Add to parent using interface method
 	sb.parent.addSubcommand(sb.name, sb.subcmd)
 
@@ -239,6 +357,7 @@ func (cb *CommandBuilder) Done() Builder {
 
 // addSubcommand adds a subcommand to the root command
 func (cb *CommandBuilder) addSubcommand(name string, subcmd *Subcommand) {
+	checkSubcommandNames(cb.cmd.subcommands, subcmd)
 	cb.cmd.subcommands[name] = subcmd
 }
 
@@ -259,6 +378,7 @@ func (sb *SubcommandBuilder) addSubcommand(name string, subcmd *Subcommand) {
 	if sb.subcmd.Subcommands == nil {
 		sb.subcmd.Subcommands = make(map[string]*Subcommand)
 	}
+	checkSubcommandNames(sb.subcmd.Subcommands, subcmd)
 	sb.subcmd.Subcommands[name] = subcmd
 }
 
@@ -342,13 +462,75 @@ func (cmd *Command) demotedRootGlobalFlags() []*FlagSpec {
 	return out
 }
 
-// hasSubcommand checks if a subcommand name is registered
+// hasSubcommand checks if a subcommand name (or alias, or prefix when
+// AllowPrefixMatch is on) is registered
 func (cmd *Command) hasSubcommand(name string) bool {
-	if cmd.subcommands == nil {
-		return false
+	subcmd, _ := cmd.resolveSubcommand(cmd.subcommands, name)
+	return subcmd != nil
+}
+
+// resolveSubcommand finds the subcommand word refers to among subcommands: a
+// canonical name, then an alias, then (with AllowPrefixMatch) an unambiguous
+// prefix of a name or alias. It returns the subcommand and its canonical name,
+// or nil when nothing matches or a prefix is ambiguous.
+func (cmd *Command) resolveSubcommand(subcommands map[string]*Subcommand, word string) (*Subcommand, string) {
+	if subcmd, ok := subcommands[word]; ok {
+		return subcmd, word
+	}
+	for name, subcmd := range subcommands {
+		for _, alias := range subcmd.Aliases {
+			if alias == word {
+				return subcmd, name
+			}
+		}
+	}
+	if !cmd.allowPrefixMatch || word == "" {
+		return nil, ""
 	}
-	_, exists := cmd.subcommands[name]
-	return exists
+
+	var match *Subcommand
+	var matchName string
+	for name, subcmd := range subcommands {
+		for _, candidate := range append([]string{name}, subcmd.Aliases...) {
+			if !strings.HasPrefix(candidate, word) {
+				continue
+			}
+			if match != nil && match != subcmd {
+				return nil, "" // Ambiguous
+			}
+			match, matchName = subcmd, name
+			break
+		}
+	}
+	return match, matchName
+}
+
+// checkSubcommandNames panics when subcmd's name or aliases collide with a
+// sibling's name or aliases
+func checkSubcommandNames(siblings map[string]*Subcommand, subcmd *Subcommand) {
+	for _, name := range append([]string{subcmd.Name}, subcmd.Aliases...) {
+		for siblingName, sibling := range siblings {
+			if sibling == subcmd {
+				continue
+			}
+			taken := name == siblingName
+			for _, alias := range sibling.Aliases {
+				taken = taken || name == alias
+			}
+			if taken {
+				panic(fmt.Sprintf("subcommand %q: name %q already used by subcommand %q", subcmd.Name, name, siblingName))
+			}
+		}
+	}
+}
+
+// label renders the subcommand's name for listings, with any aliases:
+// "remove (rm, del)"
+func (subcmd *Subcommand) label() string {
+	if len(subcmd.Aliases) == 0 {
+		return subcmd.Name
+	}
+	return fmt.Sprintf("%s (%s)", subcmd.Name, strings.Join(subcmd.Aliases, ", "))
 }
 
 // getSubcommand retrieves a subcommand by name (internal use)
@@ -422,6 +604,12 @@ func (subcmd *Subcommand) GenerateHelp(parentName string) string {
 		sb.WriteString(fmt.Sprintf("    %s\n\n", subcmd.Description))
 	}
 
+	// Aliases
+	if len(subcmd.Aliases) > 0 {
+		sb.WriteString("ALIASES:\n")
+		sb.WriteString(fmt.Sprintf("    %s\n\n", strings.Join(subcmd.Aliases, ", ")))
+	}
+
 	// Nested subcommands (e.g. `ssql to table`, `ssql to csv`) — list them so a
 	// dispatcher command's help actually shows what it dispatches to.
 	if len(subcmd.Subcommands) > 0 {
@@ -432,7 +620,8 @@ func (subcmd *Subcommand) GenerateHelp(parentName string) string {
 		}
 		sort.Strings(names)
 		for _, name := range names {
-			sb.WriteString(fmt.Sprintf("    %-15s %s\n", name, subcmd.Subcommands[name].Description))
+			nested := subcmd.Subcommands[name]
+			sb.WriteString(fmt.Sprintf("    %-15s %s\n", nested.label(), nested.Description))
 		}
 		sb.WriteString("\n")
 	}
@@ -487,6 +676,9 @@ func (subcmd *Subcommand) GenerateHelp(parentName string) string {
 		}
 	}
 
+	// Constraints across flags
+	sb.WriteString(formatGroups(subcmd.Groups))
+
 	// Clauses explanation
 	if len(localFlags) > 0 {
 		sb.WriteString("CLAUSES:\n")
@@ -549,21 +741,7 @@ func formatPositionalForSubcommand(spec *FlagSpec) string {
 
 	// Type information
 	if len(spec.ArgTypes) > 0 && spec.ArgTypes[0] != ArgString {
-		var typeName string
-		switch spec.ArgTypes[0] {
-		case ArgInt:
-			typeName = "integer"
-		case ArgFloat:
-			typeName = "float"
-		case ArgBool:
-			typeName = "boolean"
-		case ArgDuration:
-			typeName = "duration"
-		case ArgTime:
-			typeName = "time"
-		default:
-			typeName = "string"
-		}
+		typeName := argLabel(spec, 0)
 		sb.WriteString(fmt.Sprintf("        Type: %s\n", typeName))
 	}
 
@@ -572,6 +750,11 @@ func formatPositionalForSubcommand(spec *FlagSpec) string {
 		sb.WriteString(fmt.Sprintf("        Default: %v\n", spec.Default))
 	}
 
+	// Environment fallback
+	if spec.EnvVar != "" {
+		sb.WriteString(fmt.Sprintf("        Environment: $%s\n", spec.EnvVar))
+	}
+
 	// Required
 	if spec.Required {
 		sb.WriteString("        Required\n")
@@ -589,7 +772,9 @@ func formatFlagForSubcommand(spec *FlagSpec) string {
 	sb.WriteString(strings.Join(spec.Names, ", "))
 
 	// Arguments
-	if spec.ArgCount > 0 {
+	if spec.OptionalArg {
+		sb.WriteString(optionalArgLabel(spec))
+	} else if spec.ArgCount > 0 {
 		for i := 0; i < spec.ArgCount; i++ {
 			sb.WriteString(" ")
 			if i < len(spec.ArgNames) {
@@ -612,15 +797,26 @@ func formatFlagForSubcommand(spec *FlagSpec) string {
 		sb.WriteString(fmt.Sprintf("        Default: %v\n", spec.Default))
 	}
 
+	// Environment fallback
+	if spec.EnvVar != "" {
+		sb.WriteString(fmt.Sprintf("        Environment: $%s\n", spec.EnvVar))
+	}
+
 	// Required
 	if spec.Required {
 		sb.WriteString("        Required: yes\n")
 	}
+	for _, cond := range spec.RequiredIf {
+		sb.WriteString(fmt.Sprintf("        Required when: %s\n", cond))
+	}
 
 	// Multi-value
 	if spec.IsSlice {
 		sb.WriteString("        Can be specified multiple times\n")
 	}
+	for _, note := range formNotes(spec) {
+		sb.WriteString("        " + note + "\n")
+	}
 
 	return sb.String()
 }
@@ -680,6 +876,13 @@ func (subcmd *Subcommand) GenerateManPage(parentName string) string {
 		sb.WriteString("\n")
 	}
 
+	// ALIASES section
+	if len(subcmd.Aliases) > 0 {
+		sb.WriteString(".SH ALIASES\n")
+		sb.WriteString(escapeGroff(strings.Join(subcmd.Aliases, ", ")))
+		sb.WriteString("\n")
+	}
+
 	// COMMANDS section — list nested subcommands (parity with GenerateHelp).
 	if len(subcmd.Subcommands) > 0 {
 		sb.WriteString(".SH COMMANDS\n")
@@ -690,7 +893,7 @@ func (subcmd *Subcommand) GenerateManPage(parentName string) string {
 		sort.Strings(names)
 		for _, name := range names {
 			sb.WriteString(".TP\n.B ")
-			sb.WriteString(name)
+			sb.WriteString(escapeGroff(subcmd.Subcommands[name].label()))
 			sb.WriteString("\n")
 			sb.WriteString(subcmd.Subcommands[name].Description)
 			sb.WriteString("\n")
@@ -710,7 +913,9 @@ func (subcmd *Subcommand) GenerateManPage(parentName string) string {
 		sb.WriteString(strings.Join(spec.Names, ", "))
 
 		// Arguments
-		if spec.ArgCount > 0 {
+		if spec.OptionalArg {
+			sb.WriteString(optionalArgLabel(spec))
+		} else if spec.ArgCount > 0 {
 			for i := 0; i < spec.ArgCount; i++ {
 				sb.WriteString(" ")
 				if i < len(spec.ArgNames) {
@@ -732,8 +937,23 @@ func (subcmd *Subcommand) GenerateManPage(parentName string) string {
 		if spec.Scope == ScopeLocal {
 			sb.WriteString("(per-clause)\n")
 		}
+
+		// Environment fallback
+		if spec.EnvVar != "" {
+			sb.WriteString(fmt.Sprintf("Environment: $%s\n", spec.EnvVar))
+		}
+
+		for _, cond := range spec.RequiredIf {
+			sb.WriteString(fmt.Sprintf("Required when: %s\n", cond))
+		}
+		for _, note := range formNotes(spec) {
+			sb.WriteString(note + "\n")
+		}
 	}
 
+	// CONSTRAINTS section
+	sb.WriteString(formatManGroups(subcmd.Groups))
+
 	// EXAMPLES section
 	if len(subcmd.Examples) > 0 {
 		sb.WriteString(".SH EXAMPLES\n")
@@ -840,6 +1060,39 @@ func (sfb *SubcommandFlagBuilder) Time() *SubcommandFlagBuilder {
 	return sfb.Args(1).ArgType(0, ArgTime).ArgName(0, "TIME")
 }
 
+// Bytes is a shorthand for a single byte-size argument ("512MiB", "1.5G")
+func (sfb *SubcommandFlagBuilder) Bytes() *SubcommandFlagBuilder {
+	return sfb.Args(1).ArgType(0, ArgBytes).ArgName(0, "SIZE")
+}
+
+// IP is a shorthand for a single IP address argument
+func (sfb *SubcommandFlagBuilder) IP() *SubcommandFlagBuilder {
+	return sfb.Args(1).ArgType(0, ArgIP).ArgName(0, "IP")
+}
+
+// CIDR is a shorthand for a single CIDR block argument ("10.0.0.0/8")
+func (sfb *SubcommandFlagBuilder) CIDR() *SubcommandFlagBuilder {
+	return sfb.Args(1).ArgType(0, ArgCIDR).ArgName(0, "CIDR")
+}
+
+// URL is a shorthand for a single absolute URL argument
+func (sfb *SubcommandFlagBuilder) URL() *SubcommandFlagBuilder {
+	return sfb.Args(1).ArgType(0, ArgURL).ArgName(0, "URL")
+}
+
+// Regexp is a shorthand for a single regular expression argument
+func (sfb *SubcommandFlagBuilder) Regexp() *SubcommandFlagBuilder {
+	return sfb.Args(1).ArgType(0, ArgRegexp).ArgName(0, "REGEXP")
+}
+
+// Enum is a shorthand for a single argument that must be one of values.
+// The values are also offered for completion.
+func (sfb *SubcommandFlagBuilder) Enum(values ...string) *SubcommandFlagBuilder {
+	sfb.Args(1).ArgName(0, "VALUE")
+	setEnum(sfb.spec, 0, values)
+	return sfb
+}
+
 // ArgName sets the name for a specific argument
 func (sfb *SubcommandFlagBuilder) ArgName(index int, name string) *SubcommandFlagBuilder {
 	if index >= 0 && index < len(sfb.spec.ArgNames) {
@@ -850,9 +1103,7 @@ func (sfb *SubcommandFlagBuilder) ArgName(index int, name string) *SubcommandFla
 
 // ArgType sets the type for a specific argument
 func (sfb *SubcommandFlagBuilder) ArgType(index int, t ArgType) *SubcommandFlagBuilder {
-	if index >= 0 && index < len(sfb.spec.ArgTypes) {
-		sfb.spec.ArgTypes[index] = t
-	}
+	setArgType(sfb.spec, index, t)
 	return sfb
 }
 
@@ -864,18 +1115,83 @@ func (sfb *SubcommandFlagBuilder) ArgCompleter(index int, c Completer) *Subcomma
 	return sfb
 }
 
+// Count makes a flag that takes no argument and counts its occurrences:
+// -v -v -v yields the int 3, and no -v at all yields 0
+func (sfb *SubcommandFlagBuilder) Count() *SubcommandFlagBuilder {
+	sfb.Bool()
+	sfb.spec.IsCounter = true
+	if sfb.spec.Default == nil {
+		sfb.spec.Default = 0
+	}
+	return sfb
+}
+
+// OptionalValue lets the flag's single argument be omitted, storing value
+// when it is (see FlagBuilder.OptionalValue)
+func (sfb *SubcommandFlagBuilder) OptionalValue(value interface{}) *SubcommandFlagBuilder {
+	if sfb.spec.ArgCount == 0 {
+		sfb.String()
+	}
+	if sfb.spec.ArgCount != 1 {
+		panic(fmt.Sprintf("flag %s: OptionalValue requires a single-argument flag", sfb.spec.Names[0]))
+	}
+	sfb.spec.OptionalArg = true
+	sfb.spec.BareValue = value
+	return sfb
+}
+
 // Required marks the flag as required
 func (sfb *SubcommandFlagBuilder) Required() *SubcommandFlagBuilder {
 	sfb.spec.Required = true
 	return sfb
 }
 
+// RequiredIf marks the flag as required when flag has the given value
+func (sfb *SubcommandFlagBuilder) RequiredIf(flag string, value interface{}) *SubcommandFlagBuilder {
+	sfb.spec.RequiredIf = append(sfb.spec.RequiredIf, Condition{Flag: flag, Value: value})
+	return sfb
+}
+
+// RequiredInEachClause requires a local flag in every clause, rather than
+// in at least one as Required does. Done panics if the flag is global.
+func (sfb *SubcommandFlagBuilder) RequiredInEachClause() *SubcommandFlagBuilder {
+	sfb.spec.RequiredInEachClause = true
+	return sfb
+}
+
+// MaxPerClause limits how many times an accumulated or counted flag may be
+// given in one clause. Done panics if the flag is global or neither
+// Accumulate nor Count.
+func (sfb *SubcommandFlagBuilder) MaxPerClause(n int) *SubcommandFlagBuilder {
+	sfb.spec.MaxPerClause = n
+	return sfb
+}
+
+// DependsOn defers parsing until the named flags have their final values
+// (see FlagBuilder.DependsOn)
+func (sfb *SubcommandFlagBuilder) DependsOn(flags ...string) *SubcommandFlagBuilder {
+	sfb.spec.DependsOn = append(sfb.spec.DependsOn, flags...)
+	return sfb
+}
+
+// ParseWith parses the flag's argument with fn (see FlagBuilder.ParseWith)
+func (sfb *SubcommandFlagBuilder) ParseWith(fn DependentParser) *SubcommandFlagBuilder {
+	sfb.spec.ParseFunc = fn
+	return sfb
+}
+
 // Default sets the default value
 func (sfb *SubcommandFlagBuilder) Default(value interface{}) *SubcommandFlagBuilder {
 	sfb.spec.Default = value
 	return sfb
 }
 
+// Env sets an environment variable to fall back on when the flag is absent
+func (sfb *SubcommandFlagBuilder) Env(name string) *SubcommandFlagBuilder {
+	sfb.spec.EnvVar = name
+	return sfb
+}
+
 // Validate sets a validation function
 func (sfb *SubcommandFlagBuilder) Validate(fn ValidatorFunc) *SubcommandFlagBuilder {
 	sfb.spec.Validator = fn
@@ -911,6 +1227,19 @@ func (sfb *SubcommandFlagBuilder) Hidden() *SubcommandFlagBuilder {
 	return sfb
 }
 
+// Secret marks the flag's value as sensitive (see FlagBuilder.Secret)
+func (sfb *SubcommandFlagBuilder) Secret() *SubcommandFlagBuilder {
+	sfb.spec.Secret = true
+	return sfb
+}
+
+// SecretFile makes the flag Secret and adds -NAME-file (see FlagBuilder.SecretFile)
+func (sfb *SubcommandFlagBuilder) SecretFile() *SubcommandFlagBuilder {
+	sfb.spec.Secret = true
+	sfb.spec.SecretFile = true
+	return sfb
+}
+
 // Accumulate marks the flag to accumulate multiple values
 func (sfb *SubcommandFlagBuilder) Accumulate() *SubcommandFlagBuilder {
 	sfb.spec.IsSlice = true
@@ -977,14 +1306,21 @@ func (sfb *SubcommandFlagBuilder) Arg(name string) *SubcommandArgBuilder {
 // Done finalizes the flag and returns to subcommand builder
 func (sfb *SubcommandFlagBuilder) Done() *SubcommandBuilder {
 	sfb.sb.subcmd.Flags = append(sfb.sb.subcmd.Flags, sfb.spec)
+	if fileFlag := secretFileFlag(sfb.spec); fileFlag != nil {
+		sfb.sb.subcmd.Flags = append(sfb.sb.subcmd.Flags, fileFlag)
+	}
 	return sfb.sb
 }
 
 // Type sets the type for this argument
 func (sab *SubcommandArgBuilder) Type(t ArgType) *SubcommandArgBuilder {
-	if sab.argIndex >= 0 && sab.argIndex < len(sab.sfb.spec.ArgTypes) {
-		sab.sfb.spec.ArgTypes[sab.argIndex] = t
-	}
+	setArgType(sab.sfb.spec, sab.argIndex, t)
+	return sab
+}
+
+// Enum restricts this argument to values and offers them for completion
+func (sab *SubcommandArgBuilder) Enum(values ...string) *SubcommandArgBuilder {
+	setEnum(sab.sfb.spec, sab.argIndex, values)
 	return sab
 }
 
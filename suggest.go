@@ -0,0 +1,132 @@
+package completionflags
+
+import (
+	"sort"
+	"strings"
+)
+
+// maxSuggestions caps how many "did you mean" candidates an error carries
+const maxSuggestions = 3
+
+// suggest returns the candidates that look like a mistyped name: those within
+// a small edit distance (transpositions count as one edit) and those the name
+// is a prefix of. Closest candidates come first.
+func suggest(name string, candidates []string) []string {
+	type match struct {
+		name     string
+		distance int
+	}
+
+	core := strings.TrimLeft(name, "-+")
+	threshold := 1 + len(core)/4
+	if threshold >= len(core) {
+		// Don't suggest "b" for "a"
+		threshold = len(core) - 1
+	}
+	seen := make(map[string]bool)
+	var matches []match
+	for _, candidate := range candidates {
+		if candidate == name || seen[candidate] {
+			continue
+		}
+		seen[candidate] = true
+
+		distance := editDistance(name, candidate)
+		if distance > threshold {
+			if len(core) < 2 || !strings.HasPrefix(candidate, name) {
+				continue
+			}
+			// Truncated names rank after genuine typos
+			distance = threshold + 1
+		}
+		matches = append(matches, match{candidate, distance})
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].distance != matches[j].distance {
+			return matches[i].distance < matches[j].distance
+		}
+		return matches[i].name < matches[j].name
+	})
+
+	var result []string
+	for i := 0; i < len(matches) && i < maxSuggestions; i++ {
+		result = append(result, matches[i].name)
+	}
+	return result
+}
+
+// editDistance is the optimal string alignment distance between a and b:
+// insertions, deletions, substitutions and adjacent transpositions each
+// cost one
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev2 := make([]int, len(rb)+1)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				curr[j] = min(curr[j], prev2[j-2]+1)
+			}
+		}
+		prev2, prev, curr = prev, curr, prev2
+	}
+	return prev[len(rb)]
+}
+
+// didYouMean renders suggestions as " (did you mean a, b or c?)", or "" when
+// there are none. quote formats each candidate.
+func didYouMean(suggestions []string, quote func(string) string) string {
+	if len(suggestions) == 0 {
+		return ""
+	}
+	quoted := make([]string, len(suggestions))
+	for i, s := range suggestions {
+		quoted[i] = quote(s)
+	}
+	list := quoted[0]
+	if len(quoted) > 1 {
+		list = strings.Join(quoted[:len(quoted)-1], ", ") + " or " + quoted[len(quoted)-1]
+	}
+	return " (did you mean " + list + "?)"
+}
+
+// flagSuggestions returns the visible flag names close to name
+func (cmd *Command) flagSuggestions(name string) []string {
+	var names []string
+	for _, spec := range cmd.flags {
+		if spec.Hidden || spec.isPositional() {
+			continue
+		}
+		names = append(names, spec.Names...)
+	}
+	return suggest(name, names)
+}
+
+// subcommandSuggestions returns the subcommand names close to name, each
+// prefixed with path so callers can show the full command
+func subcommandSuggestions(name string, subcommands map[string]*Subcommand, path []string) []string {
+	var names []string
+	for n := range subcommands {
+		names = append(names, n)
+	}
+	suggestions := suggest(name, names)
+	if len(path) > 0 {
+		prefix := strings.Join(path, " ") + " "
+		for i := range suggestions {
+			suggestions[i] = prefix + suggestions[i]
+		}
+	}
+	return suggestions
+}
@@ -0,0 +1,89 @@
+package completionflags
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSuggest(t *testing.T) {
+	candidates := []string{"status", "stats", "start", "stop", "remote"}
+	tests := []struct {
+		name string
+		want []string
+	}{
+		{"stauts", []string{"stats", "status", "start"}},
+		{"stpo", []string{"stop"}},
+		{"remo", []string{"remote"}},
+		{"xyz", nil},
+		{"s", nil},
+	}
+	for _, tt := range tests {
+		if got := suggest(tt.name, candidates); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("suggest(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSuggest_UnknownFlag(t *testing.T) {
+	cmd := NewCommand("test").
+		Flag("-format").String().Global().Done().
+		Flag("-secret").String().Global().Hidden().Done().
+		Handler(func(ctx *Context) error { return nil }).
+		Build()
+
+	err := cmd.Execute([]string{"-fromat", "json"})
+	var perr ParseError
+	if !errors.As(err, &perr) {
+		t.Fatalf("expected ParseError, got %T: %v", err, err)
+	}
+	if !reflect.DeepEqual(perr.Suggestions, []string{"-format"}) {
+		t.Errorf("unexpected suggestions: %v", perr.Suggestions)
+	}
+	if !strings.Contains(err.Error(), "did you mean -format?") {
+		t.Errorf("error message missing suggestion: %v", err)
+	}
+
+	// Hidden flags are never suggested
+	err = cmd.Execute([]string{"-secrt", "x"})
+	if errors.As(err, &perr) && len(perr.Suggestions) != 0 {
+		t.Errorf("hidden flag suggested: %v", perr.Suggestions)
+	}
+}
+
+func TestSuggest_UnknownCommand(t *testing.T) {
+	cmd := NewCommand("app").
+		Subcommand("status").Handler(func(ctx *Context) error { return nil }).Done().
+		Subcommand("remote").
+		Subcommand("add").Handler(func(ctx *Context) error { return nil }).Done().
+		Done().
+		Build()
+
+	err := cmd.Execute([]string{"stauts"})
+	var unknown UnknownCommandError
+	if !errors.As(err, &unknown) {
+		t.Fatalf("expected UnknownCommandError, got %T: %v", err, err)
+	}
+	if !reflect.DeepEqual(unknown.Suggestions, []string{"status"}) {
+		t.Errorf("unexpected suggestions: %v", unknown.Suggestions)
+	}
+	if !strings.Contains(err.Error(), `did you mean "status"?`) {
+		t.Errorf("error message missing suggestion: %v", err)
+	}
+
+	// Still matches the original sentinel type
+	var legacy ErrUnknownCommand
+	if !errors.As(err, &legacy) || string(legacy) != "stauts" {
+		t.Errorf("expected ErrUnknownCommand(%q), got %v", "stauts", legacy)
+	}
+
+	// Nested: suggestions come from the subcommands at that level
+	err = cmd.Execute([]string{"remote", "ad"})
+	if !errors.As(err, &unknown) {
+		t.Fatalf("expected UnknownCommandError, got %T: %v", err, err)
+	}
+	if unknown.Name != "remote ad" || !reflect.DeepEqual(unknown.Suggestions, []string{"remote add"}) {
+		t.Errorf("unexpected nested error: %+v", unknown)
+	}
+}
@@ -0,0 +1,222 @@
+package completionflags
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// timeoutFlagName is the root-global flag added by TimeoutFlag
+const timeoutFlagName = "-timeout"
+
+// TimeoutError is returned by dispatch when a handler runs past its timeout
+// (SubcommandBuilder.Timeout or -timeout). It unwraps to
+// context.DeadlineExceeded and is the context.Cause of the handler's Ctx().
+type TimeoutError struct {
+	Command string        // Full command name, e.g. "tool deploy"
+	Timeout time.Duration // The limit that expired
+}
+
+func (e TimeoutError) Error() string {
+	return fmt.Sprintf("%s: timed out after %v", e.Command, e.Timeout)
+}
+
+// Unwrap returns context.DeadlineExceeded
+func (e TimeoutError) Unwrap() error {
+	return context.DeadlineExceeded
+}
+
+// TimeoutFlag adds a root-global -timeout DURATION flag limiting how long
+// the handler of the command or any subcommand may run. It overrides
+// SubcommandBuilder.Timeout; -timeout 0 removes the limit.
+func (cb *CommandBuilder) TimeoutFlag() *CommandBuilder {
+	cb.cmd.timeoutFlag = true
+	return cb
+}
+
+// addTimeoutFlag registers the -timeout flag when TimeoutFlag was called
+// and the command doesn't already define the flag itself
+func (cmd *Command) addTimeoutFlag() {
+	if !cmd.timeoutFlag || cmd.findFlagSpec(timeoutFlagName) != nil {
+		return
+	}
+	cmd.flags = append(cmd.flags, &FlagSpec{
+		Names:         []string{timeoutFlagName},
+		Description:   "Abort the command if it runs longer than DURATION (e.g. 30s, 5m)",
+		Scope:         ScopeGlobal,
+		ArgCount:      1,
+		ArgNames:      []string{"DURATION"},
+		ArgTypes:      []ArgType{ArgDuration},
+		ArgCompleters: []Completer{NoCompleter{Hint: "<DURATION>"}},
+	})
+}
+
+// handlerTimeout returns the limit for a handler: -timeout when given, else
+// the Timeout of the deepest subcommand in chain that sets one
+func (cmd *Command) handlerTimeout(ctx *Context, chain []*Subcommand) time.Duration {
+	if cmd.timeoutFlag {
+		if d, ok := ctx.GlobalFlags[timeoutFlagName].(time.Duration); ok {
+			return d
+		}
+	}
+	for i := len(chain) - 1; i >= 0; i-- {
+		if chain[i].Timeout > 0 {
+			return chain[i].Timeout
+		}
+	}
+	return 0
+}
+
+// runWithTimeout runs h with a deadline d on ctx.Ctx(). Dispatch returns a
+// TimeoutError when the deadline passes, even if h ignores its context and
+// keeps running in the background; from then on its output is discarded and
+// its questions fail (see detachableIO). A panic in h is re-raised here as a
+// HandlerPanic.
+func runWithTimeout(ctx *Context, d time.Duration, name string, h ClauseHandlerFunc) error {
+	if d <= 0 {
+		return h(ctx)
+	}
+	timeout := TimeoutError{Command: name, Timeout: d}
+	deadlineCtx, cancel := context.WithTimeoutCause(ctx.Ctx(), d, timeout)
+	defer cancel()
+	ctx.ctx = deadlineCtx
+	session := detachIO(ctx, timeout)
+
+	type result struct {
+		err      error
+		panicked *HandlerPanic
+	}
+	done := make(chan result, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- result{panicked: &HandlerPanic{Value: r, Stack: debug.Stack()}}
+			}
+		}()
+		done <- result{err: h(ctx)}
+	}()
+
+	select {
+	case r := <-done:
+		if r.panicked != nil {
+			panic(*r.panicked)
+		}
+		if r.err != nil && context.Cause(deadlineCtx) == timeout && errors.Is(r.err, context.DeadlineExceeded) {
+			return timeout
+		}
+		return r.err
+	case <-deadlineCtx.Done():
+		if context.Cause(deadlineCtx) == timeout {
+			session.wait()
+			return timeout
+		}
+		// Cancelled from outside (a signal, a closed session): wait for
+		// the handler to wind down as it would without a timeout
+		r := <-done
+		if r.panicked != nil {
+			panic(*r.panicked)
+		}
+		return r.err
+	}
+}
+
+// HandlerPanic is the value runWithTimeout re-panics with when a handler
+// running under a timeout panics: the original value and the stack of the
+// goroutine the handler ran on, which the re-panic would otherwise lose.
+type HandlerPanic struct {
+	Value interface{} // The value the handler panicked with
+	Stack []byte      // The handler goroutine's stack (debug.Stack)
+}
+
+// Error gives the panic value followed by the handler's stack, which is
+// what the runtime prints if nothing recovers
+func (p HandlerPanic) Error() string {
+	return fmt.Sprintf("%v\n\nhandler goroutine stack:\n%s", p.Value, p.Stack)
+}
+
+// Unwrap returns the panic value when it is an error
+func (p HandlerPanic) Unwrap() error {
+	err, _ := p.Value.(error)
+	return err
+}
+
+// detachableIO stands between a handler running under a timeout and the
+// session's Stdout, Stderr and Prompter. Once the timeout passes, writes
+// are discarded and questions fail with the TimeoutError, so a handler left
+// running in the background can't write to an embedded shell's terminal or
+// read the line meant for its next command. A question already being asked
+// is let finish first: the line editor can't be shared.
+type detachableIO struct {
+	mu      sync.RWMutex // Held for reading while a question is asked
+	ctx     context.Context
+	timeout TimeoutError
+}
+
+// detachIO puts a detachableIO in front of ctx's streams and Prompter
+func detachIO(ctx *Context, timeout TimeoutError) *detachableIO {
+	session := &detachableIO{ctx: ctx.ctx, timeout: timeout}
+	ctx.stdout = detachedWriter{session, ctx.Stdout()}
+	ctx.stderr = detachedWriter{session, ctx.Stderr()}
+	if ctx.prompter != nil {
+		ctx.prompter = detachedPrompter{session, ctx.prompter}
+	}
+	return session
+}
+
+// timedOut reports whether the handler has run past its timeout
+func (s *detachableIO) timedOut() bool {
+	return context.Cause(s.ctx) == s.timeout
+}
+
+// wait returns once a question asked before the timeout has been answered;
+// later ones see timedOut
+func (s *detachableIO) wait() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+}
+
+// ask runs one Prompter read unless the handler has timed out. An answer
+// that arrives after the timeout is dropped.
+func (s *detachableIO) ask(read func() (string, error)) (string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.timedOut() {
+		return "", s.timeout
+	}
+	answer, err := read()
+	if s.timedOut() {
+		return "", s.timeout
+	}
+	return answer, err
+}
+
+// detachedWriter discards writes once the handler has timed out
+type detachedWriter struct {
+	session *detachableIO
+	w       io.Writer
+}
+
+func (w detachedWriter) Write(p []byte) (int, error) {
+	if w.session.timedOut() {
+		return len(p), nil
+	}
+	return w.w.Write(p)
+}
+
+// detachedPrompter fails questions once the handler has timed out
+type detachedPrompter struct {
+	session *detachableIO
+	p       Prompter
+}
+
+func (p detachedPrompter) ReadLine(prompt string) (string, error) {
+	return p.session.ask(func() (string, error) { return p.p.ReadLine(prompt) })
+}
+
+func (p detachedPrompter) ReadPassword(prompt string) (string, error) {
+	return p.session.ask(func() (string, error) { return p.p.ReadPassword(prompt) })
+}
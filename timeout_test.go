@@ -0,0 +1,213 @@
+package completionflags
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTimeout_Subcommand(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	cmd := NewCommand("tool").
+		TimeoutFlag().
+		Subcommand("hang").
+		Timeout(20 * time.Millisecond).
+		Handler(func(ctx *Context) error {
+			<-release // Ignores its context, like a hung backend call
+			return nil
+		}).
+		Done().
+		Subcommand("wait").
+		Timeout(time.Hour).
+		Handler(func(ctx *Context) error {
+			<-ctx.Ctx().Done()
+			return ctx.Ctx().Err()
+		}).
+		Done().
+		Subcommand("quick").
+		Timeout(time.Hour).
+		Handler(func(ctx *Context) error {
+			if _, ok := ctx.Ctx().Deadline(); !ok {
+				return errors.New("no deadline on Ctx()")
+			}
+			return nil
+		}).
+		Done().
+		Subcommand("remote").
+		Timeout(20 * time.Millisecond).
+		Subcommand("sync").
+		Handler(func(ctx *Context) error {
+			<-ctx.Ctx().Done()
+			return context.Cause(ctx.Ctx())
+		}).
+		Done().
+		Done().
+		Build()
+
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"hang"}, "tool hang: timed out after 20ms"},
+		{[]string{"-timeout", "10ms", "wait"}, "tool wait: timed out after 10ms"},
+		{[]string{"remote", "sync"}, "tool remote sync: timed out after 20ms"},
+	}
+	for _, tt := range tests {
+		err := cmd.ExecuteWith(tt.args, nil)
+		var timeout TimeoutError
+		if !errors.As(err, &timeout) || err.Error() != tt.want {
+			t.Errorf("%v: error = %v, want %q", tt.args, err, tt.want)
+		}
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("%v: TimeoutError should unwrap to context.DeadlineExceeded", tt.args)
+		}
+		if ExitCode(err) != ExitTimeout {
+			t.Errorf("%v: ExitCode = %d", tt.args, ExitCode(err))
+		}
+	}
+
+	if err := cmd.ExecuteWith([]string{"quick"}, nil); err != nil {
+		t.Errorf("quick: %v", err)
+	}
+}
+
+func TestTimeout_FlagZeroDisables(t *testing.T) {
+	cmd := NewCommand("tool").
+		TimeoutFlag().
+		Subcommand("run").
+		Timeout(time.Millisecond).
+		Handler(func(ctx *Context) error {
+			if _, ok := ctx.Ctx().Deadline(); ok {
+				return errors.New("unexpected deadline")
+			}
+			return nil
+		}).
+		Done().
+		Build()
+
+	if err := cmd.ExecuteWith([]string{"-timeout", "0s", "run"}, nil); err != nil {
+		t.Error(err)
+	}
+	if !strings.Contains(cmd.GenerateHelp(), "-timeout DURATION") {
+		t.Errorf("help missing -timeout:\n%s", cmd.GenerateHelp())
+	}
+}
+
+func TestTimeout_OuterCancelIsNotTimeout(t *testing.T) {
+	cmd := NewCommand("tool").
+		Subcommand("run").
+		Timeout(time.Hour).
+		Handler(func(ctx *Context) error {
+			<-ctx.Ctx().Done()
+			return ctx.Ctx().Err()
+		}).
+		Done().
+		Build()
+
+	parent, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := cmd.ExecuteWith([]string{"run"}, (&Context{}).SetCtx(parent))
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("error = %v, want context.Canceled", err)
+	}
+}
+
+func TestTimeout_PanicPropagates(t *testing.T) {
+	cmd := NewCommand("tool").
+		Subcommand("crash").
+		Timeout(time.Hour).
+		Handler(func(ctx *Context) error { panic("oops") }).
+		Done().
+		Build()
+
+	defer func() {
+		r, ok := recover().(HandlerPanic)
+		if !ok || r.Value != "oops" || !strings.Contains(string(r.Stack), "timeout_test.go") {
+			t.Errorf("recovered %#v, want a HandlerPanic with the handler's stack", r)
+		}
+	}()
+	cmd.ExecuteWith([]string{"crash"}, nil)
+}
+
+func TestTimeout_DetachesIO(t *testing.T) {
+	proceed := make(chan struct{})
+	late := make(chan error, 1)
+	cmd := NewCommand("tool").
+		Subcommand("hang").
+		Timeout(10 * time.Millisecond).
+		Handler(func(ctx *Context) error {
+			fmt.Fprint(ctx.Stdout(), "early ")
+			<-proceed // Still running after dispatch has returned
+			fmt.Fprint(ctx.Stdout(), "late")
+			_, err := ctx.Prompt("Name", "")
+			late <- err
+			return nil
+		}).
+		Done().
+		Build()
+
+	var out bytes.Buffer
+	p := &scriptedPrompter{answers: []string{"bob"}}
+	err := cmd.ExecuteWith([]string{"hang"}, (&Context{}).SetStdout(&out).SetPrompter(p))
+	if !errors.As(err, new(TimeoutError)) {
+		t.Fatalf("error = %v, want TimeoutError", err)
+	}
+	close(proceed)
+	if err := <-late; !errors.As(err, new(TimeoutError)) {
+		t.Errorf("Prompt after timeout = %v, want TimeoutError", err)
+	}
+	if out.String() != "early " || len(p.asked) != 0 {
+		t.Errorf("output %q, asked %q after the timeout", out.String(), p.asked)
+	}
+}
+
+// blockingPrompter answers once the test sends on answer
+type blockingPrompter struct {
+	asked  chan struct{}
+	answer chan string
+}
+
+func (p blockingPrompter) ReadLine(prompt string) (string, error) {
+	p.asked <- struct{}{}
+	return <-p.answer, nil
+}
+
+func (p blockingPrompter) ReadPassword(prompt string) (string, error) { return p.ReadLine(prompt) }
+
+func TestTimeout_WaitsForQuestion(t *testing.T) {
+	answered := make(chan error, 1)
+	cmd := NewCommand("tool").
+		Subcommand("ask").
+		Timeout(10 * time.Millisecond).
+		Handler(func(ctx *Context) error {
+			_, err := ctx.Prompt("Name", "")
+			answered <- err
+			return err
+		}).
+		Done().
+		Build()
+
+	p := blockingPrompter{asked: make(chan struct{}), answer: make(chan string)}
+	result := make(chan error, 1)
+	go func() { result <- cmd.ExecuteWith([]string{"ask"}, (&Context{}).SetPrompter(p)) }()
+
+	<-p.asked
+	time.Sleep(30 * time.Millisecond)
+	select {
+	case err := <-result:
+		t.Fatalf("dispatch returned %v while the prompter was still reading", err)
+	default:
+	}
+	p.answer <- "bob"
+	if err := <-result; !errors.As(err, new(TimeoutError)) {
+		t.Errorf("error = %v, want TimeoutError", err)
+	}
+	if err := <-answered; !errors.As(err, new(TimeoutError)) {
+		t.Errorf("late answer gave %v, want TimeoutError", err)
+	}
+}
@@ -0,0 +1,16 @@
+//go:build !linux && !darwin && !dragonfly && !freebsd && !netbsd && !openbsd
+
+package completionflags
+
+import "errors"
+
+// isTerminal reports false: terminals aren't detected on this platform, so
+// Execute never prompts
+func isTerminal(fd uintptr) bool {
+	return false
+}
+
+// withoutEcho can't hide input on this platform
+func withoutEcho(fd uintptr, fn func() error) error {
+	return errors.New("hiding input is not supported on this platform")
+}
@@ -0,0 +1,46 @@
+//go:build linux || darwin || dragonfly || freebsd || netbsd || openbsd
+
+package completionflags
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+// termios reads the terminal attributes of fd
+func termios(fd uintptr) (*syscall.Termios, error) {
+	var t syscall.Termios
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, ioctlGetTermios, uintptr(unsafe.Pointer(&t))); errno != 0 {
+		return nil, errno
+	}
+	return &t, nil
+}
+
+// setTermios sets the terminal attributes of fd
+func setTermios(fd uintptr, t *syscall.Termios) error {
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, ioctlSetTermios, uintptr(unsafe.Pointer(t))); errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// isTerminal reports whether fd is a terminal
+func isTerminal(fd uintptr) bool {
+	_, err := termios(fd)
+	return err == nil
+}
+
+// withoutEcho runs fn with echo turned off on terminal fd
+func withoutEcho(fd uintptr, fn func() error) error {
+	old, err := termios(fd)
+	if err != nil {
+		return err
+	}
+	t := *old
+	t.Lflag &^= syscall.ECHO
+	if err := setTermios(fd, &t); err != nil {
+		return err
+	}
+	defer setTermios(fd, old)
+	return fn()
+}
@@ -0,0 +1,128 @@
+package completionflags
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+var errNeedsSort = errors.New("-limit needs -sort")
+
+func TestValidateContext(t *testing.T) {
+	cmd := NewCommand("tool").
+		Flag("-start").Duration().Global().Done().
+		Flag("-end").Duration().Global().Default(time.Hour).Done().
+		Flag("-limit").Int().Global().Done().
+		Flag("-sort").String().Global().Done().
+		ValidateContext(func(ctx *Context) error {
+			if _, ok := ctx.GlobalFlags["-limit"]; ok && ctx.GetString("-sort", "") == "" {
+				return errNeedsSort
+			}
+			return nil
+		}).
+		ValidateContext(func(ctx *Context) error {
+			// Sees the default for -end
+			if ctx.GetDuration("-end", 0) <= ctx.GetDuration("-start", 0) {
+				return ValidationError{Flag: "-end", Message: "must be after -start"}
+			}
+			return nil
+		}).
+		Handler(func(ctx *Context) error { return nil }).
+		Build()
+
+	if err := cmd.Execute([]string{"-limit", "5", "-sort", "name"}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	err := cmd.Execute([]string{"-limit", "5"})
+	var verr ValidationError
+	if !errors.As(err, &verr) || !errors.Is(err, errNeedsSort) {
+		t.Errorf("got %v, want a ValidationError wrapping errNeedsSort", err)
+	}
+
+	err = cmd.Execute([]string{"-start", "2h"})
+	if err == nil || err.Error() != "validation failed for -end: must be after -start" {
+		t.Errorf("got %v", err)
+	}
+
+	// Parse only parses; the hooks are left to Execute
+	if _, err := cmd.Parse([]string{"-limit", "5"}); err != nil {
+		t.Errorf("Parse: got %v", err)
+	}
+
+	// Completion isn't blocked by them either
+	matches, _ := cmd.Complete([]string{"-limit", "5", "-so"}, 3)
+	if len(matches) != 1 || matches[0] != "-sort" {
+		t.Errorf("completion: got %v", matches)
+	}
+}
+
+func TestValidateContext_Subcommand(t *testing.T) {
+	cmd := NewCommand("tool").
+		Flag("-dry-run").Bool().Global().Done().
+		Subcommand("deploy").
+		Flag("-force").Bool().Done().
+		ValidateContext(func(ctx *Context) error {
+			if ctx.GetBool("-dry-run", false) && ctx.Clauses[0].GetBool("-force", false) {
+				return errors.New("-force makes no sense with -dry-run")
+			}
+			return nil
+		}).
+		Handler(func(ctx *Context) error { return nil }).
+		Done().
+		Build()
+
+	err := cmd.Execute([]string{"-dry-run", "deploy", "-force"})
+	if err == nil || !strings.Contains(err.Error(), "validation failed: -force makes no sense") {
+		t.Errorf("got %v", err)
+	}
+	if err := cmd.Execute([]string{"deploy", "-force"}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestValidateContext_AfterValidation(t *testing.T) {
+	var seen []string
+	cmd := NewCommand("tool").
+		Flag("-n").Int().Global().Required().Done().
+		ValidateContext(func(ctx *Context) error {
+			seen = append(seen, "root")
+			if ctx.State != "state" {
+				t.Errorf("root hook: State = %v", ctx.State)
+			}
+			return nil
+		}).
+		Subcommand("remote").
+		ValidateContext(func(ctx *Context) error {
+			seen = append(seen, "remote")
+			return nil
+		}).
+		Subcommand("add").
+		Flag("-x").Int().Global().Required().Done().
+		ValidateContext(func(ctx *Context) error {
+			seen = append(seen, strings.Join(ctx.SubcommandPath, " "))
+			return nil
+		}).
+		Handler(func(ctx *Context) error { return nil }).
+		Done().
+		Done().
+		Build()
+
+	// A missing Required flag is reported before the hooks run
+	err := cmd.ExecuteWith([]string{"remote", "add"}, &Context{State: "state"})
+	if err == nil || !strings.Contains(err.Error(), "required flag not provided") || len(seen) != 0 {
+		t.Errorf("got %v, hooks run %v", err, seen)
+	}
+	// Parse neither validates nor runs the hooks
+	if _, err := cmd.Parse(nil); err != nil || len(seen) != 0 {
+		t.Errorf("Parse: got %v, hooks run %v", err, seen)
+	}
+
+	if err := cmd.ExecuteWith([]string{"remote", "add", "-x", "1"}, &Context{State: "state"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Join(seen, ",") != "root,remote,remote add" {
+		t.Errorf("hooks run %v", seen)
+	}
+}